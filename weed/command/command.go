@@ -16,6 +16,8 @@ var Commands = []*Command{
 	cmdServer,
 	cmdMaster,
 	cmdFiler,
+	cmdFilerMigrate,
+	cmdFilerReindex,
 	cmdUpload,
 	cmdDownload,
 	cmdShell,
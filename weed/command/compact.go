@@ -25,6 +25,7 @@ var (
 	compactVolumeId          = cmdCompact.Flag.Int("volumeId", -1, "a volume id. The volume should already exist in the dir.")
 	compactMethod            = cmdCompact.Flag.Int("method", 0, "option to choose which compact method. use 0 or 1.")
 	compactVolumePreallocate = cmdCompact.Flag.Int64("preallocateMB", 0, "preallocate volume disk space")
+	compactVolumeMBps        = cmdCompact.Flag.Int("compactionMBps", 0, "limit compaction speed in mega bytes per second. 0 means unlimited.")
 )
 
 func runCompact(cmd *Command, args []string) bool {
@@ -42,7 +43,7 @@ func runCompact(cmd *Command, args []string) bool {
 		glog.Fatalf("Load Volume [ERROR] %s\n", err)
 	}
 	if *compactMethod == 0 {
-		if err = v.Compact(preallocate); err != nil {
+		if err = v.Compact(preallocate, int64(*compactVolumeMBps)*1024*1024); err != nil {
 			glog.Fatalf("Compact Volume [ERROR] %s\n", err)
 		}
 	} else {
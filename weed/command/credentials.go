@@ -0,0 +1,24 @@
+package command
+
+import (
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// loadCredentials loads the credentials file if given, reloading it when it
+// changes, and sets the api key this process sends to other servers.
+func loadCredentials(credentialsFile, apiKey string) *security.CredentialStore {
+	util.SetApiKey(apiKey)
+	if credentialsFile == "" {
+		return nil
+	}
+	credentials, err := security.LoadCredentialStore(credentialsFile)
+	if err != nil {
+		glog.Fatalf("load credentials: %v", err)
+	}
+	go credentials.WatchForChanges(5 * time.Second)
+	return credentials
+}
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -51,7 +52,7 @@ func runDownload(cmd *Command, args []string) bool {
 }
 
 func downloadToFile(server, fileId, saveDir string) error {
-	fileUrl, lookupError := operation.LookupFileId(server, fileId)
+	fileUrl, lookupError := operation.LookupFileIdForRead(context.Background(), server, fileId)
 	if lookupError != nil {
 		return lookupError
 	}
@@ -103,7 +104,7 @@ func downloadToFile(server, fileId, saveDir string) error {
 }
 
 func fetchContent(server string, fileId string) (filename string, content []byte, e error) {
-	fileUrl, lookupError := operation.LookupFileId(server, fileId)
+	fileUrl, lookupError := operation.LookupFileIdForRead(context.Background(), server, fileId)
 	if lookupError != nil {
 		return "", nil, lookupError
 	}
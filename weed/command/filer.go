@@ -1,13 +1,17 @@
 package command
 
 import (
+	"crypto/tls"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/server"
+	stats_collect "github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 	"github.com/soheilhy/cmux"
 	"google.golang.org/grpc/reflection"
@@ -32,12 +36,28 @@ type FilerOptions struct {
 	confFile                *string
 	maxMB                   *int
 	secretKey               *string
+	readSecretKey           *string
+	readExpireSeconds       *int
 	cassandra_server        *string
 	cassandra_keyspace      *string
 	redis_server            *string
 	redis_password          *string
 	redis_database          *int
+	leveldbStore            *bool
 	syncFile                *string
+	metricsAddress          *string
+	metricsPort             *int
+	metricsInterval         *int
+	tracingEndpoint         *string
+	tracingSampleRate       *float64
+	tlsCert                 *string
+	tlsKey                  *string
+	tlsCaCert               *string
+	serverTLS               *tls.Config
+	publicTLS               *tls.Config
+	credentialsFile         *string
+	apiKey                  *string
+	credentials             *security.CredentialStore
 }
 
 func init() {
@@ -58,7 +78,20 @@ func init() {
 	f.redis_server = cmdFiler.Flag.String("redis.server", "", "comma separated host:port[,host2:port2]* of the redis server, e.g., 127.0.0.1:6379")
 	f.redis_password = cmdFiler.Flag.String("redis.password", "", "password in clear text")
 	f.redis_database = cmdFiler.Flag.Int("redis.database", 0, "the database on the redis server")
+	f.leveldbStore = cmdFiler.Flag.Bool("leveldb", false, "store the meta data in a LevelDB with full path keys, in the leveldb sub directory of -dir")
 	f.secretKey = cmdFiler.Flag.String("secure.secret", "", "secret to encrypt Json Web Token(JWT)")
+	f.readSecretKey = cmdFiler.Flag.String("secure.read.secret", "", "secret to sign read tokens for volume servers, same as the master's")
+	f.readExpireSeconds = cmdFiler.Flag.Int("secure.read.expireSeconds", 60, "seconds read tokens are valid for")
+	f.metricsAddress = cmdFiler.Flag.String("metrics.address", "", "Prometheus push gateway address. Metrics are only served on -metrics.port if empty.")
+	f.metricsInterval = cmdFiler.Flag.Int("metrics.intervalSeconds", 15, "Prometheus push interval in seconds")
+	f.metricsPort = cmdFiler.Flag.Int("metrics.port", 0, "port to serve /metrics on, apart from the file paths. Not served if 0.")
+	f.tracingEndpoint = cmdFiler.Flag.String("tracing.endpoint", "", "OTLP/HTTP collector address to export traces to, e.g. localhost:4318. Tracing is disabled if empty.")
+	f.tracingSampleRate = cmdFiler.Flag.Float64("tracing.sampleRate", 0.01, "fraction of requests to trace")
+	f.tlsCert = cmdFiler.Flag.String("tls.cert", "", "certificate file to serve https and grpc with TLS, and to connect to the master and volume servers")
+	f.tlsKey = cmdFiler.Flag.String("tls.key", "", "private key file of the certificate")
+	f.tlsCaCert = cmdFiler.Flag.String("tls.caCert", "", "CA certificate file to verify servers and to require client certificates, for mutual TLS")
+	f.credentialsFile = cmdFiler.Flag.String("credentials", "", "json file of named api keys and their permissions, reloaded when changed. Not checked if empty.")
+	f.apiKey = cmdFiler.Flag.String("credentials.apiKey", "", "api key this server sends to the master and volume servers requiring credentials")
 
 }
 
@@ -78,6 +111,10 @@ var cmdFiler = &Command{
 
   Current <fullpath~fileid> mapping metadata store is local embedded leveldb.
   It should be highly scalable to hundreds of millions of files on a modest machine.
+  With -leveldb, the directories are kept in the same leveldb, by their full paths,
+  to scale to millions of directories too.
+  For a single node, {"sqlite": {"Path": "/data/filer.db"}} in the -confFile keeps
+  the meta data in an embedded SQLite database instead, if weed is built with cgo.
 
   Future we will ensure it can avoid of being SPOF.
 
@@ -90,6 +127,12 @@ func runFiler(cmd *Command, args []string) bool {
 		glog.Fatalf("Check Meta Folder (-dir) Writable %s : %s", *f.dir, err)
 	}
 
+	stats_collect.StartPushingMetric("filer", *f.ip+":"+strconv.Itoa(*f.port), *f.metricsAddress, *f.metricsInterval)
+	stats_collect.StartMetricsServer(*f.ip, *f.metricsPort)
+	tracing.Init("weed-filer", *f.tracingEndpoint, *f.tracingSampleRate)
+	f.serverTLS, f.publicTLS = setupTLS(*f.tlsCert, *f.tlsKey, *f.tlsCaCert)
+	f.credentials = loadCredentials(*f.credentialsFile, *f.apiKey)
+
 	f.start()
 
 	return true
@@ -110,8 +153,11 @@ func (fo *FilerOptions) start() {
 		*fo.confFile,
 		*fo.maxMB,
 		*fo.secretKey,
+		*fo.readSecretKey, *fo.readExpireSeconds,
+		fo.credentials,
 		*fo.cassandra_server, *fo.cassandra_keyspace,
 		*fo.redis_server, *fo.redis_password, *fo.redis_database,
+		*fo.leveldbStore,
 		*fo.syncFile,
 	)
 	if nfs_err != nil {
@@ -125,6 +171,7 @@ func (fo *FilerOptions) start() {
 		if e != nil {
 			glog.Fatalf("Filer server public listener error on port %d:%v", *fo.publicPort, e)
 		}
+		publicListener = withTLS(publicListener, fo.publicTLS)
 		go func() {
 			if e := http.Serve(publicListener, publicVolumeMux); e != nil {
 				glog.Fatalf("Volume server fail to serve public: %v", e)
@@ -140,13 +187,14 @@ func (fo *FilerOptions) start() {
 	if e != nil {
 		glog.Fatalf("Filer listener error: %v", e)
 	}
+	filerListener = withTLS(filerListener, fo.serverTLS)
 
 	m := cmux.New(filerListener)
 	grpcL := m.Match(cmux.HTTP2HeaderField("content-type", "application/grpc"))
 	httpL := m.Match(cmux.Any())
 
 	// Create your protocol servers.
-	grpcS := grpc.NewServer()
+	grpcS := grpc.NewServer(grpc.UnaryInterceptor(tracing.UnaryServerInterceptor()))
 	filer_pb.RegisterSeaweedFilerServer(grpcS, fs)
 	reflection.Register(grpcS)
 
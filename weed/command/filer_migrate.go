@@ -0,0 +1,140 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/server"
+)
+
+func init() {
+	cmdFilerMigrate.Run = runFilerMigrate // break init cycle
+}
+
+var cmdFilerMigrate = &Command{
+	UsageLine: "filer.migrate -from=embedded:/tmp -to=mysql:/etc/filer.conf",
+	Short:     "copy the file meta data from one filer store into another",
+	Long: `Walk all the directories of one filer store, and write every file into another.
+
+  The stores are given as <type>:<location>:
+    embedded:<filer dir>
+    leveldb:<filer dir>              the store in the leveldb sub directory
+    mysql:<filer.conf>
+    postgres:<filer.conf>
+    sqlite:<filer.conf>
+    cassandra:<host[:port]>/<keyspace>
+    redis:[password@]<host:port>[/database]
+
+  The mysql, cassandra and redis stores are scanned in one pass, including the
+  files written before they indexed their directories. The other stores are
+  walked directory by directory. The directories done are appended to the
+  -checkpoint file, and skipped when the same migration is run again after a
+  failure. The checkpoint is removed once all the files are written.
+  Empty directories are not migrated.
+
+  Afterwards, the file counts of both stores are compared, if the target store
+  can list its files, and a random sample of the files is looked up in the target.
+  Stop the filers using the stores during the migration.
+
+  `,
+}
+
+var (
+	filerMigrateFrom         = cmdFilerMigrate.Flag.String("from", "", "the source filer store, <type>:<location>")
+	filerMigrateTo           = cmdFilerMigrate.Flag.String("to", "", "the target filer store, <type>:<location>")
+	filerMigrateCheckpoint   = cmdFilerMigrate.Flag.String("checkpoint", "filer.migrate.checkpoint", "file to record the migrated directories in, to resume from. empty to disable")
+	filerMigrateVerify       = cmdFilerMigrate.Flag.Bool("verify", true, "compare both stores after the migration")
+	filerMigrateVerifySample = cmdFilerMigrate.Flag.Int("verify.sample", 1000, "number of random files to look up in the target store")
+)
+
+func runFilerMigrate(cmd *Command, args []string) bool {
+	if *filerMigrateFrom == "" || *filerMigrateTo == "" {
+		return false
+	}
+	from, err := openFilerStore(*filerMigrateFrom)
+	if err != nil {
+		glog.Fatalf("Open source filer store %s: %v", *filerMigrateFrom, err)
+	}
+	to, err := openFilerStore(*filerMigrateTo)
+	if err != nil {
+		glog.Fatalf("Open target filer store %s: %v", *filerMigrateTo, err)
+	}
+
+	stores := fmt.Sprintf("from %s to %s", *filerMigrateFrom, *filerMigrateTo)
+	files, err := filer.Migrate(from, to, stores, *filerMigrateCheckpoint, func(dirPath string, files int64) {
+		glog.V(1).Infof("migrated %s, %d files in total", dirPath, files)
+	})
+	if err != nil {
+		glog.Fatalf("Migrate after %d files: %v", files, err)
+	}
+	fmt.Printf("migrated %d files from %s to %s\n", files, *filerMigrateFrom, *filerMigrateTo)
+
+	if !*filerMigrateVerify {
+		return true
+	}
+	result, err := filer.Verify(from, to, *filerMigrateVerifySample)
+	if err != nil {
+		glog.Fatalf("Verify: %v", err)
+	}
+	if result.TargetFiles < 0 {
+		fmt.Printf("source files: %d, target files not counted: %v\n", result.SourceFiles, result.TargetError)
+	} else {
+		fmt.Printf("source files: %d, target files: %d\n", result.SourceFiles, result.TargetFiles)
+	}
+	fmt.Printf("sampled files: %d, mismatched: %d\n", result.Sampled, len(result.Mismatches))
+	for _, m := range result.Mismatches {
+		fmt.Println("  ", m)
+	}
+	if !result.Ok() {
+		glog.Fatalf("Verification failed")
+	}
+	return true
+}
+
+// openFilerStore opens the filer store of a <type>:<location> spec.
+func openFilerStore(spec string) (filer.Filer, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("expecting <type>:<location>")
+	}
+	storeType, location := parts[0], parts[1]
+	var storeName string
+	var store filer.Filer
+	var err error
+	switch storeType {
+	case "embedded", "leveldb":
+		storeName, store, err = weed_server.NewFilerStore("", location, "", "", "", "", "", 0, storeType == "leveldb")
+	case "mysql", "postgres", "sqlite":
+		storeName, store, err = weed_server.NewFilerStore("", "", location, "", "", "", "", 0, false)
+	case "cassandra":
+		i := strings.LastIndex(location, "/")
+		if i < 0 {
+			return nil, fmt.Errorf("expecting cassandra:<host[:port]>/<keyspace>")
+		}
+		storeName, store, err = weed_server.NewFilerStore("", "", "", location[:i], location[i+1:], "", "", 0, false)
+	case "redis":
+		password, database := "", 0
+		if i := strings.LastIndex(location, "@"); i >= 0 {
+			password, location = location[:i], location[i+1:]
+		}
+		if i := strings.LastIndex(location, "/"); i >= 0 {
+			if database, err = strconv.Atoi(location[i+1:]); err != nil {
+				return nil, fmt.Errorf("redis database %s: %v", location[i+1:], err)
+			}
+			location = location[:i]
+		}
+		storeName, store, err = weed_server.NewFilerStore("", "", "", "", "", location, password, database, false)
+	default:
+		return nil, fmt.Errorf("unknown filer store type %s", storeType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if storeName != storeType {
+		return nil, fmt.Errorf("%s configures a %s store", location, storeName)
+	}
+	return store, nil
+}
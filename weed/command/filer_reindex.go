@@ -0,0 +1,54 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/filer/flat_namespace"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+func init() {
+	cmdFilerReindex.Run = runFilerReindex // break init cycle
+}
+
+var cmdFilerReindex = &Command{
+	UsageLine: "filer.reindex -store=mysql:/etc/filer.conf",
+	Short:     "index the existing files of a filer store in their directories",
+	Long: `Scan all the files of a mysql, cassandra or redis filer store, and index each
+  one in its directory, so the files written before the stores indexed their
+  directories are listed, moved and deleted with them.
+
+  The store is given as <type>:<location>, as for filer.migrate:
+    mysql:<filer.conf>
+    cassandra:<host[:port]>/<keyspace>
+    redis:[password@]<host:port>[/database]
+
+  The files are indexed again if already indexed, so the pass can be run again
+  after a failure. The filers may keep running during the pass.
+
+  `,
+}
+
+var (
+	filerReindexStore = cmdFilerReindex.Flag.String("store", "", "the filer store, <type>:<location>")
+)
+
+func runFilerReindex(cmd *Command, args []string) bool {
+	if *filerReindexStore == "" {
+		return false
+	}
+	store, err := openFilerStore(*filerReindexStore)
+	if err != nil {
+		glog.Fatalf("Open filer store %s: %v", *filerReindexStore, err)
+	}
+	f, ok := store.(*flat_namespace.FlatNamespaceFiler)
+	if !ok {
+		glog.Fatalf("%s indexes its directories already", *filerReindexStore)
+	}
+	files, err := f.Reindex()
+	if err != nil {
+		glog.Fatalf("Reindex after %d files: %v", files, err)
+	}
+	fmt.Printf("indexed %d files of %s\n", files, *filerReindexStore)
+	return true
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/server"
+	stats_collect "github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 	"github.com/gorilla/mux"
 	"github.com/soheilhy/cmux"
@@ -44,12 +47,32 @@ var (
 	// mTimeout                = cmdMaster.Flag.Int("idleTimeout", 30, "connection idle seconds")
 	mMaxCpu               = cmdMaster.Flag.Int("maxCpu", 0, "maximum number of CPUs. 0 means all available CPUs")
 	garbageThreshold      = cmdMaster.Flag.String("garbageThreshold", "0.3", "threshold to vacuum and reclaim spaces")
+	mVacuumThresholds     = cmdMaster.Flag.String("vacuum.collectionThresholds", "", "comma separated collection:threshold pairs, overriding -garbageThreshold, e.g. logs:0.5,pictures:0.2")
+	mVacuumWindows        = cmdMaster.Flag.String("vacuum.windows", "", "comma separated local time ranges to vacuum in, e.g. 01:00-05:00,22:00-23:30. Any time if empty.")
+	mVacuumInterval       = cmdMaster.Flag.Int("vacuum.intervalMinutes", 15, "minutes between checking volumes to vacuum")
+	mVacuumConcurrency    = cmdMaster.Flag.Int("vacuum.concurrencyPerNode", 1, "volumes vacuumed at the same time on each volume server")
 	masterWhiteListOption = cmdMaster.Flag.String("whiteList", "", "comma separated Ip addresses having write permission. No limit if empty.")
 	masterSecureKey       = cmdMaster.Flag.String("secure.secret", "", "secret to encrypt Json Web Token(JWT)")
+	masterReadSecureKey   = cmdMaster.Flag.String("secure.read.secret", "", "secret to sign read tokens. Reads on volume servers are not checked if empty.")
+	masterReadExpire      = cmdMaster.Flag.Int("secure.read.expireSeconds", 60, "seconds read tokens returned by /dir/lookup are valid for")
+	masterReadCollections = cmdMaster.Flag.String("secure.read.collections", "", "comma separated collections requiring read tokens. All collections if empty.")
 	masterCpuProfile      = cmdMaster.Flag.String("cpuprofile", "", "cpu profile output file")
 	masterMemProfile      = cmdMaster.Flag.String("memprofile", "", "memory profile output file")
-
-	masterWhiteList []string
+	masterMetricsAddress  = cmdMaster.Flag.String("metrics.address", "", "Prometheus push gateway address. Metrics are only served on /metrics if empty.")
+	masterMetricsInterval = cmdMaster.Flag.Int("metrics.intervalSeconds", 15, "Prometheus push interval in seconds")
+	masterTracingEndpoint = cmdMaster.Flag.String("tracing.endpoint", "", "OTLP/HTTP collector address to export traces to, e.g. localhost:4318. Tracing is disabled if empty.")
+	masterTracingSample   = cmdMaster.Flag.Float64("tracing.sampleRate", 0.01, "fraction of requests to trace")
+	masterTlsCert         = cmdMaster.Flag.String("tls.cert", "", "certificate file to serve https and grpc with TLS")
+	masterTlsKey          = cmdMaster.Flag.String("tls.key", "", "private key file of the certificate")
+	masterTlsCaCert       = cmdMaster.Flag.String("tls.caCert", "", "CA certificate file to verify servers and to require client certificates, for mutual TLS")
+	masterCredentials     = cmdMaster.Flag.String("credentials", "", "json file of named api keys and their permissions, reloaded when changed. Only the white list is checked if empty.")
+	masterApiKey          = cmdMaster.Flag.String("credentials.apiKey", "", "api key this server sends to volume servers requiring credentials")
+	masterSequencer       = cmdMaster.Flag.String("sequencer", "memory", sequencerUsage)
+	masterPlacement       = cmdMaster.Flag.String("placement", topology.PlacementRandom, placementUsage)
+	masterSnowflakeId     = cmdMaster.Flag.Int("sequencer.snowflakeId", -1, "node id 0~1023 of the snowflake sequencer, unique among masters. Required with -peers.")
+
+	masterWhiteList          []string
+	masterPrivateCollections []string
 )
 
 func runMaster(cmd *Command, args []string) bool {
@@ -65,17 +88,34 @@ func runMaster(cmd *Command, args []string) bool {
 	if *masterWhiteListOption != "" {
 		masterWhiteList = strings.Split(*masterWhiteListOption, ",")
 	}
+	if *masterReadCollections != "" {
+		masterPrivateCollections = strings.Split(*masterReadCollections, ",")
+	}
 	if *volumeSizeLimitMB > 30*1000 {
 		glog.Fatalf("volumeSizeLimitMB should be smaller than 30000")
 	}
 
+	serverTLS, _ := setupTLS(*masterTlsCert, *masterTlsKey, *masterTlsCaCert)
+	if *masterSecureKey != "" && serverTLS == nil {
+		glog.Warningf("-secure.secret is sent to volume servers in clear text without -tls.cert")
+	}
+
+	credentials := loadCredentials(*masterCredentials, *masterApiKey)
+
 	r := mux.NewRouter()
 	ms := weed_server.NewMasterServer(r, *mport, *metaFolder,
+		newSequencer(*masterSequencer, *metaFolder, *masterSnowflakeId, *masterPeers),
 		*volumeSizeLimitMB, *volumePreallocate,
-		*mpulse, *defaultReplicaPlacement, *garbageThreshold,
+		*mpulse, *defaultReplicaPlacement, newPlacementStrategy(*masterPlacement),
+		vacuumOptions(*garbageThreshold, *mVacuumThresholds, *mVacuumWindows, *mVacuumInterval, *mVacuumConcurrency),
 		masterWhiteList, *masterSecureKey,
+		*masterReadSecureKey, *masterReadExpire, masterPrivateCollections,
+		credentials,
 	)
 
+	stats_collect.StartPushingMetric("master", *masterIp+":"+strconv.Itoa(*mport), *masterMetricsAddress, *masterMetricsInterval)
+	tracing.Init("weed-master", *masterTracingEndpoint, *masterTracingSample)
+
 	listeningAddress := *masterBindIp + ":" + strconv.Itoa(*mport)
 
 	glog.V(0).Infoln("Start Seaweed Master", util.VERSION, "at", listeningAddress)
@@ -84,6 +124,7 @@ func runMaster(cmd *Command, args []string) bool {
 	if e != nil {
 		glog.Fatalf("Master startup error: %v", e)
 	}
+	listener = withTLS(listener, serverTLS)
 
 	go func() {
 		time.Sleep(100 * time.Millisecond)
@@ -103,7 +144,7 @@ func runMaster(cmd *Command, args []string) bool {
 	httpL := m.Match(cmux.Any())
 
 	// Create your protocol servers.
-	grpcS := grpc.NewServer()
+	grpcS := grpc.NewServer(grpc.UnaryInterceptor(tracing.UnaryServerInterceptor()))
 	master_pb.RegisterSeaweedServer(grpcS, ms)
 	reflection.Register(grpcS)
 
@@ -118,3 +159,20 @@ func runMaster(cmd *Command, args []string) bool {
 
 	return true
 }
+
+func vacuumOptions(garbageThreshold, collectionThresholds, windows string, intervalMinutes, concurrencyPerNode int) topology.VacuumOptions {
+	options := topology.NewVacuumOptions(garbageThreshold)
+	var err error
+	if options.CollectionThresholds, err = topology.ParseCollectionThresholds(collectionThresholds); err != nil {
+		glog.Fatalf("-vacuum.collectionThresholds: %v", err)
+	}
+	if options.Windows, err = topology.ParseVacuumWindows(windows); err != nil {
+		glog.Fatalf("-vacuum.windows: %v", err)
+	}
+	if intervalMinutes < 1 || concurrencyPerNode < 1 {
+		glog.Fatalf("-vacuum.intervalMinutes and -vacuum.concurrencyPerNode should be at least 1")
+	}
+	options.Interval = time.Duration(intervalMinutes) * time.Minute
+	options.ConcurrencyPerNode = concurrencyPerNode
+	return options
+}
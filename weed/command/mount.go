@@ -3,6 +3,11 @@ package command
 type MountOptions struct {
 	filer *string
 	dir   *string
+
+	tlsCert   *string
+	tlsKey    *string
+	tlsCaCert *string
+	apiKey    *string
 }
 
 var (
@@ -14,6 +19,10 @@ func init() {
 	cmdMount.IsDebug = cmdMount.Flag.Bool("debug", false, "verbose debug information")
 	mountOptions.filer = cmdMount.Flag.String("filer", "localhost:8888", "weed filer location")
 	mountOptions.dir = cmdMount.Flag.String("dir", ".", "mount weed filer to this directory")
+	mountOptions.tlsCert = cmdMount.Flag.String("tls.cert", "", "client certificate file, if the filer requires mutual TLS")
+	mountOptions.tlsKey = cmdMount.Flag.String("tls.key", "", "private key file of the client certificate")
+	mountOptions.tlsCaCert = cmdMount.Flag.String("tls.caCert", "", "CA certificate file to verify the filer, which enables TLS")
+	mountOptions.apiKey = cmdMount.Flag.String("credentials.apiKey", "", "api key sent to the volume servers, if they require credentials")
 }
 
 var cmdMount = &Command{
@@ -20,6 +20,9 @@ func runMount(cmd *Command, args []string) bool {
 		return false
 	}
 
+	setupClientTLS(*mountOptions.tlsCert, *mountOptions.tlsKey, *mountOptions.tlsCaCert)
+	util.SetApiKey(*mountOptions.apiKey)
+
 	fuse.Unmount(*mountOptions.dir)
 
 	c, err := fuse.Mount(
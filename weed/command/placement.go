@@ -0,0 +1,17 @@
+package command
+
+import (
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+)
+
+const placementUsage = "how new volumes are placed: random (weighted by free volume slots), leastUsedBytes (on servers storing the least bytes) or spread (evenly per collection). A collection can override it with /col/settings?placement="
+
+// newPlacementStrategy creates the default volume placement strategy of the master.
+func newPlacementStrategy(name string) topology.PlacementStrategy {
+	strategy, err := topology.NewPlacementStrategy(name)
+	if err != nil {
+		glog.Fatalf("volume placement: %v", err)
+	}
+	return strategy
+}
@@ -0,0 +1,39 @@
+package command
+
+import (
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/sequence"
+)
+
+const sequencerUsage = "file id sequencer: memory, file (reserved in -mdir), raft (reserved through the masters' raft log, set on all masters) or snowflake (time based)"
+
+// newSequencer creates the file id sequencer of the master. The snowflake
+// node id may only be left out, i.e. negative, by a master without peers.
+func newSequencer(kind string, metaFolder string, snowflakeId int, peers string) sequence.Sequencer {
+	switch kind {
+	case "memory":
+		return sequence.NewMemorySequencer()
+	case "file":
+		seq, err := sequence.NewFileSequencer(metaFolder)
+		if err != nil {
+			glog.Fatalf("file sequencer in %s: %v", metaFolder, err)
+		}
+		return seq
+	case "raft":
+		return sequence.NewRaftSequencer()
+	case "snowflake":
+		if snowflakeId < 0 {
+			if peers != "" {
+				glog.Fatalf("snowflake sequencer: set a node id unique among the masters %s", peers)
+			}
+			snowflakeId = 0
+		}
+		seq, err := sequence.NewSnowflakeSequencer(snowflakeId)
+		if err != nil {
+			glog.Fatalf("snowflake sequencer: %v", err)
+		}
+		return seq
+	}
+	glog.Fatalf("unknown sequencer %q", kind)
+	return nil
+}
@@ -13,7 +13,10 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/server"
+	stats_collect "github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 	"github.com/gorilla/mux"
 	"github.com/soheilhy/cmux"
@@ -57,10 +60,20 @@ var (
 	serverWhiteListOption         = cmdServer.Flag.String("whiteList", "", "comma separated Ip addresses having write permission. No limit if empty.")
 	serverPeers                   = cmdServer.Flag.String("master.peers", "", "other master nodes in comma separated ip:masterPort list")
 	serverSecureKey               = cmdServer.Flag.String("secure.secret", "", "secret to encrypt Json Web Token(JWT)")
+	serverReadSecureKey           = cmdServer.Flag.String("secure.read.secret", "", "secret to sign read tokens. Reads on volume servers are not checked if empty.")
+	serverReadExpire              = cmdServer.Flag.Int("secure.read.expireSeconds", 60, "seconds read tokens issued by the master and filer are valid for")
+	serverReadCollections         = cmdServer.Flag.String("secure.read.collections", "", "comma separated collections requiring read tokens. All collections if empty.")
 	serverGarbageThreshold        = cmdServer.Flag.String("garbageThreshold", "0.3", "threshold to vacuum and reclaim spaces")
+	serverVacuumThresholds        = cmdServer.Flag.String("vacuum.collectionThresholds", "", "comma separated collection:threshold pairs, overriding -garbageThreshold, e.g. logs:0.5,pictures:0.2")
+	serverVacuumWindows           = cmdServer.Flag.String("vacuum.windows", "", "comma separated local time ranges to vacuum in, e.g. 01:00-05:00,22:00-23:30. Any time if empty.")
+	serverVacuumInterval          = cmdServer.Flag.Int("vacuum.intervalMinutes", 15, "minutes between checking volumes to vacuum")
+	serverVacuumConcurrency       = cmdServer.Flag.Int("vacuum.concurrencyPerNode", 1, "volumes vacuumed at the same time on each volume server")
 	masterPort                    = cmdServer.Flag.Int("master.port", 9333, "master server http listen port")
 	masterMetaFolder              = cmdServer.Flag.String("master.dir", "", "data directory to store meta data, default to same as -dir specified")
 	masterVolumeSizeLimitMB       = cmdServer.Flag.Uint("master.volumeSizeLimitMB", 30*1000, "Master stops directing writes to oversized volumes.")
+	serverSequencer               = cmdServer.Flag.String("master.sequencer", "memory", sequencerUsage)
+	serverPlacement               = cmdServer.Flag.String("master.placement", topology.PlacementRandom, placementUsage)
+	serverSnowflakeId             = cmdServer.Flag.Int("master.sequencer.snowflakeId", -1, "node id 0~1023 of the snowflake sequencer, unique among masters. Required with -master.peers.")
 	masterVolumePreallocate       = cmdServer.Flag.Bool("master.volumePreallocate", false, "Preallocate disk space for volumes.")
 	masterDefaultReplicaPlacement = cmdServer.Flag.String("master.defaultReplicaPlacement", "000", "Default replication type if not specified.")
 	volumePort                    = cmdServer.Flag.Int("volume.port", 8080, "volume server http listen port")
@@ -68,13 +81,30 @@ var (
 	volumeDataFolders             = cmdServer.Flag.String("dir", os.TempDir(), "directories to store data files. dir[,dir]...")
 	volumeMaxDataVolumeCounts     = cmdServer.Flag.String("volume.max", "7", "maximum numbers of volumes, count[,count]...")
 	volumePulse                   = cmdServer.Flag.Int("pulseSeconds", 5, "number of seconds between heartbeats")
-	volumeIndexType               = cmdServer.Flag.String("volume.index", "memory", "Choose [memory|leveldb|boltdb|btree] mode for memory~performance balance.")
+	volumeIndexType               = cmdServer.Flag.String("volume.index", "memory", "Choose [memory|leveldb|boltdb|btree|sorted|sorted.compressed] mode for memory~performance balance. The sorted modes search read-only volumes on disk.")
 	volumeFixJpgOrientation       = cmdServer.Flag.Bool("volume.images.fix.orientation", true, "Adjust jpg orientation when uploading.")
 	volumeReadRedirect            = cmdServer.Flag.Bool("volume.read.redirect", true, "Redirect moved or non-local volumes.")
+	volumeTransformSecret         = cmdServer.Flag.String("volume.images.transform.secret", "", "if set, image transforms need a \"sig\" parameter signed with this secret. Only resizing and rotating are allowed if empty.")
+	volumeImageCacheCollection    = cmdServer.Flag.String("volume.images.cache.collection", "image_variants", "collection to cache the transformed images in. Empty to disable the cache.")
+	volumeImageCacheMB            = cmdServer.Flag.Int("volume.images.cache.sizeMB", 1024, "evict the oldest transformed images once the cache is over this size.")
+	volumeImageCacheMaxAge        = cmdServer.Flag.Int("volume.images.cache.maxAgeHours", 720, "evict the transformed images older than this.")
+	volumeCompactionMBPerSecond   = cmdServer.Flag.Int("volume.compactionMBps", 0, "limit background compaction speed in mega bytes per second. 0 means unlimited.")
+	volumeReadMmap                = cmdServer.Flag.Bool("volume.read.mmap", false, "read the read-only volumes through mmap.")
+	volumeReadCacheMB             = cmdServer.Flag.Int("volume.read.cache.sizeMB", 0, "cache the most recently read needles up to this size in memory. 0 disables the cache.")
 	volumeServerPublicUrl         = cmdServer.Flag.String("volume.publicUrl", "", "publicly accessible address")
 	isStartingFiler               = cmdServer.Flag.Bool("filer", false, "whether to start filer")
-
-	serverWhiteList []string
+	serverMetricsAddress          = cmdServer.Flag.String("metrics.address", "", "Prometheus push gateway address. Metrics are only served on /metrics if empty.")
+	serverMetricsInterval         = cmdServer.Flag.Int("metrics.intervalSeconds", 15, "Prometheus push interval in seconds")
+	serverTracingEndpoint         = cmdServer.Flag.String("tracing.endpoint", "", "OTLP/HTTP collector address to export traces to, e.g. localhost:4318. Tracing is disabled if empty.")
+	serverTracingSampleRate       = cmdServer.Flag.Float64("tracing.sampleRate", 0.01, "fraction of requests to trace")
+	serverTlsCert                 = cmdServer.Flag.String("tls.cert", "", "certificate file to serve https and grpc with TLS on all ports")
+	serverTlsKey                  = cmdServer.Flag.String("tls.key", "", "private key file of the certificate")
+	serverTlsCaCert               = cmdServer.Flag.String("tls.caCert", "", "CA certificate file to verify servers and to require client certificates, for mutual TLS")
+	serverCredentials             = cmdServer.Flag.String("credentials", "", "json file of named api keys and their permissions, reloaded when changed. Only the white list is checked if empty.")
+	serverApiKey                  = cmdServer.Flag.String("credentials.apiKey", "", "api key the servers send to each other, and to other servers requiring credentials")
+
+	serverWhiteList          []string
+	serverPrivateCollections []string
 )
 
 func init() {
@@ -94,11 +124,14 @@ func init() {
 	filerOptions.redis_server = cmdServer.Flag.String("filer.redis.server", "", "host:port of the redis server, e.g., 127.0.0.1:6379")
 	filerOptions.redis_password = cmdServer.Flag.String("filer.redis.password", "", "redis password in clear text")
 	filerOptions.redis_database = cmdServer.Flag.Int("filer.redis.database", 0, "the database on the redis server")
+	filerOptions.leveldbStore = cmdServer.Flag.Bool("filer.leveldb", false, "store the meta data in a LevelDB with full path keys, in the leveldb sub directory of -filer.dir")
 	filerOptions.syncFile = cmdServer.Flag.String("sync.file.url", "", "sync file url")
 }
 
 func runServer(cmd *Command, args []string) bool {
 	filerOptions.secretKey = serverSecureKey
+	filerOptions.readSecretKey = serverReadSecureKey
+	filerOptions.readExpireSeconds = serverReadExpire
 	if *serverOptions.cpuprofile != "" {
 		f, err := os.Create(*serverOptions.cpuprofile)
 		if err != nil {
@@ -170,6 +203,19 @@ func runServer(cmd *Command, args []string) bool {
 	if *serverWhiteListOption != "" {
 		serverWhiteList = strings.Split(*serverWhiteListOption, ",")
 	}
+	if *serverReadCollections != "" {
+		serverPrivateCollections = strings.Split(*serverReadCollections, ",")
+	}
+
+	stats_collect.StartPushingMetric("server", *serverIp+":"+strconv.Itoa(*masterPort), *serverMetricsAddress, *serverMetricsInterval)
+	tracing.Init("weed-server", *serverTracingEndpoint, *serverTracingSampleRate)
+	serverTLS, publicTLS := setupTLS(*serverTlsCert, *serverTlsKey, *serverTlsCaCert)
+	if *serverSecureKey != "" && serverTLS == nil {
+		glog.Warningf("-secure.secret is sent to volume servers in clear text without -tls.cert")
+	}
+	filerOptions.serverTLS, filerOptions.publicTLS = serverTLS, publicTLS
+	credentials := loadCredentials(*serverCredentials, *serverApiKey)
+	filerOptions.credentials = credentials
 
 	if *isStartingFiler {
 		go func() {
@@ -189,9 +235,13 @@ func runServer(cmd *Command, args []string) bool {
 	go func() {
 		r := mux.NewRouter()
 		ms := weed_server.NewMasterServer(r, *masterPort, *masterMetaFolder,
+			newSequencer(*serverSequencer, *masterMetaFolder, *serverSnowflakeId, *serverPeers),
 			*masterVolumeSizeLimitMB, *masterVolumePreallocate,
-			*volumePulse, *masterDefaultReplicaPlacement, *serverGarbageThreshold,
+			*volumePulse, *masterDefaultReplicaPlacement, newPlacementStrategy(*serverPlacement),
+			vacuumOptions(*serverGarbageThreshold, *serverVacuumThresholds, *serverVacuumWindows, *serverVacuumInterval, *serverVacuumConcurrency),
 			serverWhiteList, *serverSecureKey,
+			*serverReadSecureKey, *serverReadExpire, serverPrivateCollections,
+			credentials,
 		)
 
 		glog.V(0).Infoln("Start Seaweed Master", util.VERSION, "at", *serverIp+":"+strconv.Itoa(*masterPort))
@@ -199,6 +249,7 @@ func runServer(cmd *Command, args []string) bool {
 		if e != nil {
 			glog.Fatalf("Master startup error: %v", e)
 		}
+		masterListener = withTLS(masterListener, serverTLS)
 
 		go func() {
 			raftWaitForMaster.Wait()
@@ -222,7 +273,7 @@ func runServer(cmd *Command, args []string) bool {
 		httpL := m.Match(cmux.Any())
 
 		// Create your protocol servers.
-		grpcS := grpc.NewServer()
+		grpcS := grpc.NewServer(grpc.UnaryInterceptor(tracing.UnaryServerInterceptor()))
 		master_pb.RegisterSeaweedServer(grpcS, ms)
 		reflection.Register(grpcS)
 
@@ -259,13 +310,20 @@ func runServer(cmd *Command, args []string) bool {
 		volumeNeedleMapKind = storage.NeedleMapBoltDb
 	case "btree":
 		volumeNeedleMapKind = storage.NeedleMapBtree
+	case "sorted":
+		volumeNeedleMapKind = storage.NeedleMapSorted
+	case "sorted.compressed":
+		volumeNeedleMapKind = storage.NeedleMapSortedCompressed
 	}
 	volumeServer := weed_server.NewVolumeServer(volumeMux, publicVolumeMux,
 		*serverIp, *volumePort, *volumeServerPublicUrl,
 		folders, maxCounts,
 		volumeNeedleMapKind,
 		*serverIp+":"+strconv.Itoa(*masterPort), *volumePulse, *serverDataCenter, *serverRack,
-		serverWhiteList, *volumeFixJpgOrientation, *volumeReadRedirect,
+		serverWhiteList, credentials, *volumeFixJpgOrientation, *volumeReadRedirect, *volumeTransformSecret,
+		*volumeImageCacheCollection, *volumeImageCacheMB, *volumeImageCacheMaxAge,
+		*volumeCompactionMBPerSecond,
+		*volumeReadMmap, *volumeReadCacheMB,
 	)
 
 	glog.V(0).Infoln("Start Seaweed volume server", util.VERSION, "at", *serverIp+":"+strconv.Itoa(*volumePort))
@@ -276,6 +334,7 @@ func runServer(cmd *Command, args []string) bool {
 	if eListen != nil {
 		glog.Fatalf("Volume server listener error: %v", eListen)
 	}
+	volumeListener = withTLS(volumeListener, serverTLS)
 	if isSeperatedPublicPort {
 		publicListeningAddress := *serverIp + ":" + strconv.Itoa(*volumePublicPort)
 		glog.V(0).Infoln("Start Seaweed volume server", util.VERSION, "public at", publicListeningAddress)
@@ -283,6 +342,7 @@ func runServer(cmd *Command, args []string) bool {
 		if e != nil {
 			glog.Fatalf("Volume server listener error:%v", e)
 		}
+		publicListener = withTLS(publicListener, publicTLS)
 		go func() {
 			if e := http.Serve(publicListener, publicVolumeMux); e != nil {
 				glog.Fatalf("Volume server fail to serve public: %v", e)
@@ -3,9 +3,14 @@ package command
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
 func init() {
@@ -13,16 +18,43 @@ func init() {
 }
 
 var cmdShell = &Command{
-	UsageLine: "shell",
-	Short:     "run interactive commands, now just echo",
-	Long: `run interactive commands.
+	UsageLine: "shell -master=localhost:9333",
+	Short:     "run interactive administrative commands",
+	Long: `run interactive administrative commands against the master cluster.
+
+  cluster.status                 show the leader and the masters in the raft cluster
+  cluster.add <host:port>        add a running master to the raft cluster
+  cluster.remove <host:port>     remove a master from the raft cluster
+  volume.move <volumeId> <source host:port> <target host:port>
+                                 move a volume replica to another volume server
+
+  A master is only added or removed if the masters still live after the
+  change can reach the quorum. The leader can not be removed; stop it
+  first so another master is elected.
 
   `,
 }
 
-var ()
+var (
+	shellMaster = cmdShell.Flag.String("master", "localhost:9333", "a master server of the cluster")
+	shellApiKey = cmdShell.Flag.String("credentials.apiKey", "", "api key with admin permission, if the master requires credentials")
+)
+
+type shellCommand struct {
+	args string
+	run  func(args []string) error
+}
+
+var shellCommands = map[string]shellCommand{
+	"cluster.status": {"", shellClusterStatus},
+	"cluster.add":    {"<host:port>", shellClusterAdd},
+	"cluster.remove": {"<host:port>", shellClusterRemove},
+	"volume.move":    {"<volumeId> <source host:port> <target host:port>", shellVolumeMove},
+}
 
 func runShell(command *Command, args []string) bool {
+	util.SetApiKey(*shellApiKey)
+
 	r := bufio.NewReader(os.Stdin)
 	o := bufio.NewWriter(os.Stdout)
 	e := bufio.NewWriter(os.Stderr)
@@ -37,17 +69,39 @@ func runShell(command *Command, args []string) bool {
 	}
 	readLine := func() string {
 		ret, err := r.ReadString('\n')
-		if err != nil {
+		if err == io.EOF && ret == "" {
+			os.Exit(0)
+		}
+		if err != nil && err != io.EOF {
 			fmt.Fprint(e, err)
+			e.Flush()
 			os.Exit(1)
 		}
 		return ret
 	}
 	execCmd := func(cmd string) int {
-		if cmd != "" {
-			if _, err := o.WriteString(cmd); err != nil {
-				glog.V(0).Infoln("error writing to stdout:", err)
+		fields := strings.Fields(cmd)
+		if len(fields) == 0 {
+			return 0
+		}
+		c, found := shellCommands[fields[0]]
+		if !found {
+			fmt.Fprintf(e, "unknown command %s, available commands:\n", fields[0])
+			var names []string
+			for name := range shellCommands {
+				names = append(names, name)
 			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(e, "  %s %s\n", name, shellCommands[name].args)
+			}
+			e.Flush()
+			return 1
+		}
+		if err := c.run(fields[1:]); err != nil {
+			fmt.Fprintf(e, "%s: %v\n", fields[0], err)
+			e.Flush()
+			return 1
 		}
 		return 0
 	}
@@ -59,3 +113,36 @@ func runShell(command *Command, args []string) bool {
 		execCmd(cmd)
 	}
 }
+
+func shellClusterStatus(args []string) error {
+	leader, peers, err := operation.ListMasters(*shellMaster)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("leader: %s\n", leader)
+	for _, peer := range peers {
+		fmt.Printf("master: %s\n", peer)
+	}
+	return nil
+}
+
+func shellClusterAdd(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: cluster.add <host:port>")
+	}
+	return operation.AddMaster(*shellMaster, args[0])
+}
+
+func shellClusterRemove(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: cluster.remove <host:port>")
+	}
+	return operation.RemoveMaster(*shellMaster, args[0])
+}
+
+func shellVolumeMove(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: volume.move <volumeId> <source host:port> <target host:port>")
+	}
+	return operation.MoveVolume(*shellMaster, args[0], args[1], args[2])
+}
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"crypto/tls"
+	"net"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// setupTLS loads the certificates given on the command line, and makes all
+// outgoing http and grpc connections of this process use TLS.
+// The returned configs are nil if no certificate is given.
+// publicTLS does not require client certificates.
+func setupTLS(certFile, keyFile, caFile string) (serverTLS, publicTLS *tls.Config) {
+	if certFile == "" {
+		if caFile != "" {
+			glog.Fatalf("-tls.caCert requires -tls.cert and -tls.key")
+		}
+		return nil, nil
+	}
+	serverTLS, err := security.LoadServerTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		glog.Fatalf("TLS setup error: %v", err)
+	}
+	clientTLS, err := security.LoadClientTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		glog.Fatalf("TLS setup error: %v", err)
+	}
+	security.SetClientTLSConfig(clientTLS)
+	util.EnableTLS(clientTLS)
+
+	publicTLS = serverTLS.Clone()
+	publicTLS.ClientAuth = tls.NoClientCert
+	publicTLS.ClientCAs = nil
+
+	if caFile != "" {
+		glog.V(0).Infof("mutual TLS enabled with certificate %s and CA %s", certFile, caFile)
+	} else {
+		glog.V(0).Infof("TLS enabled with certificate %s", certFile)
+	}
+	return serverTLS, publicTLS
+}
+
+// setupClientTLS makes the outgoing http and grpc connections use TLS,
+// for commands only connecting to servers. The client certificate is
+// optional unless the servers require mutual TLS.
+func setupClientTLS(certFile, keyFile, caFile string) {
+	if certFile == "" && caFile == "" {
+		return
+	}
+	clientTLS, err := security.LoadClientTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		glog.Fatalf("TLS setup error: %v", err)
+	}
+	security.SetClientTLSConfig(clientTLS)
+	util.EnableTLS(clientTLS)
+}
+
+func withTLS(listener net.Listener, config *tls.Config) net.Listener {
+	if config == nil {
+		return listener
+	}
+	return tls.NewListener(listener, config)
+}
@@ -10,7 +10,9 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/server"
+	stats_collect "github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
@@ -36,8 +38,24 @@ type VolumeServerOptions struct {
 	indexType             *string
 	fixJpgOrientation     *bool
 	readRedirect          *bool
+	transformSecret       *string
+	imageCacheCollection  *string
+	imageCacheMB          *int
+	imageCacheMaxAge      *int
+	compactionMBPerSecond *int
+	readMmap              *bool
+	readCacheMB           *int
 	cpuProfile            *string
 	memProfile            *string
+	metricsAddress        *string
+	metricsInterval       *int
+	tracingEndpoint       *string
+	tracingSampleRate     *float64
+	tlsCert               *string
+	tlsKey                *string
+	tlsCaCert             *string
+	credentialsFile       *string
+	apiKey                *string
 }
 
 func init() {
@@ -53,11 +71,27 @@ func init() {
 	v.maxCpu = cmdVolume.Flag.Int("maxCpu", 0, "maximum number of CPUs. 0 means all available CPUs")
 	v.dataCenter = cmdVolume.Flag.String("dataCenter", "", "current volume server's data center name")
 	v.rack = cmdVolume.Flag.String("rack", "", "current volume server's rack name")
-	v.indexType = cmdVolume.Flag.String("index", "memory", "Choose [memory|leveldb|boltdb|btree] mode for memory~performance balance.")
+	v.indexType = cmdVolume.Flag.String("index", "memory", "Choose [memory|leveldb|boltdb|btree|sorted|sorted.compressed] mode for memory~performance balance. The sorted modes search read-only volumes on disk.")
 	v.fixJpgOrientation = cmdVolume.Flag.Bool("images.fix.orientation", true, "Adjust jpg orientation when uploading.")
 	v.readRedirect = cmdVolume.Flag.Bool("read.redirect", true, "Redirect moved or non-local volumes.")
+	v.transformSecret = cmdVolume.Flag.String("images.transform.secret", "", "if set, image transforms need a \"sig\" parameter signed with this secret. Only resizing and rotating are allowed if empty.")
+	v.imageCacheCollection = cmdVolume.Flag.String("images.cache.collection", "image_variants", "collection to cache the transformed images in. Empty to disable the cache.")
+	v.imageCacheMB = cmdVolume.Flag.Int("images.cache.sizeMB", 1024, "evict the oldest transformed images once the cache is over this size.")
+	v.imageCacheMaxAge = cmdVolume.Flag.Int("images.cache.maxAgeHours", 720, "evict the transformed images older than this.")
+	v.compactionMBPerSecond = cmdVolume.Flag.Int("compactionMBps", 0, "limit background compaction speed in mega bytes per second. 0 means unlimited.")
+	v.readMmap = cmdVolume.Flag.Bool("read.mmap", false, "read the read-only volumes through mmap.")
+	v.readCacheMB = cmdVolume.Flag.Int("read.cache.sizeMB", 0, "cache the most recently read needles up to this size in memory. 0 disables the cache.")
 	v.cpuProfile = cmdVolume.Flag.String("cpuprofile", "", "cpu profile output file")
 	v.memProfile = cmdVolume.Flag.String("memprofile", "", "memory profile output file")
+	v.metricsAddress = cmdVolume.Flag.String("metrics.address", "", "Prometheus push gateway address. Metrics are only served on /metrics if empty.")
+	v.metricsInterval = cmdVolume.Flag.Int("metrics.intervalSeconds", 15, "Prometheus push interval in seconds")
+	v.tracingEndpoint = cmdVolume.Flag.String("tracing.endpoint", "", "OTLP/HTTP collector address to export traces to, e.g. localhost:4318. Tracing is disabled if empty.")
+	v.tracingSampleRate = cmdVolume.Flag.Float64("tracing.sampleRate", 0.01, "fraction of requests to trace")
+	v.tlsCert = cmdVolume.Flag.String("tls.cert", "", "certificate file to serve https and to connect to the master with TLS")
+	v.tlsKey = cmdVolume.Flag.String("tls.key", "", "private key file of the certificate")
+	v.tlsCaCert = cmdVolume.Flag.String("tls.caCert", "", "CA certificate file to verify servers and to require client certificates, for mutual TLS")
+	v.credentialsFile = cmdVolume.Flag.String("credentials", "", "json file of named api keys and their permissions, reloaded when changed. Only the white list is checked if empty.")
+	v.apiKey = cmdVolume.Flag.String("credentials.apiKey", "", "api key this server sends to the master and other volume servers requiring credentials")
 }
 
 var cmdVolume = &Command{
@@ -122,6 +156,9 @@ func runVolume(cmd *Command, args []string) bool {
 		publicVolumeMux = http.NewServeMux()
 	}
 
+	serverTLS, publicTLS := setupTLS(*v.tlsCert, *v.tlsKey, *v.tlsCaCert)
+	credentials := loadCredentials(*v.credentialsFile, *v.apiKey)
+
 	volumeNeedleMapKind := storage.NeedleMapInMemory
 	switch *v.indexType {
 	case "leveldb":
@@ -130,22 +167,33 @@ func runVolume(cmd *Command, args []string) bool {
 		volumeNeedleMapKind = storage.NeedleMapBoltDb
 	case "btree":
 		volumeNeedleMapKind = storage.NeedleMapBtree
+	case "sorted":
+		volumeNeedleMapKind = storage.NeedleMapSorted
+	case "sorted.compressed":
+		volumeNeedleMapKind = storage.NeedleMapSortedCompressed
 	}
 	volumeServer := weed_server.NewVolumeServer(volumeMux, publicVolumeMux,
 		*v.ip, *v.port, *v.publicUrl,
 		v.folders, v.folderMaxLimits,
 		volumeNeedleMapKind,
 		*v.master, *v.pulseSeconds, *v.dataCenter, *v.rack,
-		v.whiteList,
-		*v.fixJpgOrientation, *v.readRedirect,
+		v.whiteList, credentials,
+		*v.fixJpgOrientation, *v.readRedirect, *v.transformSecret,
+		*v.imageCacheCollection, *v.imageCacheMB, *v.imageCacheMaxAge,
+		*v.compactionMBPerSecond,
+		*v.readMmap, *v.readCacheMB,
 	)
 
+	stats_collect.StartPushingMetric("volumeServer", *v.ip+":"+strconv.Itoa(*v.port), *v.metricsAddress, *v.metricsInterval)
+	tracing.Init("weed-volume", *v.tracingEndpoint, *v.tracingSampleRate)
+
 	listeningAddress := *v.bindIp + ":" + strconv.Itoa(*v.port)
 	glog.V(0).Infoln("Start Seaweed volume server", util.VERSION, "at", listeningAddress)
 	listener, e := util.NewListener(listeningAddress, time.Duration(*v.idleConnectionTimeout)*time.Second)
 	if e != nil {
 		glog.Fatalf("Volume server listener error:%v", e)
 	}
+	listener = withTLS(listener, serverTLS)
 	if isSeperatedPublicPort {
 		publicListeningAddress := *v.bindIp + ":" + strconv.Itoa(*v.publicPort)
 		glog.V(0).Infoln("Start Seaweed volume server", util.VERSION, "public at", publicListeningAddress)
@@ -153,6 +201,7 @@ func runVolume(cmd *Command, args []string) bool {
 		if e != nil {
 			glog.Fatalf("Volume server listener error:%v", e)
 		}
+		publicListener = withTLS(publicListener, publicTLS)
 		go func() {
 			if e := http.Serve(publicListener, publicVolumeMux); e != nil {
 				glog.Fatalf("Volume server fail to serve public: %v", e)
@@ -17,10 +17,17 @@ CREATE TABLE seaweed_files (
    fids list<varchar>,
    PRIMARY KEY (path)
 );
+and one to index the entries of each directory, clustered by name:
+CREATE TABLE seaweed_entries (
+   parent varchar,
+   is_directory boolean,
+   name varchar,
+   fid varchar,
+   PRIMARY KEY ((parent, is_directory), name)
+);
+The store creates seaweed_entries if missing, for the keyspaces created before it.
+Their existing files are indexed by "weed filer.reindex".
 Need to match flat_namespace.FlatNamespaceStore interface
-	Put(fullFileName string, fid string) (err error)
-	Get(fullFileName string) (fid string, err error)
-	Delete(fullFileName string) (fid string, err error)
 */
 type CassandraStore struct {
 	cluster *gocql.ClusterConfig
@@ -41,10 +48,23 @@ func NewCassandraStore(keyspace string, hosts string) (c *CassandraStore, err er
 	c.session, err = c.cluster.CreateSession()
 	if err != nil {
 		glog.V(0).Infof("Failed to open cassandra store, hosts %v, keyspace %s", hosts, keyspace)
+		return
+	}
+	if err = c.session.Query(createEntriesTable).Exec(); err != nil {
+		c.session.Close()
+		return nil, fmt.Errorf("create table seaweed_entries in keyspace %s, or create it as in schema.cql: %v", keyspace, err)
 	}
 	return
 }
 
+const createEntriesTable = `CREATE TABLE IF NOT EXISTS seaweed_entries (
+   parent varchar,
+   is_directory boolean,
+   name varchar,
+   fid varchar,
+   PRIMARY KEY ((parent, is_directory), name)
+)`
+
 func (c *CassandraStore) Put(fullFileName string, fid string) (err error) {
 	var input []string
 	input = append(input, fid)
@@ -61,10 +81,11 @@ func (c *CassandraStore) Get(fullFileName string) (fid string, err error) {
 	if err := c.session.Query(
 		`select fids FROM seaweed_files WHERE path = ? LIMIT 1`,
 		fullFileName).Consistency(gocql.One).Scan(&output); err != nil {
-		if err != gocql.ErrNotFound {
-			glog.V(0).Infof("Failed to find file %s: %v", fullFileName, fid, err)
+		if err == gocql.ErrNotFound {
 			return "", filer.ErrNotFound
 		}
+		glog.V(0).Infof("Failed to find file %s: %v", fullFileName, err)
+		return "", err
 	}
 	if len(output) == 0 {
 		return "", fmt.Errorf("No file id found for %s", fullFileName)
@@ -85,6 +106,69 @@ func (c *CassandraStore) Delete(fullFileName string) (err error) {
 	return nil
 }
 
+func (c *CassandraStore) PutEntry(dirPath string, name string, fid string, isDirectory bool) (err error) {
+	if err = c.session.Query(
+		`INSERT INTO seaweed_entries (parent, is_directory, name, fid) VALUES (?, ?, ?, ?)`,
+		dirPath, isDirectory, name, fid).Exec(); err != nil {
+		glog.V(0).Infof("Failed to index %s in %s: %v", name, dirPath, err)
+	}
+	return err
+}
+
+func (c *CassandraStore) DeleteEntry(dirPath string, name string, isDirectory bool) (err error) {
+	return c.session.Query(
+		`DELETE FROM seaweed_entries WHERE parent = ? AND is_directory = ? AND name = ?`,
+		dirPath, isDirectory, name).Exec()
+}
+
+func (c *CassandraStore) HasEntry(dirPath string, name string, isDirectory bool) (found bool, err error) {
+	var fid string
+	if err = c.session.Query(
+		`SELECT fid FROM seaweed_entries WHERE parent = ? AND is_directory = ? AND name = ?`,
+		dirPath, isDirectory, name).Scan(&fid); err == gocql.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c *CassandraStore) ListEntries(dirPath string, isDirectory bool, lastName string, limit int) (entries []filer.FileEntry, err error) {
+	cql := `SELECT name, fid FROM seaweed_entries WHERE parent = ? AND is_directory = ? AND name > ?`
+	if limit > 0 {
+		cql += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	iter := c.session.Query(cql, dirPath, isDirectory, lastName).Iter()
+	var name, fid string
+	for iter.Scan(&name, &fid) {
+		entries = append(entries, filer.FileEntry{Name: name, Id: filer.FileId(fid)})
+	}
+	if err = iter.Close(); err != nil {
+		glog.V(0).Infof("Failed to list %s: %v", dirPath, err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Scan visits the full path mappings, paging through the files table.
+func (c *CassandraStore) Scan(visit func(fullFileName string, fid string) error) error {
+	iter := c.session.Query(`SELECT path, fids FROM seaweed_files`).PageSize(1000).Iter()
+	var path string
+	var fids []string
+	for iter.Scan(&path, &fids) {
+		if len(fids) == 0 {
+			continue
+		}
+		if err := visit(path, fids[0]); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	if err := iter.Close(); err != nil {
+		glog.V(0).Infof("Failed to scan seaweed_files: %v", err)
+		return err
+	}
+	return nil
+}
+
 func (c *CassandraStore) Close() {
 	if c.session != nil {
 		c.session.Close()
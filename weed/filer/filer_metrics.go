@@ -0,0 +1,65 @@
+package filer
+
+import (
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/stats"
+)
+
+// FilerWithMetrics wraps a Filer and records the latency of every store operation.
+type FilerWithMetrics struct {
+	storeName string
+	filer     Filer
+}
+
+func NewFilerWithMetrics(storeName string, filer Filer) *FilerWithMetrics {
+	return &FilerWithMetrics{
+		storeName: storeName,
+		filer:     filer,
+	}
+}
+
+func (f *FilerWithMetrics) observe(requestType string, start time.Time) {
+	stats.FilerStoreCounter.WithLabelValues(f.storeName, requestType).Inc()
+	stats.FilerStoreHistogram.WithLabelValues(f.storeName, requestType).Observe(time.Since(start).Seconds())
+}
+
+func (f *FilerWithMetrics) CreateFile(fullFileName string, fid string) (err error) {
+	defer f.observe("createFile", time.Now())
+	return f.filer.CreateFile(fullFileName, fid)
+}
+
+func (f *FilerWithMetrics) FindFile(fullFileName string) (fid string, err error) {
+	defer f.observe("findFile", time.Now())
+	return f.filer.FindFile(fullFileName)
+}
+
+func (f *FilerWithMetrics) DeleteFile(fullFileName string) (fid string, err error) {
+	defer f.observe("deleteFile", time.Now())
+	return f.filer.DeleteFile(fullFileName)
+}
+
+func (f *FilerWithMetrics) ListDirectories(dirPath string) (dirs []DirectoryName, err error) {
+	defer f.observe("listDirectories", time.Now())
+	return f.filer.ListDirectories(dirPath)
+}
+
+func (f *FilerWithMetrics) ListFiles(dirPath string, lastFileName string, limit int) (files []FileEntry, err error) {
+	defer f.observe("listFiles", time.Now())
+	return f.filer.ListFiles(dirPath, lastFileName, limit)
+}
+
+func (f *FilerWithMetrics) DeleteDirectory(dirPath string, recursive bool) (err error) {
+	defer f.observe("deleteDirectory", time.Now())
+	return f.filer.DeleteDirectory(dirPath, recursive)
+}
+
+func (f *FilerWithMetrics) Move(fromPath string, toPath string) (err error) {
+	defer f.observe("move", time.Now())
+	return f.filer.Move(fromPath, toPath)
+}
+
+func (f *FilerWithMetrics) LookupDirectoryEntry(dirPath string, name string) (found bool, fileId string, err error) {
+	defer f.observe("lookupDirectoryEntry", time.Now())
+	return f.filer.LookupDirectoryEntry(dirPath, name)
+}
@@ -0,0 +1,238 @@
+package filer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+)
+
+const walkPageSize = 1000
+
+// Walk visits the files under the directory, depth first: the files of a directory
+// in name order, then its sub directories in name order. Directory paths end with "/".
+// The files of the directories skipped by skipFiles are not listed, but their
+// sub directories still are.
+func Walk(f Filer, dirPath string, skipFiles func(dirPath string) bool,
+	visitFile func(fullPath string, fid string) error,
+	visitedFiles func(dirPath string) error) error {
+	if !strings.HasSuffix(dirPath, "/") {
+		dirPath += "/"
+	}
+	if skipFiles == nil || !skipFiles(dirPath) {
+		lastFileName := ""
+		for {
+			files, err := f.ListFiles(dirPath, lastFileName, walkPageSize)
+			if err != nil {
+				return fmt.Errorf("list files of %s: %v", dirPath, err)
+			}
+			for _, file := range files {
+				if err = visitFile(dirPath+file.Name, string(file.Id)); err != nil {
+					return err
+				}
+			}
+			if len(files) < walkPageSize {
+				break
+			}
+			lastFileName = files[len(files)-1].Name
+		}
+		if visitedFiles != nil {
+			if err := visitedFiles(dirPath); err != nil {
+				return err
+			}
+		}
+	}
+	dirs, err := f.ListDirectories(dirPath)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("list directories of %s: %v", dirPath, err)
+	}
+	names := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		names = append(names, string(dir))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err = Walk(f, dirPath+name+"/", skipFiles, visitFile, visitedFiles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scanner is implemented by the filers visiting all their files in one pass, in
+// no particular order, including the files their directory listings miss.
+// ScanFiles returns ErrScanNotSupported if the underlying store can not scan.
+type Scanner interface {
+	ScanFiles(visit func(fullPath string, fid string) error) error
+}
+
+var ErrScanNotSupported = errors.New("the filer store can not scan its files")
+
+// walkFiles visits all the files of the filer, scanning them if it can,
+// or else walking its directories.
+func walkFiles(f Filer, visitFile func(fullPath string, fid string) error) error {
+	if scanner, ok := f.(Scanner); ok {
+		if err := scanner.ScanFiles(visitFile); err != ErrScanNotSupported {
+			return err
+		}
+	}
+	return Walk(f, "/", nil, visitFile, nil)
+}
+
+/*
+Migrate writes all the files of one filer store into another.
+
+The stores scanning their files are migrated in one pass. The other stores are
+walked directory by directory, and each directory whose files are written is
+appended to the checkpoint file, if set. Its files are skipped when migrating
+again, so an interrupted migration resumes from the directory it stopped in.
+The first line of the checkpoint is the stores description, and a checkpoint
+of other stores is refused. The checkpoint is removed once the migration completes.
+Rewriting a file is harmless, as the stores overwrite the file id of an existing path.
+Empty directories are not migrated.
+*/
+func Migrate(from, to Filer, stores string, checkpoint string, progress func(dirPath string, files int64)) (files int64, err error) {
+	createFile := func(fullPath string, fid string) error {
+		if err := to.CreateFile(fullPath, fid); err != nil {
+			return fmt.Errorf("create %s: %v", fullPath, err)
+		}
+		files++
+		return nil
+	}
+	if scanner, ok := from.(Scanner); ok {
+		err = scanner.ScanFiles(func(fullPath string, fid string) error {
+			if err := createFile(fullPath, fid); err != nil {
+				return err
+			}
+			if progress != nil && files%walkPageSize == 0 {
+				dir, _ := SplitPath(fullPath)
+				progress(dir, files)
+			}
+			return nil
+		})
+		if err != ErrScanNotSupported {
+			return files, err
+		}
+	}
+
+	done := make(map[string]bool)
+	var log *os.File
+	if checkpoint != "" {
+		var found bool
+		if done, found, err = readCheckpoint(checkpoint, stores); err != nil {
+			return 0, err
+		}
+		if log, err = os.OpenFile(checkpoint, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+			return 0, err
+		}
+		defer log.Close()
+		if !found {
+			if _, err = log.WriteString(checkpointHeader(stores)); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	err = Walk(from, "/", func(dirPath string) bool {
+		return done[dirPath]
+	}, createFile, func(dirPath string) error {
+		if progress != nil {
+			progress(dirPath, files)
+		}
+		if log == nil {
+			return nil
+		}
+		_, err := log.WriteString(dirPath + "\n")
+		return err
+	})
+	if err == nil && log != nil {
+		log.Close()
+		err = os.Remove(checkpoint)
+	}
+	return files, err
+}
+
+func checkpointHeader(stores string) string {
+	return "# " + stores + "\n"
+}
+
+// readCheckpoint returns the directories done, after checking the checkpoint
+// is of the stores.
+func readCheckpoint(checkpoint string, stores string) (done map[string]bool, found bool, err error) {
+	done = make(map[string]bool)
+	data, err := os.Open(checkpoint)
+	if os.IsNotExist(err) {
+		return done, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	defer data.Close()
+	scanner := bufio.NewScanner(data)
+	if !scanner.Scan() {
+		return done, false, scanner.Err()
+	}
+	if scanner.Text()+"\n" != checkpointHeader(stores) {
+		return nil, true, fmt.Errorf("checkpoint %s is of another migration: %s", checkpoint, scanner.Text())
+	}
+	for scanner.Scan() {
+		done[scanner.Text()] = true
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, true, fmt.Errorf("read checkpoint %s: %v", checkpoint, err)
+	}
+	return done, true, nil
+}
+
+// VerifyResult compares the files of the migrated stores.
+type VerifyResult struct {
+	SourceFiles int64
+	TargetFiles int64 // -1 if the target store can not list its files
+	TargetError error // why the target store can not list its files
+	Sampled     int
+	Mismatches  []string // the sampled files missing or with another file id in the target
+}
+
+func (r *VerifyResult) Ok() bool {
+	return len(r.Mismatches) == 0 && (r.TargetFiles < 0 || r.TargetFiles == r.SourceFiles)
+}
+
+// Verify counts the files of both stores, and looks up a random sample
+// of the source files in the target.
+func Verify(from, to Filer, samples int) (*VerifyResult, error) {
+	result := &VerifyResult{}
+	type file struct{ path, fid string }
+	var sampled []file
+	// reservoir sampling, for a uniform sample in one pass
+	if err := walkFiles(from, func(fullPath string, fid string) error {
+		result.SourceFiles++
+		if len(sampled) < samples {
+			sampled = append(sampled, file{fullPath, fid})
+		} else if i := rand.Int63n(result.SourceFiles); i < int64(samples) {
+			sampled[i] = file{fullPath, fid}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := walkFiles(to, func(fullPath string, fid string) error {
+		result.TargetFiles++
+		return nil
+	}); err != nil {
+		result.TargetFiles, result.TargetError = -1, err
+	}
+
+	for _, f := range sampled {
+		fid, err := to.FindFile(f.path)
+		if err != nil {
+			result.Mismatches = append(result.Mismatches, fmt.Sprintf("%s: %v", f.path, err))
+		} else if fid != f.fid {
+			result.Mismatches = append(result.Mismatches, fmt.Sprintf("%s: file id %s, expected %s", f.path, fid, f.fid))
+		}
+	}
+	result.Sampled = len(sampled)
+	return result, nil
+}
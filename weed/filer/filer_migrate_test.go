@@ -0,0 +1,76 @@
+package filer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/filer/leveldb_store"
+)
+
+func TestMigrate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "filer_migrate")
+	defer os.RemoveAll(dir)
+	from, err := leveldb_store.NewLevelDbStore("", filepath.Join(dir, "from"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer from.Close()
+	to, err := leveldb_store.NewLevelDbStore("", filepath.Join(dir, "to"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer to.Close()
+
+	for path, fid := range map[string]string{
+		"/a/b/c.txt": "1,01",
+		"/a/b/d.txt": "1,02",
+		"/a/e.txt":   "1,03",
+		"/f.txt":     "1,04",
+	} {
+		if err = from.CreateFile(path, fid); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkpoint := filepath.Join(dir, "checkpoint")
+	stores := "from leveldb:from to leveldb:to"
+	if files, err := filer.Migrate(from, to, stores, checkpoint, nil); err != nil || files != 4 {
+		t.Fatalf("migrated %d files: %v", files, err)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("checkpoint kept after the migration: %v", err)
+	}
+	result, err := filer.Verify(from, to, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Ok() || result.SourceFiles != 4 || result.TargetFiles != 4 || result.Sampled != 2 {
+		t.Errorf("verify: %+v", result)
+	}
+
+	// resuming skips the migrated directories
+	from.CreateFile("/a/b/g/h.txt", "1,05")
+	if err := ioutil.WriteFile(checkpoint, []byte("# "+stores+"\n/\n/a/\n/a/b/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if files, err := filer.Migrate(from, to, "from leveldb:from to leveldb:other", checkpoint, nil); err == nil {
+		t.Errorf("resumed the checkpoint of another migration with %d files", files)
+	}
+	if files, err := filer.Migrate(from, to, stores, checkpoint, nil); err != nil || files != 1 {
+		t.Fatalf("resumed with %d files: %v", files, err)
+	}
+	if fid, err := to.FindFile("/a/b/g/h.txt"); err != nil || fid != "1,05" {
+		t.Errorf("find /a/b/g/h.txt: %s %v", fid, err)
+	}
+
+	to.DeleteFile("/a/e.txt")
+	if result, err = filer.Verify(from, to, 10); err != nil {
+		t.Fatal(err)
+	}
+	if result.Ok() || len(result.Mismatches) != 1 || result.TargetFiles != 4 {
+		t.Errorf("verify with a missing file: %+v", result)
+	}
+}
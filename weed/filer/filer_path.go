@@ -0,0 +1,42 @@
+package filer
+
+import (
+	"path/filepath"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+)
+
+const deleteBatchSize = 100
+
+// CleanPath turns a path into the full path of an entry, "/" for the root.
+func CleanPath(fullPath string) string {
+	return filepath.ToSlash(filepath.Clean("/" + fullPath))
+}
+
+// SplitPath splits the full path of an entry into its parent directory and its name.
+func SplitPath(fullPath string) (dir string, name string) {
+	dir, name = filepath.Split(CleanPath(fullPath))
+	return CleanPath(dir), name
+}
+
+// DeleteFileIds deletes the files from the volume servers, in batches.
+// The stores call it once their entries are deleted, so it is best effort:
+// the files failing to be deleted are only logged.
+func DeleteFileIds(master string, fids []string) {
+	for len(fids) > 0 {
+		batch := fids
+		if len(batch) > deleteBatchSize {
+			batch = batch[:deleteBatchSize]
+		}
+		fids = fids[len(batch):]
+		result, err := operation.DeleteFiles(master, batch)
+		if err != nil {
+			glog.V(0).Infof("failed to delete %d files: %v", len(batch), err)
+			continue
+		}
+		for _, e := range result.Errors {
+			glog.V(0).Infof("failed to delete file: %s", e)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package filertest checks the behavior shared by the filer stores.
+package filertest
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+)
+
+// TestStore creates, lists, moves and deletes files and directories in the empty store.
+// The files are deleted from the volume servers at the master of the store on a best
+// effort basis, so the master need not be running.
+func TestStore(t *testing.T, s filer.Filer) {
+	for path, fid := range map[string]string{
+		"/a/b/c.txt": "1,01",
+		"/a/b/d.txt": "1,02",
+		"/a/b/e.txt": "1,03",
+		"/a/bc.txt":  "1,04",
+		"/a/b/f/g":   "1,05",
+		"/a/b_/h":    "1,06",
+		"/top.txt":   "1,07",
+	} {
+		if err := s.CreateFile(path, fid); err != nil {
+			t.Fatalf("create %s: %v", path, err)
+		}
+	}
+	if err := s.CreateFile("/a/b/d.txt", "1,12"); err != nil {
+		t.Fatal(err)
+	}
+	if fid, err := s.FindFile("/a/b/d.txt"); err != nil || fid != "1,12" {
+		t.Errorf("find the overwritten /a/b/d.txt: %s %v", fid, err)
+	}
+	if _, err := s.FindFile("/a/b"); err != filer.ErrNotFound {
+		t.Errorf("find the directory /a/b: %v", err)
+	}
+	if err := s.CreateFile("/a/b", "1,08"); err == nil {
+		t.Errorf("overwrote the directory /a/b with a file")
+	}
+	if err := s.CreateFile("/top.txt/x", "1,09"); err == nil {
+		t.Errorf("created a file under the file /top.txt")
+	}
+
+	dirs, _ := s.ListDirectories("/a/")
+	if len(dirs) != 2 || dirs[0] != "b" || dirs[1] != "b_" {
+		t.Errorf("directories of /a: %v", dirs)
+	}
+	files, _ := s.ListFiles("/a/b/", "", 2)
+	if len(files) != 2 || files[0].Name != "c.txt" || files[1].Name != "d.txt" {
+		t.Errorf("first page of /a/b: %v", files)
+	}
+	files, _ = s.ListFiles("/a/b/", "d.txt", 2)
+	if len(files) != 1 || files[0].Name != "e.txt" {
+		t.Errorf("second page of /a/b: %v", files)
+	}
+	if files, _ = s.ListFiles("/", "", 10); len(files) != 1 || files[0].Name != "top.txt" {
+		t.Errorf("files of /: %v", files)
+	}
+	if _, err := s.ListFiles("/x/", "", 10); err != filer.ErrNotFound {
+		t.Errorf("list the missing directory /x: %v", err)
+	}
+
+	if err := s.DeleteDirectory("/a/b/", false); err == nil {
+		t.Errorf("deleted the non-empty directory /a/b")
+	}
+
+	// move a non-empty directory under another one
+	if err := s.Move("/a/b", "/x/y"); err != nil {
+		t.Fatal(err)
+	}
+	if fid, err := s.FindFile("/x/y/f/g"); err != nil || fid != "1,05" {
+		t.Errorf("find the moved /x/y/f/g: %s %v", fid, err)
+	}
+	if _, err := s.FindFile("/a/b/c.txt"); err != filer.ErrNotFound {
+		t.Errorf("find the moved /a/b/c.txt: %v", err)
+	}
+	for path, fid := range map[string]string{"/a/bc.txt": "1,04", "/a/b_/h": "1,06"} {
+		if found, err := s.FindFile(path); err != nil || found != fid {
+			t.Errorf("find the sibling %s: %s %v", path, found, err)
+		}
+	}
+	if err := s.Move("/x", "/x/y/z"); err == nil {
+		t.Errorf("moved /x under itself")
+	}
+
+	// rename a file into an existing directory
+	if err := s.Move("/top.txt", "/x"); err != nil {
+		t.Fatal(err)
+	}
+	if fid, err := s.FindFile("/x/top.txt"); err != nil || fid != "1,07" {
+		t.Errorf("find the moved /x/top.txt: %s %v", fid, err)
+	}
+
+	if err := s.DeleteDirectory("/x/y/f/", false); err == nil {
+		t.Errorf("deleted the non-empty directory /x/y/f")
+	}
+	if _, err := s.DeleteFile("/x/y/f/g"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteDirectory("/x/y/f/", false); err != nil {
+		t.Errorf("delete the empty directory /x/y/f: %v", err)
+	}
+	if dirs, _ = s.ListDirectories("/x/y"); len(dirs) != 0 {
+		t.Errorf("directories of /x/y: %v", dirs)
+	}
+
+	// the entries are gone even if their files fail to be deleted
+	if err := s.DeleteDirectory("/x", true); err != nil {
+		t.Fatalf("delete the directory /x: %v", err)
+	}
+	if _, err := s.FindFile("/x/y/c.txt"); err != filer.ErrNotFound {
+		t.Errorf("find the deleted /x/y/c.txt: %v", err)
+	}
+	if dirs, _ = s.ListDirectories("/"); len(dirs) != 1 || dirs[0] != "a" {
+		t.Errorf("directories of /: %v", dirs)
+	}
+}
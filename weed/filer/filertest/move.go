@@ -0,0 +1,91 @@
+package filertest
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+)
+
+// TestMove moves a directory under the root while others read its files, which must
+// be found at either their old or their new paths, then moves a file. The root should
+// be unique to the test run, and deleted by the caller.
+func TestMove(t *testing.T, f filer.Filer, root string) {
+	fromDir, toDir := root+"/a_%", root+"/x/y"
+	const count = 50
+	for i := 0; i < count; i++ {
+		if err := f.CreateFile(fmt.Sprintf("%s/sub/%d.txt", fromDir, i), fmt.Sprintf("1,%x", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a sibling matching the LIKE pattern of the moved directory, if not escaped
+	if err := f.CreateFile(root+"/ab/keep.txt", "1,ff"); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var reads, missing int32
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for i := 0; i < count; i++ {
+					atomic.AddInt32(&reads, 1)
+					name := fmt.Sprintf("/sub/%d.txt", i)
+					if _, err := f.FindFile(fromDir + name); err == nil {
+						continue
+					}
+					if _, err := f.FindFile(toDir + name); err != nil {
+						atomic.AddInt32(&missing, 1)
+					}
+				}
+			}
+		}()
+	}
+	err := f.Move(fromDir, toDir)
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing > 0 {
+		t.Errorf("%d of %d reads found neither the old nor the new path", missing, reads)
+	}
+
+	for i := 0; i < count; i++ {
+		if fid, err := f.FindFile(fmt.Sprintf("%s/sub/%d.txt", toDir, i)); err != nil || fid != fmt.Sprintf("1,%x", i) {
+			t.Errorf("find the moved file %d: %s %v", i, fid, err)
+		}
+	}
+	if _, err := f.FindFile(fromDir + "/sub/0.txt"); err == nil {
+		t.Errorf("found the moved file at its old path")
+	}
+	if fid, err := f.FindFile(root + "/ab/keep.txt"); err != nil || fid != "1,ff" {
+		t.Errorf("find the sibling %s/ab/keep.txt: %s %v", root, fid, err)
+	}
+	if files, _ := f.ListFiles(toDir+"/sub/", "", 100); len(files) != count {
+		t.Errorf("listed %d moved files, expected %d", len(files), count)
+	}
+
+	// move a file under an existing directory
+	if err = f.Move(toDir+"/sub/0.txt", toDir); err != nil {
+		t.Fatal(err)
+	}
+	if fid, err := f.FindFile(toDir + "/0.txt"); err != nil || fid != "1,0" {
+		t.Errorf("find the moved file %s/0.txt: %s %v", toDir, fid, err)
+	}
+	if err = f.Move(root+"/x", toDir+"/z"); err == nil {
+		t.Errorf("moved %s/x under itself", root)
+	}
+}
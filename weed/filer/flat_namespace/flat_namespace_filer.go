@@ -2,20 +2,38 @@ package flat_namespace
 
 import (
 	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/chrislusf/seaweedfs/weed/filer"
-	"path/filepath"
+	"github.com/chrislusf/seaweedfs/weed/glog"
 )
 
+/*
+FlatNamespaceFiler keeps the full path to file id mappings in a key value store,
+and indexes the entries of each directory in the same store, to list, move and
+delete directories.
+
+Files created before the directory indexes are found but not listed, until
+they are written again or indexed by Reindex.
+*/
 type FlatNamespaceFiler struct {
-	master string
-	store  FlatNamespaceStore
+	master  string
+	store   FlatNamespaceStore
+	mvMutex sync.Mutex
 }
 
 var (
 	ErrNotImplemented = errors.New("Not Implemented for flat namespace meta data store")
 )
 
+const (
+	pageSize    = 100
+	reindexDirs = 10000
+)
+
 func NewFlatNamespaceFiler(master string, store FlatNamespaceStore) *FlatNamespaceFiler {
 	return &FlatNamespaceFiler{
 		master: master,
@@ -23,44 +41,317 @@ func NewFlatNamespaceFiler(master string, store FlatNamespaceStore) *FlatNamespa
 	}
 }
 
-func (filer *FlatNamespaceFiler) CreateFile(fullFileName string, fid string) (err error) {
-	return filer.store.Put(fullFileName, fid)
+// makeDirectory indexes the directory and its parents. They are indexed again on
+// every file created, as other filers on the same store may move or delete them.
+func (f *FlatNamespaceFiler) makeDirectory(dirPath string) error {
+	for dirPath != "/" {
+		parent, name := filer.SplitPath(dirPath)
+		if err := f.store.PutEntry(parent, name, "", true); err != nil {
+			return fmt.Errorf("index directory %s: %v", dirPath, err)
+		}
+		dirPath = parent
+	}
+	return nil
+}
+
+// checkNoFileParent refuses a directory path going through a file.
+func (f *FlatNamespaceFiler) checkNoFileParent(dirPath string) error {
+	for ; dirPath != "/"; dirPath, _ = filer.SplitPath(dirPath) {
+		if _, err := f.store.Get(dirPath); err == nil {
+			return fmt.Errorf("%s is a file", dirPath)
+		} else if err != filer.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FlatNamespaceFiler) isDirectory(fullPath string) (bool, error) {
+	dir, name := filer.SplitPath(fullPath)
+	if name == "" {
+		return true, nil
+	}
+	return f.store.HasEntry(dir, name, true)
+}
+
+func (f *FlatNamespaceFiler) CreateFile(fullFileName string, fid string) (err error) {
+	fullFileName = filer.CleanPath(fullFileName)
+	if isDir, err := f.isDirectory(fullFileName); err != nil {
+		return err
+	} else if isDir {
+		return fmt.Errorf("%s is a directory", fullFileName)
+	}
+	dir, name := filer.SplitPath(fullFileName)
+	if err = f.checkNoFileParent(dir); err != nil {
+		return err
+	}
+	if err = f.makeDirectory(dir); err != nil {
+		return err
+	}
+	if err = f.store.Put(fullFileName, fid); err != nil {
+		return err
+	}
+	return f.store.PutEntry(dir, name, fid, false)
 }
-func (filer *FlatNamespaceFiler) FindFile(fullFileName string) (fid string, err error) {
-	return filer.store.Get(fullFileName)
+func (f *FlatNamespaceFiler) FindFile(fullFileName string) (fid string, err error) {
+	return f.store.Get(fullFileName)
 }
-func (filer *FlatNamespaceFiler) LookupDirectoryEntry(dirPath string, name string) (found bool, fileId string, err error) {
-	if fileId, err = filer.FindFile(filepath.Join(dirPath, name)); err == nil {
+func (f *FlatNamespaceFiler) LookupDirectoryEntry(dirPath string, name string) (found bool, fileId string, err error) {
+	if isDir, err := f.isDirectory(filepath.Join(dirPath, name)); err == nil && isDir {
+		return true, "", nil
+	}
+	if fileId, err = f.FindFile(filepath.Join(dirPath, name)); err == nil {
 		return true, fileId, nil
 	}
 	return false, "", err
 }
-func (filer *FlatNamespaceFiler) ListDirectories(dirPath string) (dirs []filer.DirectoryName, err error) {
-	return nil, ErrNotImplemented
+func (f *FlatNamespaceFiler) ListDirectories(dirPath string) (dirs []filer.DirectoryName, err error) {
+	entries, err := f.store.ListEntries(filer.CleanPath(dirPath), true, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		dirs = append(dirs, filer.DirectoryName(entry.Name))
+	}
+	return dirs, nil
+}
+func (f *FlatNamespaceFiler) ListFiles(dirPath string, lastFileName string, limit int) (files []filer.FileEntry, err error) {
+	dirPath = filer.CleanPath(dirPath)
+	if files, err = f.store.ListEntries(dirPath, false, lastFileName, limit); err != nil || len(files) > 0 {
+		return files, err
+	}
+	if isDir, err := f.isDirectory(dirPath); err != nil {
+		return nil, err
+	} else if !isDir {
+		return nil, filer.ErrNotFound
+	}
+	return nil, nil
+}
+
+func (f *FlatNamespaceFiler) DeleteDirectory(dirPath string, recursive bool) (err error) {
+	dirPath = filer.CleanPath(dirPath)
+	if isDir, err := f.isDirectory(dirPath); err != nil {
+		return err
+	} else if !isDir {
+		return fmt.Errorf("directory %s is not found", dirPath)
+	}
+	if !recursive {
+		for _, isDirectory := range []bool{true, false} {
+			entries, err := f.store.ListEntries(dirPath, isDirectory, "", 1)
+			if err != nil {
+				return err
+			}
+			if len(entries) > 0 {
+				return fmt.Errorf("Fail to delete non-empty directory %s!", dirPath)
+			}
+		}
+	}
+	return f.deleteDirectory(dirPath)
 }
-func (filer *FlatNamespaceFiler) ListFiles(dirPath string, lastFileName string, limit int) (files []filer.FileEntry, err error) {
-	return nil, ErrNotImplemented
+
+// deleteDirectory deletes the directory with all its files and sub directories.
+func (f *FlatNamespaceFiler) deleteDirectory(dirPath string) error {
+	dirs, err := f.store.ListEntries(dirPath, true, "", 0)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err = f.deleteDirectory(filepath.Join(dirPath, dir.Name)); err != nil {
+			return err
+		}
+	}
+	for {
+		files, err := f.store.ListEntries(dirPath, false, "", pageSize)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			break
+		}
+		// the files are deleted from the volume servers once their entries are gone
+		var fids []string
+		for _, file := range files {
+			if err = f.deleteFile(filepath.Join(dirPath, file.Name)); err != nil {
+				filer.DeleteFileIds(f.master, fids)
+				return err
+			}
+			fids = append(fids, string(file.Id))
+		}
+		filer.DeleteFileIds(f.master, fids)
+	}
+	if dirPath == "/" {
+		return nil
+	}
+	parent, name := filer.SplitPath(dirPath)
+	glog.V(3).Infof("deleting directory %s", dirPath)
+	return f.store.DeleteEntry(parent, name, true)
 }
-func (filer *FlatNamespaceFiler) DeleteDirectory(dirPath string, recursive bool) (err error) {
-	return ErrNotImplemented
+
+func (f *FlatNamespaceFiler) deleteFile(fullFileName string) error {
+	if err := f.store.Delete(fullFileName); err != nil {
+		return err
+	}
+	dir, name := filer.SplitPath(fullFileName)
+	return f.store.DeleteEntry(dir, name, false)
 }
 
-func (filer *FlatNamespaceFiler) DeleteFile(fullFileName string) (fid string, err error) {
-	fid, err = filer.FindFile(fullFileName)
+func (f *FlatNamespaceFiler) DeleteFile(fullFileName string) (fid string, err error) {
+	fid, err = f.FindFile(fullFileName)
 	if err != nil {
 		return "", err
 	}
 
-	err = filer.store.Delete(fullFileName)
+	err = f.deleteFile(filer.CleanPath(fullFileName))
 	if err != nil {
 		return "", err
 	}
 
 	return fid, nil
-	//return filer.store.Delete(fullFileName)
-	//are you kidding me!!!!
 }
 
-func (filer *FlatNamespaceFiler) Move(fromPath string, toPath string) error {
-	return ErrNotImplemented
+/*
+Move a folder or a file, with 4 Use cases:
+mv fromDir toNewDir
+mv fromDir toOldDir
+mv fromFile toDir
+mv fromFile toFile
+
+A folder is moved file by file, not atomically, unless the store
+renames it in one transaction.
+*/
+func (f *FlatNamespaceFiler) Move(fromPath string, toPath string) error {
+	f.mvMutex.Lock()
+	defer f.mvMutex.Unlock()
+	fromPath, toPath = filer.CleanPath(fromPath), filer.CleanPath(toPath)
+	if fromPath == "/" {
+		return errors.New("Can not move the root directory")
+	}
+
+	fid, err := f.store.Get(fromPath)
+	if err != nil && err != filer.ErrNotFound {
+		return err
+	}
+	isFile := err == nil
+	if !isFile {
+		if isDir, err := f.isDirectory(fromPath); err != nil {
+			return err
+		} else if !isDir {
+			return fmt.Errorf("File %s is not found!", fromPath)
+		}
+	}
+
+	if isDir, err := f.isDirectory(toPath); err != nil {
+		return err
+	} else if isDir {
+		// move under an existing folder
+		toPath = filepath.Join(toPath, filepath.Base(fromPath))
+		if existing, err := f.isDirectory(toPath); err != nil {
+			return err
+		} else if existing && (isFile || toPath != fromPath) {
+			return fmt.Errorf("%s already exists", toPath)
+		}
+	}
+	if toPath == fromPath {
+		return nil
+	}
+	if !isFile {
+		if _, err := f.store.Get(toPath); err == nil {
+			return fmt.Errorf("%s is a file", toPath)
+		}
+		if strings.HasPrefix(toPath, fromPath+"/") {
+			return fmt.Errorf("Can not move %s under itself to %s", fromPath, toPath)
+		}
+	}
+	if renamer, ok := f.store.(FlatNamespaceRenamer); ok {
+		toDir, _ := filer.SplitPath(toPath)
+		if err := f.makeDirectory(toDir); err != nil {
+			return err
+		}
+		if err := renamer.Rename(fromPath, toPath, !isFile); err != ErrNotImplemented {
+			return err
+		}
+	}
+	if isFile {
+		return f.moveFile(fromPath, toPath, fid)
+	}
+	return f.moveDirectory(fromPath, toPath)
+}
+
+func (f *FlatNamespaceFiler) moveFile(fromPath, toPath string, fid string) error {
+	if err := f.CreateFile(toPath, fid); err != nil {
+		return err
+	}
+	return f.deleteFile(fromPath)
+}
+
+func (f *FlatNamespaceFiler) moveDirectory(fromPath, toPath string) error {
+	if err := f.makeDirectory(toPath); err != nil {
+		return err
+	}
+	for {
+		files, err := f.store.ListEntries(fromPath, false, "", pageSize)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			break
+		}
+		for _, file := range files {
+			if err = f.moveFile(filepath.Join(fromPath, file.Name), filepath.Join(toPath, file.Name), string(file.Id)); err != nil {
+				return err
+			}
+		}
+	}
+	dirs, err := f.store.ListEntries(fromPath, true, "", 0)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err = f.moveDirectory(filepath.Join(fromPath, dir.Name), filepath.Join(toPath, dir.Name)); err != nil {
+			return err
+		}
+	}
+	parent, name := filer.SplitPath(fromPath)
+	glog.V(3).Infof("moved directory %s to %s", fromPath, toPath)
+	return f.store.DeleteEntry(parent, name, true)
+}
+
+// ScanFiles visits all the files of the store, indexed or not, if the store
+// implements FlatNamespaceScanner.
+func (f *FlatNamespaceFiler) ScanFiles(visit func(fullPath string, fid string) error) error {
+	scanner, ok := f.store.(FlatNamespaceScanner)
+	if !ok {
+		return filer.ErrScanNotSupported
+	}
+	return scanner.Scan(visit)
+}
+
+// Reindex indexes all the files of the store in their directories, for the files
+// created before the directory indexes. It returns the number of files indexed.
+func (f *FlatNamespaceFiler) Reindex() (files int64, err error) {
+	scanner, ok := f.store.(FlatNamespaceScanner)
+	if !ok {
+		return 0, ErrNotImplemented
+	}
+	// the directories indexed in this pass, up to a bound, as the files are not
+	// visited by directory
+	dirs := make(map[string]bool)
+	err = scanner.Scan(func(fullFileName string, fid string) error {
+		dir, name := filer.SplitPath(fullFileName)
+		if !dirs[dir] {
+			if err := f.makeDirectory(dir); err != nil {
+				return err
+			}
+			if len(dirs) >= reindexDirs {
+				dirs = make(map[string]bool)
+			}
+			dirs[dir] = true
+		}
+		if err := f.store.PutEntry(dir, name, fid, false); err != nil {
+			return err
+		}
+		files++
+		return nil
+	})
+	return files, err
 }
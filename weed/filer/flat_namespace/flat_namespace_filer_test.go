@@ -0,0 +1,211 @@
+package flat_namespace
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/filer/filertest"
+)
+
+// memStore is a FlatNamespaceStore in maps.
+type memStore struct {
+	fids    map[string]string
+	entries map[string]map[string]string // by directory and kind, the fids by name
+}
+
+func newMemStore() *memStore {
+	return &memStore{fids: make(map[string]string), entries: make(map[string]map[string]string)}
+}
+
+func (s *memStore) Put(fullFileName string, fid string) error {
+	s.fids[fullFileName] = fid
+	return nil
+}
+func (s *memStore) Get(fullFileName string) (string, error) {
+	if fid, ok := s.fids[fullFileName]; ok {
+		return fid, nil
+	}
+	return "", filer.ErrNotFound
+}
+func (s *memStore) Delete(fullFileName string) error {
+	delete(s.fids, fullFileName)
+	return nil
+}
+
+func entriesKey(dirPath string, isDirectory bool) string {
+	if isDirectory {
+		return "d:" + dirPath
+	}
+	return "f:" + dirPath
+}
+
+func (s *memStore) PutEntry(dirPath string, name string, fid string, isDirectory bool) error {
+	key := entriesKey(dirPath, isDirectory)
+	if s.entries[key] == nil {
+		s.entries[key] = make(map[string]string)
+	}
+	s.entries[key][name] = fid
+	return nil
+}
+func (s *memStore) DeleteEntry(dirPath string, name string, isDirectory bool) error {
+	delete(s.entries[entriesKey(dirPath, isDirectory)], name)
+	return nil
+}
+func (s *memStore) HasEntry(dirPath string, name string, isDirectory bool) (bool, error) {
+	_, found := s.entries[entriesKey(dirPath, isDirectory)][name]
+	return found, nil
+}
+func (s *memStore) ListEntries(dirPath string, isDirectory bool, lastName string, limit int) (entries []filer.FileEntry, err error) {
+	var names []string
+	for name := range s.entries[entriesKey(dirPath, isDirectory)] {
+		if name > lastName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+		entries = append(entries, filer.FileEntry{Name: name, Id: filer.FileId(s.entries[entriesKey(dirPath, isDirectory)][name])})
+	}
+	return entries, nil
+}
+
+func (s *memStore) Scan(visit func(fullFileName string, fid string) error) error {
+	for fullFileName, fid := range s.fids {
+		if err := visit(fullFileName, fid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestFlatNamespaceStore(t *testing.T) {
+	filertest.TestStore(t, NewFlatNamespaceFiler("", newMemStore()))
+}
+
+func TestFlatNamespaceDirectories(t *testing.T) {
+	f := NewFlatNamespaceFiler("", newMemStore())
+	for path, fid := range map[string]string{
+		"/a/b/c.txt": "1,01",
+		"/a/b/d.txt": "1,02",
+		"/a/b/e.txt": "1,03",
+		"/a/b/f/g":   "1,04",
+		"/a/h.txt":   "1,05",
+	} {
+		if err := f.CreateFile(path, fid); err != nil {
+			t.Fatalf("create %s: %v", path, err)
+		}
+	}
+	if err := f.CreateFile("/a/b", "1,06"); err == nil {
+		t.Errorf("overwrote the directory /a/b with a file")
+	}
+
+	if dirs, _ := f.ListDirectories("/a/"); len(dirs) != 1 || dirs[0] != "b" {
+		t.Errorf("directories of /a: %v", dirs)
+	}
+	files, _ := f.ListFiles("/a/b/", "", 2)
+	if len(files) != 2 || files[0].Name != "c.txt" || files[1].Id != "1,02" {
+		t.Errorf("first page of /a/b: %v", files)
+	}
+	if files, _ = f.ListFiles("/a/b/", "d.txt", 2); len(files) != 1 || files[0].Name != "e.txt" {
+		t.Errorf("second page of /a/b: %v", files)
+	}
+	if found, fid, _ := f.LookupDirectoryEntry("/a", "b"); !found || fid != "" {
+		t.Errorf("lookup the directory /a/b: %v %s", found, fid)
+	}
+
+	if err := f.DeleteDirectory("/a/b/f/", false); err == nil {
+		t.Errorf("deleted the non-empty directory /a/b/f")
+	}
+
+	// move a directory under an existing one
+	if err := f.Move("/a/b", "/x/y"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Move("/a", "/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Move("/x/y", "/a"); err != nil {
+		t.Fatal(err)
+	}
+	if fid, err := f.FindFile("/a/y/f/g"); err != nil || fid != "1,04" {
+		t.Errorf("find the moved /a/y/f/g: %s %v", fid, err)
+	}
+	if _, err := f.FindFile("/a/b/c.txt"); err != filer.ErrNotFound {
+		t.Errorf("find the moved /a/b/c.txt: %v", err)
+	}
+	if dirs, _ := f.ListDirectories("/x"); len(dirs) != 0 {
+		t.Errorf("directories of /x: %v", dirs)
+	}
+	if files, _ = f.ListFiles("/a/y", "", 0); len(files) != 3 {
+		t.Errorf("files of the moved /a/y: %v", files)
+	}
+	if err := f.Move("/a", "/a/y/z"); err == nil {
+		t.Errorf("moved /a under itself")
+	}
+
+	// move a file into an existing directory
+	if err := f.Move("/a/h.txt", "/x"); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ = f.ListFiles("/x", "", 0); len(files) != 1 || files[0].Name != "h.txt" {
+		t.Errorf("files of /x: %v", files)
+	}
+
+	if _, err := f.DeleteFile("/a/y/f/g"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.DeleteDirectory("/a/y/f/", false); err != nil {
+		t.Errorf("delete the empty directory /a/y/f: %v", err)
+	}
+	if dirs, _ := f.ListDirectories("/a/y"); len(dirs) != 0 {
+		t.Errorf("directories of /a/y: %v", dirs)
+	}
+}
+
+func TestFlatNamespaceReindex(t *testing.T) {
+	store := newMemStore()
+	f := NewFlatNamespaceFiler("", store)
+	// files written before the directories were indexed
+	store.Put("/a/b/c.txt", "1,01")
+	store.Put("/a/d.txt", "1,02")
+	if err := f.CreateFile("/a/b/e.txt", "1,03"); err != nil {
+		t.Fatal(err)
+	}
+	if files, err := f.Reindex(); err != nil || files != 3 {
+		t.Fatalf("reindexed %d files: %v", files, err)
+	}
+	if files, _ := f.ListFiles("/a/b", "", 0); len(files) != 2 || files[0].Name != "c.txt" || files[0].Id != "1,01" {
+		t.Errorf("files of /a/b: %v", files)
+	}
+	if dirs, _ := f.ListDirectories("/a"); len(dirs) != 1 || dirs[0] != "b" {
+		t.Errorf("directories of /a: %v", dirs)
+	}
+	if files, _ := f.ListFiles("/a", "", 0); len(files) != 1 || files[0].Name != "d.txt" {
+		t.Errorf("files of /a: %v", files)
+	}
+}
+
+func TestFlatNamespaceMigrateUnindexed(t *testing.T) {
+	store := newMemStore()
+	from := NewFlatNamespaceFiler("", store)
+	// a file written before the directories were indexed
+	store.Put("/a/b/c.txt", "1,01")
+	if err := from.CreateFile("/a/d.txt", "1,02"); err != nil {
+		t.Fatal(err)
+	}
+	to := NewFlatNamespaceFiler("", newMemStore())
+	if files, err := filer.Migrate(from, to, "from memory to memory", "", nil); err != nil || files != 2 {
+		t.Fatalf("migrated %d files: %v", files, err)
+	}
+	result, err := filer.Verify(from, to, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Ok() || result.SourceFiles != 2 || result.TargetFiles != 2 {
+		t.Errorf("verify: %+v", result)
+	}
+}
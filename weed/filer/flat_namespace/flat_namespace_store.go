@@ -1,9 +1,34 @@
 package flat_namespace
 
-import ()
+import (
+	"github.com/chrislusf/seaweedfs/weed/filer"
+)
 
 type FlatNamespaceStore interface {
 	Put(fullFileName string, fid string) (err error)
 	Get(fullFileName string) (fid string, err error)
 	Delete(fullFileName string) (err error)
+
+	// The entries of each directory are indexed apart from the full path mappings,
+	// the sub directories with empty file ids, and listed in name order.
+	// The directory paths are clean, "/" for the root.
+	PutEntry(dirPath string, name string, fid string, isDirectory bool) (err error)
+	DeleteEntry(dirPath string, name string, isDirectory bool) (err error)
+	HasEntry(dirPath string, name string, isDirectory bool) (found bool, err error)
+	// ListEntries lists the sub directories or the files after lastName, all of them if limit <= 0.
+	ListEntries(dirPath string, isDirectory bool, lastName string, limit int) (entries []filer.FileEntry, err error)
+}
+
+// FlatNamespaceRenamer is implemented by the stores that rename a file, or a directory
+// with all its files and sub directories, in one transaction. The paths are clean,
+// and the parent directories of the new path are already indexed.
+// Rename returns ErrNotImplemented if the store can not rename atomically.
+type FlatNamespaceRenamer interface {
+	Rename(fromPath string, toPath string, isDirectory bool) (err error)
+}
+
+// FlatNamespaceScanner is implemented by the stores that visit all their full path
+// to file id mappings, in no particular order, to index the files in their directories.
+type FlatNamespaceScanner interface {
+	Scan(visit func(fullFileName string, fid string) error) (err error)
 }
@@ -0,0 +1,356 @@
+package leveldb_store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/syndtr/goleveldb/leveldb"
+	leveldb_util "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+/*
+LevelDbStore keeps the filer meta data in an embedded LevelDB with full path keys,
+following the design in ../vasto_store/design.txt:
+
+  key:   <parent full path, 0x00, name>
+  value: the json encoded entry, a file with its fid, or a directory
+
+Directories are entries of their parents, so a directory is listed by a prefix
+scan of its full path and the separator, starting after the last listed name.
+Moving a directory rewrites all its sub entries, in one atomic batch.
+*/
+type LevelDbStore struct {
+	master string
+	db     *leveldb.DB
+	mutex  sync.Mutex // serializes the changes that check the existing entries
+}
+
+type entry struct {
+	Fid         string `json:"fid,omitempty"`
+	IsDirectory bool   `json:"dir,omitempty"`
+}
+
+const separator = 0x00
+
+var errIsDirectory = errors.New("is a directory")
+
+func NewLevelDbStore(master string, dir string) (*LevelDbStore, error) {
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDbStore{master: master, db: db}, nil
+}
+
+func (s *LevelDbStore) Close() {
+	s.db.Close()
+}
+
+func genKey(dir string, name string) []byte {
+	key := make([]byte, 0, len(dir)+1+len(name))
+	key = append(key, dir...)
+	key = append(key, separator)
+	return append(key, name...)
+}
+
+// entryKey is the key of the entry at the full path, nil for the root.
+func entryKey(fullPath string) []byte {
+	dir, name := filer.SplitPath(fullPath)
+	if name == "" {
+		return nil
+	}
+	return genKey(dir, name)
+}
+
+func (s *LevelDbStore) getEntry(fullPath string) (*entry, error) {
+	key := entryKey(fullPath)
+	if key == nil {
+		return &entry{IsDirectory: true}, nil
+	}
+	data, err := s.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, filer.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	e := &entry{}
+	if err = json.Unmarshal(data, e); err != nil {
+		return nil, fmt.Errorf("decode %s: %v", fullPath, err)
+	}
+	return e, nil
+}
+
+func putEntry(batch *leveldb.Batch, fullPath string, e *entry) {
+	data, _ := json.Marshal(e)
+	batch.Put(entryKey(fullPath), data)
+}
+
+// makeParents adds the missing directories of the full path to the batch.
+func (s *LevelDbStore) makeParents(batch *leveldb.Batch, fullPath string) error {
+	dir, _ := filer.SplitPath(fullPath)
+	if dir == "/" {
+		return nil
+	}
+	e, err := s.getEntry(dir)
+	if err == filer.ErrNotFound {
+		if err = s.makeParents(batch, dir); err != nil {
+			return err
+		}
+		putEntry(batch, dir, &entry{IsDirectory: true})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !e.IsDirectory {
+		return fmt.Errorf("%s is a file", dir)
+	}
+	return nil
+}
+
+func (s *LevelDbStore) CreateFile(fullFileName string, fid string) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if e, err := s.getEntry(fullFileName); err == nil && e.IsDirectory {
+		return fmt.Errorf("%s: %v", fullFileName, errIsDirectory)
+	}
+	batch := new(leveldb.Batch)
+	if err = s.makeParents(batch, fullFileName); err != nil {
+		return err
+	}
+	putEntry(batch, fullFileName, &entry{Fid: fid})
+	return s.db.Write(batch, nil)
+}
+
+func (s *LevelDbStore) FindFile(fullFileName string) (fid string, err error) {
+	e, err := s.getEntry(fullFileName)
+	if err != nil {
+		return "", err
+	}
+	if e.IsDirectory {
+		return "", filer.ErrNotFound
+	}
+	return e.Fid, nil
+}
+
+func (s *LevelDbStore) LookupDirectoryEntry(dirPath string, name string) (found bool, fileId string, err error) {
+	e, err := s.getEntry(filepath.Join(dirPath, name))
+	if err != nil {
+		return false, "", err
+	}
+	return true, e.Fid, nil
+}
+
+func (s *LevelDbStore) DeleteFile(fullFileName string) (fid string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, err := s.getEntry(fullFileName)
+	if err == filer.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if e.IsDirectory {
+		return "", fmt.Errorf("%s: %v", fullFileName, errIsDirectory)
+	}
+	return e.Fid, s.db.Delete(entryKey(fullFileName), nil)
+}
+
+// list visits the entries of the directory after the start name, in name order,
+// until visit returns false.
+func (s *LevelDbStore) list(dirPath string, startName string, visit func(name string, e *entry) bool) error {
+	e, err := s.getEntry(dirPath)
+	if err != nil {
+		return err
+	}
+	if !e.IsDirectory {
+		return fmt.Errorf("%s is a file", dirPath)
+	}
+	prefix := genKey(filer.CleanPath(dirPath), "")
+	iter := s.db.NewIterator(&leveldb_util.Range{Start: genKey(filer.CleanPath(dirPath), startName), Limit: prefixLimit(prefix)}, nil)
+	defer iter.Release()
+	for iter.Next() {
+		name := string(iter.Key()[len(prefix):])
+		if name == startName {
+			continue
+		}
+		e := &entry{}
+		if err := json.Unmarshal(iter.Value(), e); err != nil {
+			return fmt.Errorf("decode %s: %v", filepath.Join(dirPath, name), err)
+		}
+		if !visit(name, e) {
+			break
+		}
+	}
+	return iter.Error()
+}
+
+// prefixLimit is the smallest key after all the keys with the prefix.
+func prefixLimit(prefix []byte) []byte {
+	return leveldb_util.BytesPrefix(prefix).Limit
+}
+
+func (s *LevelDbStore) ListDirectories(dirPath string) (dirs []filer.DirectoryName, err error) {
+	err = s.list(dirPath, "", func(name string, e *entry) bool {
+		if e.IsDirectory {
+			dirs = append(dirs, filer.DirectoryName(name))
+		}
+		return true
+	})
+	return
+}
+
+func (s *LevelDbStore) ListFiles(dirPath string, lastFileName string, limit int) (files []filer.FileEntry, err error) {
+	err = s.list(dirPath, lastFileName, func(name string, e *entry) bool {
+		if !e.IsDirectory {
+			files = append(files, filer.FileEntry{Name: name, Id: filer.FileId(e.Fid)})
+		}
+		return limit <= 0 || len(files) < limit
+	})
+	return
+}
+
+// visitTree visits all the entries under the directory, by their full path keys.
+// The keys of the directory entries start with its full path and the separator,
+// and the keys of the deeper entries start with its full path and a slash.
+func (s *LevelDbStore) visitTree(dirPath string, visit func(key []byte, e *entry) error) error {
+	dirPath = filer.CleanPath(dirPath)
+	prefixes := [][]byte{[]byte("/")}
+	if dirPath != "/" {
+		prefixes = [][]byte{genKey(dirPath, ""), []byte(dirPath + "/")}
+	}
+	for _, prefix := range prefixes {
+		iter := s.db.NewIterator(leveldb_util.BytesPrefix(prefix), nil)
+		for iter.Next() {
+			e := &entry{}
+			if err := json.Unmarshal(iter.Value(), e); err != nil {
+				iter.Release()
+				return fmt.Errorf("decode %q: %v", iter.Key(), err)
+			}
+			if err := visit(append([]byte(nil), iter.Key()...), e); err != nil {
+				iter.Release()
+				return err
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LevelDbStore) DeleteDirectory(dirPath string, recursive bool) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, err := s.getEntry(dirPath)
+	if err != nil {
+		return err
+	}
+	if !e.IsDirectory {
+		return fmt.Errorf("%s is a file", dirPath)
+	}
+	if !recursive {
+		empty := true
+		if err = s.list(dirPath, "", func(string, *entry) bool {
+			empty = false
+			return false
+		}); err != nil {
+			return err
+		}
+		if !empty {
+			return fmt.Errorf("Fail to delete non-empty directory %s!", dirPath)
+		}
+	}
+
+	// the files are deleted from the volume servers once their entries are gone
+	batch := new(leveldb.Batch)
+	var fids []string
+	if err = s.visitTree(dirPath, func(key []byte, e *entry) error {
+		batch.Delete(key)
+		if !e.IsDirectory {
+			fids = append(fids, e.Fid)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if key := entryKey(dirPath); key != nil {
+		batch.Delete(key)
+	}
+	glog.V(3).Infof("deleting directory %s with %d entries", dirPath, batch.Len())
+	if err = s.db.Write(batch, nil); err != nil {
+		return err
+	}
+	filer.DeleteFileIds(s.master, fids)
+	return nil
+}
+
+/*
+Move a folder or a file, with 4 Use cases:
+mv fromDir toNewDir
+mv fromDir toOldDir
+mv fromFile toDir
+mv fromFile toFile
+*/
+func (s *LevelDbStore) Move(fromPath string, toPath string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fromPath, toPath = filer.CleanPath(fromPath), filer.CleanPath(toPath)
+	from, err := s.getEntry(fromPath)
+	if err != nil {
+		return fmt.Errorf("File %s is not found!", fromPath)
+	}
+	if fromPath == "/" {
+		return errors.New("Can not move the root directory")
+	}
+	if to, err := s.getEntry(toPath); err == nil {
+		if !to.IsDirectory {
+			if from.IsDirectory {
+				return fmt.Errorf("%s is a file", toPath)
+			}
+		} else {
+			// move under an existing folder
+			toPath = filer.CleanPath(filepath.Join(toPath, filepath.Base(fromPath)))
+			if to, err := s.getEntry(toPath); err == nil && (from.IsDirectory || to.IsDirectory) {
+				return fmt.Errorf("%s already exists", toPath)
+			}
+		}
+	} else if err != filer.ErrNotFound {
+		return err
+	}
+	if toPath == fromPath {
+		return nil
+	}
+	if from.IsDirectory && strings.HasPrefix(toPath, fromPath+"/") {
+		return fmt.Errorf("Can not move %s under itself to %s", fromPath, toPath)
+	}
+
+	batch := new(leveldb.Batch)
+	if err = s.makeParents(batch, toPath); err != nil {
+		return err
+	}
+	batch.Delete(entryKey(fromPath))
+	putEntry(batch, toPath, from)
+	if from.IsDirectory {
+		if err = s.visitTree(fromPath, func(key []byte, e *entry) error {
+			// replace the leading directory path of the key
+			newKey := append([]byte(toPath), key[len(fromPath):]...)
+			data, _ := json.Marshal(e)
+			batch.Delete(key)
+			batch.Put(newKey, data)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	glog.V(3).Infof("moving %s to %s with %d changes", fromPath, toPath, batch.Len())
+	return s.db.Write(batch, nil)
+}
@@ -0,0 +1,21 @@
+package leveldb_store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/filer/filertest"
+)
+
+func TestLevelDbStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "leveldb_store")
+	defer os.RemoveAll(dir)
+	s, err := NewLevelDbStore("localhost:9333", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	filertest.TestStore(t, s)
+}
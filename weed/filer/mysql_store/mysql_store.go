@@ -4,10 +4,14 @@ import (
 	"database/sql"
 	"fmt"
 	"hash/crc32"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/filer/flat_namespace"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -18,6 +22,8 @@ const (
 	default_maxOpenConnections = 50
 	default_maxTableNums       = 1024
 	tableName                  = "filer_mapping"
+	entriesTableName           = "filer_entries"
+	scanPageSize               = 1000
 )
 
 var (
@@ -79,7 +85,8 @@ func getDbConnection(confs []MySqlConf) []*sql.DB {
 	return _db_connections
 }
 
-func NewMysqlStore(confs []MySqlConf, isSharding bool, shardCount int) *MySqlStore {
+// NewMysqlStore creates the tables of the store if missing, on every database.
+func NewMysqlStore(confs []MySqlConf, isSharding bool, shardCount int) (*MySqlStore, error) {
 	ms := &MySqlStore{
 		dbs:        getDbConnection(confs),
 		isSharding: isSharding,
@@ -95,13 +102,16 @@ func NewMysqlStore(confs []MySqlConf, isSharding bool, shardCount int) *MySqlSto
 			}
 		}
 		for i := 0; i < ms.shardCount; i++ {
-			if err := ms.createTables(db, tableName, i); err != nil {
-				fmt.Printf("create table failed %v", err)
+			if err := ms.createTables(db, createTable, tableName, i); err != nil {
+				return nil, fmt.Errorf("create table %s: %v", tableName, err)
+			}
+			if err := ms.createTables(db, createEntriesTable, entriesTableName, i); err != nil {
+				return nil, fmt.Errorf("create table %s: %v", entriesTableName, err)
 			}
 		}
 	}
 
-	return ms
+	return ms, nil
 }
 
 func (s *MySqlStore) hash(fullFileName string) (instance_offset, table_postfix int) {
@@ -122,6 +132,18 @@ func (s *MySqlStore) parseFilerMappingInfo(path string) (instanceId int, tableFu
 	return
 }
 
+// parseDirectoryEntriesInfo shards the directory entries by the directory path,
+// so all the entries of a directory are in one table.
+func (s *MySqlStore) parseDirectoryEntriesInfo(dirPath string) (db *sql.DB, tableFullName string) {
+	instance_offset, table_postfix := s.hash(dirPath)
+	if s.isSharding {
+		tableFullName = fmt.Sprintf("%s_%04d", entriesTableName, table_postfix)
+	} else {
+		tableFullName = entriesTableName
+	}
+	return s.dbs[instance_offset], tableFullName
+}
+
 func (s *MySqlStore) Get(fullFilePath string) (fid string, err error) {
 	instance_offset, tableFullName, err := s.parseFilerMappingInfo(fullFilePath)
 	if err != nil {
@@ -147,11 +169,13 @@ func (s *MySqlStore) Put(fullFilePath string, fid string) (err error) {
 		return fmt.Errorf("MySqlStore Put operation failed when querying path %s: err is %v", fullFilePath, err)
 	} else {
 		if len(old_fid) == 0 {
-			err = s.insert(fullFilePath, fid, s.dbs[instance_offset], tableFullName)
-			err = fmt.Errorf("MySqlStore Put operation failed when inserting path %s with fid %s : err is %v", fullFilePath, fid, err)
+			if err = s.insert(fullFilePath, fid, s.dbs[instance_offset], tableFullName); err != nil {
+				err = fmt.Errorf("MySqlStore Put operation failed when inserting path %s with fid %s : err is %v", fullFilePath, fid, err)
+			}
 		} else {
-			err = s.update(fullFilePath, fid, s.dbs[instance_offset], tableFullName)
-			err = fmt.Errorf("MySqlStore Put operation failed when updating path %s with fid %s : err is %v", fullFilePath, fid, err)
+			if err = s.update(fullFilePath, fid, s.dbs[instance_offset], tableFullName); err != nil {
+				err = fmt.Errorf("MySqlStore Put operation failed when updating path %s with fid %s : err is %v", fullFilePath, fid, err)
+			}
 		}
 	}
 	return
@@ -175,6 +199,156 @@ func (s *MySqlStore) Delete(fullFilePath string) (err error) {
 	}
 }
 
+func (s *MySqlStore) PutEntry(dirPath string, name string, fid string, isDirectory bool) (err error) {
+	db, tableFullName := s.parseDirectoryEntriesInfo(dirPath)
+	sqlStatement := "INSERT INTO %s (parent,name,isDirectory,fid,createTime) VALUES(?,?,?,?,?) ON DUPLICATE KEY UPDATE fid=VALUES(fid), updateTime=VALUES(createTime)"
+	if _, err = db.Exec(fmt.Sprintf(sqlStatement, tableFullName), dirPath, name, isDirectory, fid, time.Now().Unix()); err != nil {
+		return fmt.Errorf("MySqlStore PutEntry operation failed when indexing %s in %s: err is %v", name, dirPath, err)
+	}
+	return nil
+}
+
+func (s *MySqlStore) DeleteEntry(dirPath string, name string, isDirectory bool) (err error) {
+	db, tableFullName := s.parseDirectoryEntriesInfo(dirPath)
+	sqlStatement := "DELETE FROM %s WHERE parent=? AND isDirectory=? AND name=?"
+	if _, err = db.Exec(fmt.Sprintf(sqlStatement, tableFullName), dirPath, isDirectory, name); err != nil {
+		return fmt.Errorf("MySqlStore DeleteEntry operation failed when deleting %s in %s: err is %v", name, dirPath, err)
+	}
+	return nil
+}
+
+func (s *MySqlStore) HasEntry(dirPath string, name string, isDirectory bool) (found bool, err error) {
+	db, tableFullName := s.parseDirectoryEntriesInfo(dirPath)
+	sqlStatement := "SELECT id FROM %s WHERE parent=? AND isDirectory=? AND name=?"
+	var id int64
+	err = db.QueryRow(fmt.Sprintf(sqlStatement, tableFullName), dirPath, isDirectory, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *MySqlStore) ListEntries(dirPath string, isDirectory bool, lastName string, limit int) (entries []filer.FileEntry, err error) {
+	db, tableFullName := s.parseDirectoryEntriesInfo(dirPath)
+	sqlStatement := "SELECT name, fid FROM %s WHERE parent=? AND isDirectory=? AND name>? ORDER BY name"
+	if limit > 0 {
+		sqlStatement += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := db.Query(fmt.Sprintf(sqlStatement, tableFullName), dirPath, isDirectory, lastName)
+	if err != nil {
+		return nil, fmt.Errorf("MySqlStore ListEntries operation failed when listing %s: err is %v", dirPath, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, fid string
+		if err = rows.Scan(&name, &fid); err != nil {
+			return nil, err
+		}
+		entries = append(entries, filer.FileEntry{Name: name, Id: filer.FileId(fid)})
+	}
+	return entries, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in a path prefix.
+func escapeLike(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+}
+
+// Rename moves a file, or a directory with all its files and sub directories, in one
+// transaction, by replacing the prefix of their paths. The paths of a sharded store
+// are spread over tables and instances, so only a store on one table renames.
+func (s *MySqlStore) Rename(fromPath string, toPath string, isDirectory bool) (err error) {
+	if s.isSharding || len(s.dbs) != 1 {
+		return flat_namespace.ErrNotImplemented
+	}
+	tx, err := s.dbs[0].Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("MySqlStore Rename operation failed when moving %s to %s: err is %v", fromPath, toPath, err)
+		}
+	}()
+
+	fromDir, fromName := filepath.Dir(fromPath), filepath.Base(fromPath)
+	toDir, toName := filepath.Dir(toPath), filepath.Base(toPath)
+	now := time.Now().Unix()
+	var statements []string
+	var args [][]interface{}
+	add := func(sqlStatement string, tableName string, arg ...interface{}) {
+		statements = append(statements, fmt.Sprintf(sqlStatement, tableName))
+		args = append(args, arg)
+	}
+	if !isDirectory {
+		add("DELETE FROM %s WHERE uriPath=?", tableName, toPath)
+		add("UPDATE %s SET uriPath=?, updateTime=? WHERE uriPath=?", tableName, toPath, now, fromPath)
+		add("DELETE FROM %s WHERE parent=? AND isDirectory=0 AND name=?", entriesTableName, toDir, toName)
+		add("UPDATE %s SET parent=?, name=?, updateTime=? WHERE parent=? AND isDirectory=0 AND name=?", entriesTableName, toDir, toName, now, fromDir, fromName)
+	} else {
+		// SUBSTRING counts characters from 1
+		rest := utf8.RuneCountInString(fromPath+"/") + 1
+		prefix := escapeLike(fromPath+"/") + "%"
+		add("UPDATE %s SET uriPath=CONCAT(?, SUBSTRING(uriPath, ?)), updateTime=? WHERE uriPath LIKE BINARY ?", tableName, toPath+"/", rest, now, prefix)
+		add("UPDATE %s SET parent=CONCAT(?, SUBSTRING(parent, ?)) WHERE parent LIKE ?", entriesTableName, toPath+"/", rest, prefix)
+		add("UPDATE %s SET parent=? WHERE parent=?", entriesTableName, toPath, fromPath)
+		add("UPDATE %s SET parent=?, name=?, updateTime=? WHERE parent=? AND isDirectory=1 AND name=?", entriesTableName, toDir, toName, now, fromDir, fromName)
+	}
+	for i, sqlStatement := range statements {
+		if _, err = tx.Exec(sqlStatement, args[i]...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Scan visits the full path mappings of all the tables, paged by their ids.
+func (s *MySqlStore) Scan(visit func(fullFileName string, fid string) error) error {
+	for _, db := range s.dbs {
+		for i := 0; i < s.shardCount; i++ {
+			tableFullName := tableName
+			if s.isSharding {
+				tableFullName = fmt.Sprintf("%s_%04d", tableName, i)
+			}
+			if err := s.scanTable(db, tableFullName, visit); err != nil {
+				return fmt.Errorf("MySqlStore Scan operation failed when scanning %s: err is %v", tableFullName, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *MySqlStore) scanTable(db *sql.DB, tableName string, visit func(fullFileName string, fid string) error) error {
+	sqlStatement := fmt.Sprintf("SELECT id, uriPath, fid FROM %s WHERE id>? ORDER BY id LIMIT %d", tableName, scanPageSize)
+	var lastId int64
+	for {
+		rows, err := db.Query(sqlStatement, lastId)
+		if err != nil {
+			return err
+		}
+		count := 0
+		for rows.Next() {
+			var uriPath, fid string
+			if err = rows.Scan(&lastId, &uriPath, &fid); err == nil {
+				err = visit(uriPath, fid)
+			}
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			count++
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		if count < scanPageSize {
+			return nil
+		}
+	}
+}
+
 func (s *MySqlStore) Close() {
 	for _, db := range s.dbs {
 		db.Close()
@@ -195,10 +369,26 @@ CREATE TABLE IF NOT EXISTS %s (
 ) DEFAULT CHARSET=utf8;
 `
 
-func (s *MySqlStore) createTables(db *sql.DB, tableName string, postfix int) error {
+// createEntriesTable indexes the entries of each directory, with the names
+// compared byte by byte, to list them in the same order as the other stores.
+var createEntriesTable = `
+CREATE TABLE IF NOT EXISTS %s (
+  id bigint(20) NOT NULL AUTO_INCREMENT,
+  parent varchar(255) CHARACTER SET utf8 COLLATE utf8_bin NOT NULL DEFAULT "" COMMENT 'parent directory path',
+  name varchar(255) CHARACTER SET utf8 COLLATE utf8_bin NOT NULL DEFAULT "" COMMENT 'file or sub directory name',
+  isDirectory tinyint(1) NOT NULL DEFAULT 0 COMMENT 'whether a sub directory',
+  fid char(36) NOT NULL DEFAULT "" COMMENT 'seaweedfs fid of a file',
+  createTime int(10) NOT NULL DEFAULT 0 COMMENT 'createdTime in unix timestamp',
+  updateTime int(10) NOT NULL DEFAULT 0 COMMENT 'updatedTime in unix timestamp',
+  PRIMARY KEY (id),
+  UNIQUE KEY index_parent_name (parent, isDirectory, name)
+) DEFAULT CHARSET=utf8;
+`
+
+func (s *MySqlStore) createTables(db *sql.DB, createTable string, tableName string, postfix int) error {
 	var realTableName string
 	if s.isSharding {
-		realTableName = fmt.Sprintf("%s_%4d", tableName, postfix)
+		realTableName = fmt.Sprintf("%s_%04d", tableName, postfix)
 	} else {
 		realTableName = tableName
 	}
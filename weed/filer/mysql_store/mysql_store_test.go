@@ -2,8 +2,14 @@ package mysql_store
 
 import (
 	"encoding/json"
+	"fmt"
 	"hash/crc32"
+	"os"
 	"testing"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer/filertest"
+	"github.com/chrislusf/seaweedfs/weed/filer/flat_namespace"
 )
 
 func TestGenerateMysqlConf(t *testing.T) {
@@ -28,3 +34,30 @@ func TestCRC32FullPathName(t *testing.T) {
 	table_postfix := int(hash_value) % 1024
 	t.Logf("table postfix %d", table_postfix)
 }
+
+// TestMysqlMove runs against the database in $MYSQL_TEST_CONF, a json MySqlConf like
+// {"User":"root","Password":"root","HostName":"localhost","Port":3306,"DataBase":"seaweedfs_test"}
+func TestMysqlMove(t *testing.T) {
+	confJson := os.Getenv("MYSQL_TEST_CONF")
+	if confJson == "" {
+		t.Skip("MYSQL_TEST_CONF is not set")
+	}
+	var conf MySqlConf
+	if err := json.Unmarshal([]byte(confJson), &conf); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewMysqlStore([]MySqlConf{conf}, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	f := flat_namespace.NewFlatNamespaceFiler("", s)
+
+	root := fmt.Sprintf("/move_test_%d", time.Now().UnixNano())
+	defer f.DeleteDirectory(root, true)
+	filertest.TestMove(t, f, root)
+
+	if dirs, _ := f.ListDirectories(root); len(dirs) != 2 || dirs[0] != "ab" || dirs[1] != "x" {
+		t.Errorf("directories of %s: %v", root, dirs)
+	}
+}
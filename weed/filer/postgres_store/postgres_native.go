@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"time"
+	"unicode/utf8"
 
 	"github.com/chrislusf/seaweedfs/weed/filer"
 	"github.com/chrislusf/seaweedfs/weed/glog"
@@ -16,6 +17,12 @@ import (
 
 type DirectoryId int32
 
+// execer runs the statements on the database, or in a transaction.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 func databaseExists(db *sql.DB, databaseName string) (bool, error) {
 	sqlStatement := "SELECT datname from pg_database WHERE datname='%s'"
 	row := db.QueryRow(fmt.Sprintf(sqlStatement, databaseName))
@@ -212,7 +219,7 @@ func (s *PostgresStore) insert(uriPath string, fid string) error {
 
 	existingId, _, _ := s.lookupDirectory(directoryPart)
 	if existingId == 0 {
-		s.recursiveInsertDirectory(directoryPart)
+		s.recursiveInsertDirectory(s.db, directoryPart)
 	}
 
 	sqlStatement := fmt.Sprintf("INSERT INTO %s (directoryPart,filePart,fid,createTime) VALUES($1, $2, $3, $4)", filesTableName)
@@ -235,7 +242,7 @@ func (s *PostgresStore) insert(uriPath string, fid string) error {
 	return nil
 }
 
-func (s *PostgresStore) recursiveInsertDirectory(dirPath string) {
+func (s *PostgresStore) recursiveInsertDirectory(db execer, dirPath string) {
 	pathParts := strings.Split(dirPath, "/")
 
 	var workingPath string = "/"
@@ -246,12 +253,12 @@ func (s *PostgresStore) recursiveInsertDirectory(dirPath string) {
 		workingPath += (part + "/")
 		existingId, _, _ := s.lookupDirectory(workingPath)
 		if existingId == 0 {
-			s.insertDirectory(workingPath)
+			s.insertDirectory(db, workingPath)
 		}
 	}
 }
 
-func (s *PostgresStore) insertDirectory(dirPath string) {
+func (s *PostgresStore) insertDirectory(db execer, dirPath string) {
 	pathParts := strings.Split(dirPath, "/")
 
 	directoryRoot := "/"
@@ -270,7 +277,7 @@ func (s *PostgresStore) insertDirectory(dirPath string) {
 	glog.V(4).Infof("Postgres query -- Inserting directory (if it doesn't exist) - root = %s, name = %s",
 		directoryRoot, directoryName)
 
-	_, err := s.db.Exec(sqlInsertDirectoryStatement, directoryRoot, directoryName, directoryRoot, directoryName)
+	_, err := db.Exec(sqlInsertDirectoryStatement, directoryRoot, directoryName, directoryRoot, directoryName)
 	if err != nil {
 		glog.V(0).Infof("Postgres query -- Error inserting directory - root = %s, name = %s: %s",
 			directoryRoot, directoryName, err)
@@ -454,3 +461,72 @@ func (s *PostgresStore) findFiles(dirPath string, lastFileName string, limit int
 
 	return files, err
 }
+
+// escapeLike escapes the LIKE wildcards in a path prefix.
+func escapeLike(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+}
+
+func fileExists(db execer, uriPath string) (bool, error) {
+	directoryPart, filePart := filepath.Split(uriPath)
+	sqlStatement := fmt.Sprintf("SELECT id FROM %s WHERE directoryPart=$1 AND filePart=$2", filesTableName)
+	var id int64
+	err := db.QueryRow(sqlStatement, directoryPart, filePart).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// directoryExists checks a directory path ending with "/".
+func (s *PostgresStore) directoryExists(db execer, dirPath string) (bool, error) {
+	if dirPath == "/" {
+		return true, nil
+	}
+	directoryRoot, directoryName := s.mySplitPath(dirPath)
+	sqlStatement := fmt.Sprintf("SELECT id FROM %s WHERE directoryRoot=$1 AND directoryName=$2", directoriesTableName)
+	var id DirectoryId
+	err := db.QueryRow(sqlStatement, directoryRoot, directoryName).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// moveFile renames a file, replacing the file at the new path if any.
+func (s *PostgresStore) moveFile(tx *sql.Tx, fromPath, toPath string) error {
+	fromDirectoryPart, fromFilePart := filepath.Split(fromPath)
+	toDirectoryPart, toFilePart := filepath.Split(toPath)
+	s.recursiveInsertDirectory(tx, toDirectoryPart)
+
+	sqlStatement := fmt.Sprintf("DELETE FROM %s WHERE directoryPart=$1 AND filePart=$2", filesTableName)
+	if _, err := tx.Exec(sqlStatement, toDirectoryPart, toFilePart); err != nil {
+		return err
+	}
+	sqlStatement = fmt.Sprintf("UPDATE %s SET directoryPart=$1, filePart=$2, updateTime=$3 WHERE directoryPart=$4 AND filePart=$5", filesTableName)
+	_, err := tx.Exec(sqlStatement, toDirectoryPart, toFilePart, time.Now().Unix(), fromDirectoryPart, fromFilePart)
+	return err
+}
+
+// moveDirectory renames a directory, with all its files and sub directories,
+// by replacing the prefix of their paths. The paths end with "/".
+func (s *PostgresStore) moveDirectory(tx *sql.Tx, fromDirPath, toDirPath string) error {
+	toDirectoryRoot, toDirectoryName := s.mySplitPath(toDirPath)
+	s.recursiveInsertDirectory(tx, toDirectoryRoot)
+
+	// substr counts characters from 1
+	rest := utf8.RuneCountInString(fromDirPath) + 1
+	prefix := escapeLike(fromDirPath) + "%"
+	sqlStatement := fmt.Sprintf("UPDATE %s SET directoryPart = $1::varchar || substr(directoryPart, $2::integer) WHERE directoryPart LIKE $3", filesTableName)
+	if _, err := tx.Exec(sqlStatement, toDirPath, rest, prefix); err != nil {
+		return err
+	}
+	sqlStatement = fmt.Sprintf("UPDATE %s SET directoryRoot = $1::varchar || substr(directoryRoot, $2::integer) WHERE directoryRoot LIKE $3", directoriesTableName)
+	if _, err := tx.Exec(sqlStatement, toDirPath, rest, prefix); err != nil {
+		return err
+	}
+	fromDirectoryRoot, fromDirectoryName := s.mySplitPath(fromDirPath)
+	sqlStatement = fmt.Sprintf("UPDATE %s SET directoryRoot=$1, directoryName=$2 WHERE directoryRoot=$3 AND directoryName=$4", directoriesTableName)
+	_, err := tx.Exec(sqlStatement, toDirectoryRoot, toDirectoryName, fromDirectoryRoot, fromDirectoryName)
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"github.com/chrislusf/seaweedfs/weed/filer"
 	"github.com/chrislusf/seaweedfs/weed/glog"
@@ -119,14 +120,80 @@ func (s *PostgresStore) ListFiles(dirPath string, lastFileName string, limit int
 func (s *PostgresStore) DeleteDirectory(dirPath string, recursive bool) (err error) {
 	err = s.deleteDirectory(dirPath, recursive)
 	if err != nil {
-		glog.V(0).Infof("Error in Postgres DeleteDir '%s' (recursive = '%t'): %s", err)
+		glog.V(0).Infof("Error in Postgres DeleteDir '%s' (recursive = '%t'): %s", dirPath, recursive, err)
 	}
 	return err
 }
 
+/*
+Move a folder or a file, with 4 Use cases:
+mv fromDir toNewDir
+mv fromDir toOldDir
+mv fromFile toDir
+mv fromFile toFile
+
+The file, or the folder with all its files and sub folders, is renamed in one
+transaction, so the readers find either the old or the new paths.
+*/
 func (s *PostgresStore) Move(fromPath string, toPath string) (err error) {
-	glog.V(3).Infoln("Calling posgres_store Move")
-	return errors.New("Move is not yet implemented for the PostgreSQL store.")
+	glog.V(3).Infof("Postgres Move %s to %s", fromPath, toPath)
+	fromPath = filepath.ToSlash(filepath.Clean("/" + fromPath))
+	toPath = filepath.ToSlash(filepath.Clean("/" + toPath))
+	if fromPath == "/" {
+		return errors.New("Can not move the root directory")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	isFile, err := fileExists(tx, fromPath)
+	if err != nil {
+		return err
+	}
+	if !isFile {
+		if isDir, err := s.directoryExists(tx, fromPath+"/"); err != nil {
+			return err
+		} else if !isDir {
+			return fmt.Errorf("File %s is not found!", fromPath)
+		}
+	}
+	if isDir, err := s.directoryExists(tx, strings.TrimSuffix(toPath, "/")+"/"); err != nil {
+		return err
+	} else if isDir {
+		// move under an existing folder
+		toPath = filepath.ToSlash(filepath.Join(toPath, filepath.Base(fromPath)))
+		if existing, err := s.directoryExists(tx, toPath+"/"); err != nil {
+			return err
+		} else if existing && (isFile || toPath != fromPath) {
+			return fmt.Errorf("%s already exists", toPath)
+		}
+	}
+	if toPath == fromPath {
+		return tx.Commit()
+	}
+
+	if isFile {
+		err = s.moveFile(tx, fromPath, toPath)
+	} else if toIsFile, e := fileExists(tx, toPath); e != nil {
+		err = e
+	} else if toIsFile {
+		err = fmt.Errorf("%s is a file", toPath)
+	} else if strings.HasPrefix(toPath, fromPath+"/") {
+		err = fmt.Errorf("Can not move %s under itself to %s", fromPath, toPath)
+	} else {
+		err = s.moveDirectory(tx, fromPath+"/", toPath+"/")
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 //func NewPostgresStore(master string, confs []PostgresConf, isSharding bool, shardCount int) *PostgresStore {
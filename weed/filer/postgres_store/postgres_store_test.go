@@ -0,0 +1,30 @@
+package postgres_store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer/filertest"
+)
+
+// TestPostgresMove runs against the database in $POSTGRES_TEST_CONF, a json PostgresConf like
+// {"User":"postgres","Password":"postgres","HostName":"localhost","Port":5432,"DataBase":"seaweedfs_test","SslMode":"disable"}
+func TestPostgresMove(t *testing.T) {
+	confJson := os.Getenv("POSTGRES_TEST_CONF")
+	if confJson == "" {
+		t.Skip("POSTGRES_TEST_CONF is not set")
+	}
+	var conf PostgresConf
+	if err := json.Unmarshal([]byte(confJson), &conf); err != nil {
+		t.Fatal(err)
+	}
+	s := NewPostgresStore("", conf)
+	defer s.Close()
+
+	root := fmt.Sprintf("/move_test_%d", time.Now().UnixNano())
+	defer s.DeleteDirectory(root+"/", true)
+	filertest.TestMove(t, s, root)
+}
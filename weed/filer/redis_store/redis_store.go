@@ -1,11 +1,25 @@
 package redis_store
 
 import (
+	"path/filepath"
+
 	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
 
 	"github.com/go-redis/redis"
 )
 
+/*
+RedisStore keeps the full path to file id mappings as plain keys, and the
+entries of each directory in two sorted sets, of its sub directories and of its
+files, all scored 0 so they are listed in name order:
+
+	/a/b.txt   -> fid
+	d:/a       -> sub directory names of /a
+	f:/a       -> file names of /a
+*/
+const scanCount = 1000
+
 type RedisStore struct {
 	Client *redis.Client
 }
@@ -43,6 +57,121 @@ func (s *RedisStore) Delete(fullFileName string) (err error) {
 	return err
 }
 
+func entriesKey(dirPath string, isDirectory bool) string {
+	if isDirectory {
+		return "d:" + dirPath
+	}
+	return "f:" + dirPath
+}
+
+// PutEntry indexes the entry name, as its file id is kept under its full path.
+func (s *RedisStore) PutEntry(dirPath string, name string, fid string, isDirectory bool) (err error) {
+	_, err = s.Client.ZAdd(entriesKey(dirPath, isDirectory), redis.Z{Member: name}).Result()
+	return err
+}
+
+func (s *RedisStore) DeleteEntry(dirPath string, name string, isDirectory bool) (err error) {
+	_, err = s.Client.ZRem(entriesKey(dirPath, isDirectory), name).Result()
+	return err
+}
+
+func (s *RedisStore) HasEntry(dirPath string, name string, isDirectory bool) (found bool, err error) {
+	_, err = s.Client.ZScore(entriesKey(dirPath, isDirectory), name).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ListEntries lists the entries after lastName. The names of the files gone from the
+// full path mappings, left by an interrupted delete, are dropped from the index,
+// and more names are listed in their place to fill the page.
+func (s *RedisStore) ListEntries(dirPath string, isDirectory bool, lastName string, limit int) (entries []filer.FileEntry, err error) {
+	key := entriesKey(dirPath, isDirectory)
+	for {
+		opt := redis.ZRangeBy{Min: "-", Max: "+"}
+		if lastName != "" {
+			opt.Min = "(" + lastName
+		}
+		if limit > 0 {
+			opt.Count = int64(limit - len(entries))
+		}
+		names, err := s.Client.ZRangeByLex(key, opt).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return entries, nil
+		}
+		lastName = names[len(names)-1]
+		if isDirectory {
+			for _, name := range names {
+				entries = append(entries, filer.FileEntry{Name: name})
+			}
+			return entries, nil
+		}
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dirPath, name)
+		}
+		fids, err := s.Client.MGet(paths...).Result()
+		if err != nil {
+			return nil, err
+		}
+		var stale []interface{}
+		for i, fid := range fids {
+			if fid, ok := fid.(string); ok {
+				entries = append(entries, filer.FileEntry{Name: names[i], Id: filer.FileId(fid)})
+			} else {
+				stale = append(stale, names[i])
+			}
+		}
+		if len(stale) == 0 || limit <= 0 {
+			return entries, s.dropStale(key, stale)
+		}
+		if err = s.dropStale(key, stale); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *RedisStore) dropStale(key string, names []interface{}) error {
+	if len(names) == 0 {
+		return nil
+	}
+	glog.V(1).Infof("dropping %d stale entries from %s", len(names), key)
+	_, err := s.Client.ZRem(key, names...).Result()
+	return err
+}
+
+// Scan visits the full path mappings, the keys starting with a slash.
+// A key may be visited more than once.
+func (s *RedisStore) Scan(visit func(fullFileName string, fid string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := s.Client.Scan(cursor, "/*", scanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			fids, err := s.Client.MGet(keys...).Result()
+			if err != nil {
+				return err
+			}
+			for i, fid := range fids {
+				if fid, ok := fid.(string); ok {
+					if err = visit(keys[i], fid); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
 func (s *RedisStore) Close() {
 	if s.Client != nil {
 		s.Client.Close()
@@ -0,0 +1,5 @@
+package sqlite_store
+
+type SqliteConf struct {
+	Path string // the database file, created if missing
+}
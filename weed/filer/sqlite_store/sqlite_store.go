@@ -0,0 +1,394 @@
+// +build cgo
+
+package sqlite_store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const tableName = "filer_entries"
+
+/*
+SqliteStore keeps the filer meta data in an embedded SQLite database, one row
+per file or directory, keyed by the full path of its parent and its name:
+
+	CREATE TABLE filer_entries (
+	  parent TEXT NOT NULL,             -- "/" for the entries of the root
+	  name TEXT NOT NULL,
+	  isDirectory INTEGER NOT NULL DEFAULT 0,
+	  fid TEXT NOT NULL DEFAULT '',
+	  createTime INTEGER NOT NULL DEFAULT 0,
+	  updateTime INTEGER NOT NULL DEFAULT 0,
+	  PRIMARY KEY (parent, name)
+	);
+
+A directory is listed by its primary key prefix, and moved, with all its sub
+entries, by replacing the prefix of their parents in one transaction.
+*/
+type SqliteStore struct {
+	master string
+	db     *sql.DB
+	mutex  sync.Mutex // serializes the changes that check the existing entries
+}
+
+var createTable = `
+CREATE TABLE IF NOT EXISTS %s (
+  parent TEXT NOT NULL,
+  name TEXT NOT NULL,
+  isDirectory INTEGER NOT NULL DEFAULT 0,
+  fid TEXT NOT NULL DEFAULT '',
+  createTime INTEGER NOT NULL DEFAULT 0,
+  updateTime INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (parent, name)
+);
+`
+
+// queryer runs the statements on the database, or in a transaction.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func NewSqliteStore(master string, conf SqliteConf) (*SqliteStore, error) {
+	if conf.Path == "" {
+		return nil, errors.New("the sqlite database path is not set")
+	}
+	// the write-ahead log lets the readers run along a writer
+	db, err := sql.Open("sqlite3", "file:"+conf.Path+"?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = db.Exec(fmt.Sprintf(createTable, tableName)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create table %s in %s: %v", tableName, conf.Path, err)
+	}
+	return &SqliteStore{master: master, db: db}, nil
+}
+
+func (s *SqliteStore) Close() {
+	s.db.Close()
+}
+
+// subtreeRange is the range of the parents of all the entries under the directory,
+// the paths starting with the directory path and a slash, which sort before the
+// directory path and the next character "0".
+func subtreeRange(dirPath string) (start string, limit string) {
+	if dirPath == "/" {
+		return "/", "0"
+	}
+	return dirPath + "/", dirPath + "0"
+}
+
+type entry struct {
+	isDirectory bool
+	fid         string
+}
+
+func getEntry(q queryer, fullPath string) (*entry, error) {
+	dir, name := filer.SplitPath(fullPath)
+	if name == "" {
+		return &entry{isDirectory: true}, nil
+	}
+	e := &entry{}
+	err := q.QueryRow(fmt.Sprintf("SELECT isDirectory, fid FROM %s WHERE parent=? AND name=?", tableName),
+		dir, name).Scan(&e.isDirectory, &e.fid)
+	if err == sql.ErrNoRows {
+		return nil, filer.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// makeParents adds the missing directories of the full path.
+func makeParents(tx *sql.Tx, fullPath string) error {
+	dir, _ := filer.SplitPath(fullPath)
+	if dir == "/" {
+		return nil
+	}
+	e, err := getEntry(tx, dir)
+	if err == filer.ErrNotFound {
+		if err = makeParents(tx, dir); err != nil {
+			return err
+		}
+		parent, name := filer.SplitPath(dir)
+		_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (parent, name, isDirectory, createTime) VALUES(?, ?, 1, ?)", tableName),
+			parent, name, time.Now().Unix())
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if !e.isDirectory {
+		return fmt.Errorf("%s is a file", dir)
+	}
+	return nil
+}
+
+// update runs the changes in one transaction.
+func (s *SqliteStore) update(fn func(tx *sql.Tx) error) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SqliteStore) CreateFile(fullFileName string, fid string) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fullFileName = filer.CleanPath(fullFileName)
+	return s.update(func(tx *sql.Tx) error {
+		if e, err := getEntry(tx, fullFileName); err == nil && e.isDirectory {
+			return fmt.Errorf("%s is a directory", fullFileName)
+		}
+		if err := makeParents(tx, fullFileName); err != nil {
+			return err
+		}
+		dir, name := filer.SplitPath(fullFileName)
+		now := time.Now().Unix()
+		_, err := tx.Exec(fmt.Sprintf("INSERT INTO %s (parent, name, fid, createTime, updateTime) VALUES(?, ?, ?, ?, ?) "+
+			"ON CONFLICT(parent, name) DO UPDATE SET fid=excluded.fid, updateTime=excluded.updateTime", tableName),
+			dir, name, fid, now, now)
+		return err
+	})
+}
+
+func (s *SqliteStore) FindFile(fullFileName string) (fid string, err error) {
+	e, err := getEntry(s.db, fullFileName)
+	if err != nil {
+		return "", err
+	}
+	if e.isDirectory {
+		return "", filer.ErrNotFound
+	}
+	return e.fid, nil
+}
+
+func (s *SqliteStore) LookupDirectoryEntry(dirPath string, name string) (found bool, fileId string, err error) {
+	e, err := getEntry(s.db, filepath.Join(dirPath, name))
+	if err != nil {
+		return false, "", err
+	}
+	return true, e.fid, nil
+}
+
+func (s *SqliteStore) DeleteFile(fullFileName string) (fid string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, err := getEntry(s.db, fullFileName)
+	if err == filer.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if e.isDirectory {
+		return "", fmt.Errorf("%s is a directory", fullFileName)
+	}
+	dir, name := filer.SplitPath(fullFileName)
+	_, err = s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE parent=? AND name=?", tableName), dir, name)
+	return e.fid, err
+}
+
+// checkDirectory returns the clean path of an existing directory.
+func (s *SqliteStore) checkDirectory(dirPath string) (string, error) {
+	dirPath = filer.CleanPath(dirPath)
+	e, err := getEntry(s.db, dirPath)
+	if err != nil {
+		return "", err
+	}
+	if !e.isDirectory {
+		return "", fmt.Errorf("%s is a file", dirPath)
+	}
+	return dirPath, nil
+}
+
+func (s *SqliteStore) ListDirectories(dirPath string) (dirs []filer.DirectoryName, err error) {
+	if dirPath, err = s.checkDirectory(dirPath); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(fmt.Sprintf("SELECT name FROM %s WHERE parent=? AND isDirectory=1 ORDER BY name", tableName), dirPath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, filer.DirectoryName(name))
+	}
+	return dirs, rows.Err()
+}
+
+func (s *SqliteStore) ListFiles(dirPath string, lastFileName string, limit int) (files []filer.FileEntry, err error) {
+	if dirPath, err = s.checkDirectory(dirPath); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = -1 // no limit
+	}
+	rows, err := s.db.Query(fmt.Sprintf("SELECT name, fid FROM %s WHERE parent=? AND isDirectory=0 AND name>? ORDER BY name LIMIT ?", tableName),
+		dirPath, lastFileName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, fid string
+		if err = rows.Scan(&name, &fid); err != nil {
+			return nil, err
+		}
+		files = append(files, filer.FileEntry{Name: name, Id: filer.FileId(fid)})
+	}
+	return files, rows.Err()
+}
+
+func (s *SqliteStore) DeleteDirectory(dirPath string, recursive bool) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if dirPath, err = s.checkDirectory(dirPath); err != nil {
+		return err
+	}
+	start, limit := subtreeRange(dirPath)
+	if !recursive {
+		var name string
+		err = s.db.QueryRow(fmt.Sprintf("SELECT name FROM %s WHERE parent=? LIMIT 1", tableName), dirPath).Scan(&name)
+		if err == nil {
+			return fmt.Errorf("Fail to delete non-empty directory %s!", dirPath)
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	// the files are deleted from the volume servers once their entries are gone
+	var fids []string
+	glog.V(3).Infof("deleting directory %s", dirPath)
+	if err = s.update(func(tx *sql.Tx) error {
+		rows, err := tx.Query(fmt.Sprintf("SELECT fid FROM %s WHERE isDirectory=0 AND (parent=? OR (parent>=? AND parent<?))", tableName),
+			dirPath, start, limit)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var fid string
+			if err = rows.Scan(&fid); err != nil {
+				rows.Close()
+				return err
+			}
+			fids = append(fids, fid)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE parent=? OR (parent>=? AND parent<?)", tableName),
+			dirPath, start, limit); err != nil {
+			return err
+		}
+		if dirPath == "/" {
+			return nil
+		}
+		parent, name := filer.SplitPath(dirPath)
+		_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE parent=? AND name=?", tableName), parent, name)
+		return err
+	}); err != nil {
+		return err
+	}
+	filer.DeleteFileIds(s.master, fids)
+	return nil
+}
+
+/*
+Move a folder or a file, with 4 Use cases:
+mv fromDir toNewDir
+mv fromDir toOldDir
+mv fromFile toDir
+mv fromFile toFile
+
+The file, or the folder with all its files and sub folders, is renamed in one
+transaction, so the readers find either the old or the new paths.
+*/
+func (s *SqliteStore) Move(fromPath string, toPath string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fromPath, toPath = filer.CleanPath(fromPath), filer.CleanPath(toPath)
+	from, err := getEntry(s.db, fromPath)
+	if err != nil {
+		return fmt.Errorf("File %s is not found!", fromPath)
+	}
+	if fromPath == "/" {
+		return errors.New("Can not move the root directory")
+	}
+	if to, err := getEntry(s.db, toPath); err == nil {
+		if !to.isDirectory {
+			if from.isDirectory {
+				return fmt.Errorf("%s is a file", toPath)
+			}
+		} else {
+			// move under an existing folder
+			toPath = filer.CleanPath(filepath.Join(toPath, filepath.Base(fromPath)))
+			if to, err := getEntry(s.db, toPath); err == nil && (from.isDirectory || to.isDirectory) {
+				if toPath == fromPath {
+					return nil
+				}
+				return fmt.Errorf("%s already exists", toPath)
+			}
+		}
+	} else if err != filer.ErrNotFound {
+		return err
+	}
+	if toPath == fromPath {
+		return nil
+	}
+	if from.isDirectory && strings.HasPrefix(toPath, fromPath+"/") {
+		return fmt.Errorf("Can not move %s under itself to %s", fromPath, toPath)
+	}
+
+	fromDir, fromName := filer.SplitPath(fromPath)
+	toDir, toName := filer.SplitPath(toPath)
+	glog.V(3).Infof("moving %s to %s", fromPath, toPath)
+	return s.update(func(tx *sql.Tx) error {
+		if err := makeParents(tx, toPath); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE parent=? AND name=? AND isDirectory=0", tableName), toDir, toName); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET parent=?, name=?, updateTime=? WHERE parent=? AND name=?", tableName),
+			toDir, toName, time.Now().Unix(), fromDir, fromName); err != nil {
+			return err
+		}
+		if !from.isDirectory {
+			return nil
+		}
+		// replace the leading directory path of the sub entries' parents,
+		// substr counting characters from 1
+		start, limit := subtreeRange(fromPath)
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET parent=? || substr(parent, ?) WHERE parent>=? AND parent<?", tableName),
+			toPath+"/", len([]rune(start))+1, start, limit); err != nil {
+			return err
+		}
+		_, err := tx.Exec(fmt.Sprintf("UPDATE %s SET parent=? WHERE parent=?", tableName), toPath, fromPath)
+		return err
+	})
+}
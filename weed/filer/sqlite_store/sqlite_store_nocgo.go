@@ -0,0 +1,14 @@
+// +build !cgo
+
+package sqlite_store
+
+import (
+	"errors"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+)
+
+// NewSqliteStore fails without cgo, which the sqlite driver is built with.
+func NewSqliteStore(master string, conf SqliteConf) (filer.Filer, error) {
+	return nil, errors.New("the sqlite filer store is not supported by this build, which needs cgo enabled")
+}
@@ -0,0 +1,34 @@
+// +build cgo
+
+package sqlite_store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/filer/filertest"
+)
+
+func TestSqliteStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "sqlite_store")
+	defer os.RemoveAll(dir)
+	conf := SqliteConf{Path: filepath.Join(dir, "filer.db")}
+	s, err := NewSqliteStore("localhost:9333", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filertest.TestStore(t, s)
+
+	// the entries are durable across a reopen
+	s.Close()
+	if s, err = NewSqliteStore("localhost:9333", conf); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if files, _ := s.ListFiles("/a", "", 0); len(files) != 1 || files[0].Id != "1,04" {
+		t.Errorf("files of /a after reopening: %v", files)
+	}
+}
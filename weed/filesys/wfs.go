@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"google.golang.org/grpc"
 	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
 )
 
 type WFS struct {
@@ -23,7 +24,7 @@ func (wfs *WFS) Root() (fs.Node, error) {
 
 func (wfs *WFS) withFilerClient(fn func(filer_pb.SeaweedFilerClient) error) error {
 
-	grpcConnection, err := grpc.Dial(wfs.filer, grpc.WithInsecure())
+	grpcConnection, err := grpc.Dial(wfs.filer, security.GrpcDialOption())
 	if err != nil {
 		return fmt.Errorf("fail to dial %s: %v", wfs.filer, err)
 	}
@@ -12,24 +12,37 @@ import (
 
 //many code is copied from http://camlistore.org/pkg/images/images.go
 func FixJpgOrientation(data []byte) (oriented []byte) {
+	angle, flipMode, ok := exifOrientation(data)
+	if !ok {
+		return data
+	}
+
+	if srcImage, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+		dstImage := flip(rotate(srcImage, angle), flipMode)
+		var buf bytes.Buffer
+		jpeg.Encode(&buf, dstImage, nil)
+		return buf.Bytes()
+	}
+
+	return data
+}
+
+// exifOrientation returns how to rotate and flip the jpeg image to its exif orientation,
+// and false if it needs no change.
+func exifOrientation(data []byte) (angle int, flipMode FlipDirection, ok bool) {
 	ex, err := exif.Decode(bytes.NewReader(data))
 	if err != nil {
-		return data
+		return
 	}
 	tag, err := ex.Get(exif.Orientation)
 	if err != nil {
-		return data
+		return
 	}
-	angle := 0
-	flipMode := FlipDirection(0)
 	orient, err := tag.Int(0)
 	if err != nil {
-		return data
+		return
 	}
 	switch orient {
-	case topLeftSide:
-		// do nothing
-		return data
 	case topRightSide:
 		flipMode = 2
 	case bottomRightSide:
@@ -47,16 +60,11 @@ func FixJpgOrientation(data []byte) (oriented []byte) {
 		flipMode = 2
 	case leftSideBottom:
 		angle = 90
+	default:
+		// topLeftSide needs nothing
+		return
 	}
-
-	if srcImage, _, err := image.Decode(bytes.NewReader(data)); err == nil {
-		dstImage := flip(rotate(srcImage, angle), flipMode)
-		var buf bytes.Buffer
-		jpeg.Encode(&buf, dstImage, nil)
-		return buf.Bytes()
-	}
-
-	return data
+	return angle, flipMode, true
 }
 
 // Exif Orientation Tag values
@@ -0,0 +1,314 @@
+package images
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// MaxTransformDimension limits the width and height of a transformed image
+	MaxTransformDimension = 4096
+	// MaxTransformBlur limits the blur sigma, as blurring costs grow with it
+	MaxTransformBlur = 20
+	// FormatAuto picks the best format accepted by the client
+	FormatAuto = "auto"
+)
+
+// ImageFormat encodes images of one format.
+type ImageFormat struct {
+	Name   string
+	Ext    string
+	Mime   string
+	Encode func(w io.Writer, m image.Image, quality int) error
+}
+
+var imageFormats = map[string]*ImageFormat{}
+
+// autoFormats are the formats FormatAuto picks from, the preferred first.
+var autoFormats []string
+
+// RegisterFormat makes the format available to transforms,
+// and to FormatAuto if preferred, before the formats registered earlier.
+func RegisterFormat(format *ImageFormat, preferred bool) {
+	imageFormats[format.Name] = format
+	if preferred {
+		autoFormats = append([]string{format.Name}, autoFormats...)
+	}
+}
+
+func init() {
+	RegisterFormat(&ImageFormat{Name: "jpeg", Ext: ".jpg", Mime: "image/jpeg", Encode: func(w io.Writer, m image.Image, quality int) error {
+		return jpeg.Encode(w, m, &jpeg.Options{Quality: quality})
+	}}, false)
+	RegisterFormat(&ImageFormat{Name: "png", Ext: ".png", Mime: "image/png", Encode: func(w io.Writer, m image.Image, quality int) error {
+		return png.Encode(w, m)
+	}}, false)
+	RegisterFormat(&ImageFormat{Name: "gif", Ext: ".gif", Mime: "image/gif", Encode: func(w io.Writer, m image.Image, quality int) error {
+		return gif.Encode(w, m, nil)
+	}}, false)
+}
+
+// FormatOfExt returns the format of a file extension, like ".jpg", or nil if unknown.
+func FormatOfExt(ext string) *ImageFormat {
+	switch strings.ToLower(ext) {
+	case ".jpg", ".jpeg":
+		return imageFormats["jpeg"]
+	case ".png":
+		return imageFormats["png"]
+	case ".gif":
+		return imageFormats["gif"]
+	case ".webp":
+		return imageFormats["webp"]
+	}
+	return nil
+}
+
+/*
+Transform describes the changes to an image, applied in this order:
+crop, resize, rotate, blur, then encoding in the format and quality.
+The exif data is always stripped, after applying its orientation to jpeg images.
+
+The url parameters are:
+
+	crop=x,y,width,height  crop to the rectangle, before resizing
+	w=width, h=height      resize to fit in the size, keeping the aspect ratio if one is 0
+	mode=fit|fill          fit in, or fill and crop to, the width and height.
+	                       The legacy f=2 means fit, and f=1 means fill
+	r=degrees              rotate counter clockwise
+	blur=sigma             gaussian blur
+	q=quality              1~100, for jpeg and webp
+	fmt=jpeg|png|gif|webp|auto, webp only if built with cgo
+	strip=1                only strip the exif data
+
+Volume servers without a transform secret only apply the basic transforms, see IsBasic.
+*/
+type Transform struct {
+	Crop    image.Rectangle
+	Width   int
+	Height  int
+	Mode    string
+	Rotate  int
+	Blur    float64
+	Quality int
+	Format  string
+	Strip   bool
+}
+
+// ParseTransform reads the transform from the url parameters.
+// It returns nil if there is nothing to transform.
+func ParseTransform(values url.Values) (t *Transform, err error) {
+	t = &Transform{}
+	if v := values.Get("crop"); v != "" {
+		parts := strings.Split(v, ",")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("crop %q is not like x,y,width,height", v)
+		}
+		var n [4]int
+		for i, part := range parts {
+			if n[i], err = strconv.Atoi(part); err != nil || n[i] < 0 {
+				return nil, fmt.Errorf("crop %q is not like x,y,width,height", v)
+			}
+		}
+		if n[2] == 0 || n[3] == 0 {
+			return nil, fmt.Errorf("crop %q is empty", v)
+		}
+		t.Crop = image.Rect(n[0], n[1], n[0]+n[2], n[1]+n[3])
+	}
+	if t.Width, err = parseTransformInt(values, "w", 0, MaxTransformDimension); err != nil {
+		return nil, err
+	}
+	if t.Height, err = parseTransformInt(values, "h", 0, MaxTransformDimension); err != nil {
+		return nil, err
+	}
+	switch mode := values.Get("mode"); mode {
+	case "fit", "fill":
+		t.Mode = mode
+	case "":
+		switch values.Get("f") {
+		case "2":
+			t.Mode = "fit"
+		case "1":
+			t.Mode = "fill"
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %q, should be fit or fill", mode)
+	}
+	if t.Mode != "" && (t.Width == 0 || t.Height == 0) {
+		t.Mode = ""
+	}
+	if t.Rotate, err = parseTransformInt(values, "r", -360, 360); err != nil {
+		return nil, err
+	}
+	if v := values.Get("blur"); v != "" {
+		if t.Blur, err = strconv.ParseFloat(v, 64); err != nil || t.Blur < 0 || t.Blur > MaxTransformBlur {
+			return nil, fmt.Errorf("blur %q should be between 0 and %d", v, MaxTransformBlur)
+		}
+	}
+	if t.Quality, err = parseTransformInt(values, "q", 0, 100); err != nil {
+		return nil, err
+	}
+	if t.Format = values.Get("fmt"); t.Format != "" && t.Format != FormatAuto && imageFormats[t.Format] == nil {
+		return nil, fmt.Errorf("unknown image format %q", t.Format)
+	}
+	t.Strip = values.Get("strip") == "1" || values.Get("strip") == "true"
+	if *t == (Transform{}) {
+		return nil, nil
+	}
+	return t, nil
+}
+
+func parseTransformInt(values url.Values, name string, min, max int) (int, error) {
+	v := values.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s %q should be between %d and %d", name, v, min, max)
+	}
+	return n, nil
+}
+
+// String is the canonical form of the transform, the same for equal transforms.
+func (t *Transform) String() string {
+	values := make(url.Values)
+	if !t.Crop.Empty() {
+		values.Set("crop", fmt.Sprintf("%d,%d,%d,%d", t.Crop.Min.X, t.Crop.Min.Y, t.Crop.Dx(), t.Crop.Dy()))
+	}
+	if t.Width != 0 {
+		values.Set("w", strconv.Itoa(t.Width))
+	}
+	if t.Height != 0 {
+		values.Set("h", strconv.Itoa(t.Height))
+	}
+	if t.Mode != "" {
+		values.Set("mode", t.Mode)
+	}
+	if t.Rotate != 0 {
+		values.Set("r", strconv.Itoa(t.Rotate))
+	}
+	if t.Blur != 0 {
+		values.Set("blur", strconv.FormatFloat(t.Blur, 'f', -1, 64))
+	}
+	if t.Quality != 0 {
+		values.Set("q", strconv.Itoa(t.Quality))
+	}
+	if t.Format != "" {
+		values.Set("fmt", t.Format)
+	}
+	if t.Strip {
+		values.Set("strip", "1")
+	}
+	return values.Encode()
+}
+
+// IsBasic reports whether the transform only resizes or rotates the image,
+// or strips its exif data, as the legacy w, h, mode, f and r parameters do.
+// Volume servers apply them without a signature if they have no transform secret.
+func (t *Transform) IsBasic() bool {
+	return t.Crop.Empty() && t.Blur == 0 && t.Quality == 0 && t.Format == ""
+}
+
+// Sign returns the signature of the transform, passed as the "sig" url parameter.
+// Volume servers with a transform secret only apply signed transforms,
+// so clients can not ask for arbitrary expensive ones.
+func (t *Transform) Sign(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t.String()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:18])
+}
+
+func (t *Transform) Verify(secret string, signature string) bool {
+	return hmac.Equal([]byte(t.Sign(secret)), []byte(signature))
+}
+
+// OutputFormat returns the format the image is encoded in, given the source
+// file extension and the Accept request header.
+func (t *Transform) OutputFormat(ext string, accept string) *ImageFormat {
+	if t.Format == FormatAuto {
+		for _, name := range autoFormats {
+			if strings.Contains(accept, imageFormats[name].Mime) {
+				return imageFormats[name]
+			}
+		}
+	} else if t.Format != "" {
+		return imageFormats[t.Format]
+	}
+	if format := FormatOfExt(ext); format != nil {
+		return format
+	}
+	return imageFormats["jpeg"]
+}
+
+// Apply transforms the image, and encodes it in the format.
+func (t *Transform) Apply(ext string, data []byte, format *ImageFormat) ([]byte, error) {
+	srcImage, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if f := FormatOfExt(ext); f != nil && f.Name == "jpeg" {
+		if angle, flipMode, ok := exifOrientation(data); ok {
+			srcImage = flip(rotate(srcImage, angle), flipMode)
+		}
+	}
+
+	dstImage := srcImage
+	if !t.Crop.Empty() {
+		crop := t.Crop.Add(dstImage.Bounds().Min).Intersect(dstImage.Bounds())
+		if crop.Empty() {
+			return nil, fmt.Errorf("crop %v is outside of the image %v", t.Crop, dstImage.Bounds())
+		}
+		dstImage = imaging.Crop(dstImage, crop)
+	}
+	if t.Width != 0 || t.Height != 0 {
+		dstImage = resize(dstImage, t.Width, t.Height, t.Mode)
+	}
+	if t.Rotate != 0 {
+		dstImage = imaging.Rotate(dstImage, float64(t.Rotate), color.Opaque)
+	}
+	if t.Blur > 0 {
+		dstImage = imaging.Blur(dstImage, t.Blur)
+	}
+
+	quality := t.Quality
+	if quality == 0 {
+		quality = jpeg.DefaultQuality
+	}
+	var buf bytes.Buffer
+	if err = format.Encode(&buf, dstImage, quality); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resize shrinks the image, but never enlarges it.
+func resize(srcImage image.Image, width, height int, mode string) image.Image {
+	bounds := srcImage.Bounds()
+	if !(bounds.Dx() > width && width != 0 || bounds.Dy() > height && height != 0) {
+		return srcImage
+	}
+	switch mode {
+	case "fit":
+		return imaging.Fit(srcImage, width, height, imaging.Lanczos)
+	case "fill":
+		return imaging.Fill(srcImage, width, height, imaging.Center, imaging.Lanczos)
+	}
+	if width == height && bounds.Dx() != bounds.Dy() {
+		return imaging.Thumbnail(srcImage, width, height, imaging.Lanczos)
+	}
+	return imaging.Resize(srcImage, width, height, imaging.Lanczos)
+}
@@ -0,0 +1,90 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"net/url"
+	"testing"
+)
+
+func TestParseTransform(t *testing.T) {
+	tr, err := ParseTransform(url.Values{"w": {"100"}, "h": {"80"}, "f": {"2"}, "fmt": {"jpeg"}, "q": {"70"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.String() != "fmt=jpeg&h=80&mode=fit&q=70&w=100" {
+		t.Errorf("unexpected canonical transform %s", tr)
+	}
+	if tr.IsBasic() {
+		t.Errorf("transform %s with a format is basic", tr)
+	}
+	if resize, _ := ParseTransform(url.Values{"w": {"100"}, "f": {"2"}}); !resize.IsBasic() {
+		t.Errorf("resizing transform %s is not basic", resize)
+	}
+	if rotate, _ := ParseTransform(url.Values{"w": {"100"}, "r": {"90"}}); !rotate.IsBasic() {
+		t.Errorf("rotating transform %s is not basic", rotate)
+	}
+	if tr, _ := ParseTransform(url.Values{"md5": {"1"}}); tr != nil {
+		t.Errorf("expected no transform, got %s", tr)
+	}
+	for _, values := range []url.Values{
+		{"w": {"5000"}},
+		{"blur": {"100"}},
+		{"crop": {"1,2,3"}},
+		{"fmt": {"bmp"}},
+		{"mode": {"stretch"}},
+	} {
+		if _, err := ParseTransform(values); err == nil {
+			t.Errorf("expected error for %v", values)
+		}
+	}
+}
+
+func TestSignTransform(t *testing.T) {
+	tr, _ := ParseTransform(url.Values{"w": {"100"}, "blur": {"2"}})
+	sig := tr.Sign("secret")
+	if !tr.Verify("secret", sig) {
+		t.Errorf("signature %s is not verified", sig)
+	}
+	if tr.Verify("other", sig) {
+		t.Errorf("signature %s is verified with another secret", sig)
+	}
+	other, _ := ParseTransform(url.Values{"w": {"1000"}, "blur": {"2"}})
+	if other.Verify("secret", sig) {
+		t.Errorf("signature %s is verified for another transform", sig)
+	}
+}
+
+func TestApplyTransform(t *testing.T) {
+	data, _ := ioutil.ReadFile("sample1.jpg")
+
+	tr, _ := ParseTransform(url.Values{"crop": {"0,0,200,100"}, "w": {"50"}, "fmt": {"png"}})
+	format := tr.OutputFormat(".jpg", "")
+	if format.Mime != "image/png" {
+		t.Fatalf("unexpected format %s", format.Name)
+	}
+	transformed, err := tr.Apply(".jpg", data, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, name, err := image.DecodeConfig(bytes.NewReader(transformed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "png" || m.Width != 50 || m.Height != 25 {
+		t.Errorf("unexpected %s image %dx%d", name, m.Width, m.Height)
+	}
+
+	auto, _ := ParseTransform(url.Values{"fmt": {"auto"}})
+	expected := "png"
+	if imageFormats["webp"] != nil {
+		expected = "webp"
+	}
+	if format := auto.OutputFormat(".png", "image/webp,*/*"); format.Name != expected {
+		t.Errorf("expected %s, got %s", expected, format.Name)
+	}
+	if format := auto.OutputFormat(".png", "*/*"); format.Name != "png" {
+		t.Errorf("expected png, got %s", format.Name)
+	}
+}
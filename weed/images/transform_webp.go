@@ -0,0 +1,17 @@
+// +build cgo
+
+package images
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// the webp encoder needs cgo, so the webp format is left out without it
+func init() {
+	RegisterFormat(&ImageFormat{Name: "webp", Ext: ".webp", Mime: "image/webp", Encode: func(w io.Writer, m image.Image, quality int) error {
+		return webp.Encode(w, m, &webp.Options{Quality: float32(quality)})
+	}}, true)
+}
@@ -0,0 +1,269 @@
+package images
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// VariantStorage keeps the contents of the variants, usually as files of a cache collection.
+type VariantStorage interface {
+	Save(data []byte, mime string) (fileId string, err error)
+	Load(fileId string) ([]byte, error)
+	Delete(fileIds []string) error
+}
+
+/*
+VariantCache keeps the derived images, like the transformed ones,
+so they are only generated once.
+
+Each variant is keyed by its original, the version of the original content,
+and the variant description, like the canonical transform and the output
+format. Its content is kept in a VariantStorage, and its file id in a local
+leveldb index. The variants of an original are deleted with Invalidate, when
+the original is deleted or overwritten. The oldest variants are evicted once
+they are older than maxAge, or take more than maxBytes.
+*/
+type VariantCache struct {
+	storage  VariantStorage
+	maxBytes int64
+	maxAge   time.Duration
+
+	sync.Mutex
+	db       *leveldb.DB
+	size     int64
+	evicting bool
+	calls    map[string]*variantCall
+}
+
+type variantEntry struct {
+	FileId  string `json:"fid"`
+	Mime    string `json:"mime"`
+	Size    int64  `json:"size"`
+	Created int64  `json:"created"`
+}
+
+// variantCall is a variant being generated, waited for by concurrent readers.
+type variantCall struct {
+	wg   sync.WaitGroup
+	data []byte
+	mime string
+	err  error
+}
+
+func NewVariantCache(dir string, storage VariantStorage, maxBytes int64, maxAge time.Duration) (c *VariantCache, err error) {
+	c = &VariantCache{
+		storage:  storage,
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		calls:    make(map[string]*variantCall),
+	}
+	if c.db, err = leveldb.OpenFile(dir, nil); err != nil {
+		return nil, err
+	}
+	iter := c.db.NewIterator(nil, nil)
+	for iter.Next() {
+		var entry variantEntry
+		if json.Unmarshal(iter.Value(), &entry) == nil {
+			c.size += entry.Size
+		}
+	}
+	iter.Release()
+	glog.V(0).Infof("loaded image variant cache %s, %d bytes", dir, c.size)
+
+	go c.loopEvict()
+	return c, nil
+}
+
+func variantKey(original, version, variant string) []byte {
+	return []byte(original + "\x00" + version + "\x00" + variant)
+}
+
+/*
+Get returns the variant of the original, generating and saving it if not cached.
+Concurrent calls for the same variant wait for one generation.
+The version, like a checksum, tells the original contents apart, so a variant
+of a previous content is not returned even if its invalidation is missed.
+*/
+func (c *VariantCache) Get(original, version, variant string, generate func() (data []byte, mime string, err error)) ([]byte, string, error) {
+	key := variantKey(original, version, variant)
+	if value, err := c.db.Get(key, nil); err == nil {
+		var entry variantEntry
+		if err = json.Unmarshal(value, &entry); err == nil {
+			data, err := c.storage.Load(entry.FileId)
+			if err == nil {
+				return data, entry.Mime, nil
+			}
+			glog.V(0).Infof("load image variant %s %s: %v", original, variant, err)
+		}
+		c.delete(key, nil)
+	}
+
+	c.Lock()
+	if call, found := c.calls[string(key)]; found {
+		c.Unlock()
+		call.wg.Wait()
+		return call.data, call.mime, call.err
+	}
+	call := &variantCall{}
+	call.wg.Add(1)
+	c.calls[string(key)] = call
+	c.Unlock()
+
+	call.data, call.mime, call.err = generate()
+	if call.err == nil {
+		c.put(key, call.data, call.mime)
+	}
+
+	c.Lock()
+	delete(c.calls, string(key))
+	c.Unlock()
+	call.wg.Done()
+	return call.data, call.mime, call.err
+}
+
+func (c *VariantCache) put(key []byte, data []byte, mime string) {
+	fileId, err := c.storage.Save(data, mime)
+	if err != nil {
+		glog.V(0).Infof("save image variant %s: %v", key, err)
+		return
+	}
+	value, _ := json.Marshal(&variantEntry{
+		FileId:  fileId,
+		Mime:    mime,
+		Size:    int64(len(data)),
+		Created: time.Now().Unix(),
+	})
+	if err = c.db.Put(key, value, nil); err != nil {
+		glog.V(0).Infof("index image variant %s: %v", key, err)
+		c.storage.Delete([]string{fileId})
+		return
+	}
+
+	c.Lock()
+	c.size += int64(len(data))
+	overflow := c.maxBytes > 0 && c.size > c.maxBytes && !c.evicting
+	c.Unlock()
+	if overflow {
+		go c.evict()
+	}
+}
+
+// delete removes the entry, and its content if fileIds is not nil.
+func (c *VariantCache) delete(key []byte, fileIds *[]string) {
+	c.Lock()
+	defer c.Unlock()
+
+	value, err := c.db.Get(key, nil)
+	if err != nil {
+		return
+	}
+	var entry variantEntry
+	json.Unmarshal(value, &entry)
+	if err = c.db.Delete(key, nil); err != nil {
+		glog.V(0).Infof("delete image variant %s: %v", key, err)
+		return
+	}
+	c.size -= entry.Size
+	if fileIds != nil && entry.FileId != "" {
+		*fileIds = append(*fileIds, entry.FileId)
+	}
+}
+
+func (c *VariantCache) deleteContents(fileIds []string) {
+	if len(fileIds) == 0 {
+		return
+	}
+	if err := c.storage.Delete(fileIds); err != nil {
+		glog.V(0).Infof("delete %d image variants: %v", len(fileIds), err)
+	}
+}
+
+// Invalidate deletes all variants of the original.
+func (c *VariantCache) Invalidate(original string) {
+	var keys [][]byte
+	iter := c.db.NewIterator(util.BytesPrefix([]byte(original+"\x00")), nil)
+	for iter.Next() {
+		keys = append(keys, append([]byte(nil), iter.Key()...))
+	}
+	iter.Release()
+
+	var fileIds []string
+	for _, key := range keys {
+		c.delete(key, &fileIds)
+	}
+	if len(fileIds) > 0 {
+		glog.V(2).Infof("invalidated %d image variants of %s", len(fileIds), original)
+	}
+	c.deleteContents(fileIds)
+}
+
+func (c *VariantCache) loopEvict() {
+	for range time.Tick(10 * time.Minute) {
+		c.evict()
+	}
+}
+
+// evict deletes the variants older than maxAge, and the oldest ones over maxBytes.
+func (c *VariantCache) evict() {
+	c.Lock()
+	if c.evicting {
+		c.Unlock()
+		return
+	}
+	c.evicting = true
+	c.Unlock()
+	defer func() {
+		c.Lock()
+		c.evicting = false
+		c.Unlock()
+	}()
+
+	type aged struct {
+		key     []byte
+		size    int64
+		created int64
+	}
+	var entries []aged
+	iter := c.db.NewIterator(nil, nil)
+	for iter.Next() {
+		var entry variantEntry
+		json.Unmarshal(iter.Value(), &entry)
+		entries = append(entries, aged{append([]byte(nil), iter.Key()...), entry.Size, entry.Created})
+	}
+	iter.Release()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].created < entries[j].created
+	})
+
+	var fileIds []string
+	expired := time.Now().Add(-c.maxAge).Unix()
+	c.Lock()
+	size := c.size
+	c.Unlock()
+	for _, entry := range entries {
+		if !(c.maxAge > 0 && entry.created < expired || c.maxBytes > 0 && size > c.maxBytes) {
+			break
+		}
+		c.delete(entry.key, &fileIds)
+		size -= entry.size
+	}
+	if len(fileIds) > 0 {
+		glog.V(1).Infof("evicted %d image variants", len(fileIds))
+	}
+	c.deleteContents(fileIds)
+}
+
+// VariantOf is the description of a transformed variant, in the output format.
+func VariantOf(t *Transform, format *ImageFormat) string {
+	return t.String() + "&out=" + format.Name
+}
+
+func (c *VariantCache) Close() {
+	c.db.Close()
+}
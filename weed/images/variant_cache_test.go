@@ -0,0 +1,112 @@
+package images
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryVariantStorage struct {
+	sync.Mutex
+	files map[string][]byte
+	next  int
+}
+
+func (s *memoryVariantStorage) Save(data []byte, mime string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.next++
+	fileId := fmt.Sprintf("9,%x", s.next)
+	s.files[fileId] = data
+	return fileId, nil
+}
+
+func (s *memoryVariantStorage) Load(fileId string) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	if data, ok := s.files[fileId]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s not found", fileId)
+}
+
+func (s *memoryVariantStorage) Delete(fileIds []string) error {
+	s.Lock()
+	defer s.Unlock()
+	for _, fileId := range fileIds {
+		delete(s.files, fileId)
+	}
+	return nil
+}
+
+func (s *memoryVariantStorage) count() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.files)
+}
+
+func TestVariantCache(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "variants")
+	defer os.RemoveAll(dir)
+	storage := &memoryVariantStorage{files: make(map[string][]byte)}
+	c, err := NewVariantCache(dir, storage, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var generated int
+	var lock sync.Mutex
+	generate := func(data string) func() ([]byte, string, error) {
+		return func() ([]byte, string, error) {
+			lock.Lock()
+			generated++
+			lock.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			return []byte(data), "image/webp", nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, mime, err := c.Get("3,1a", "v1", "w=10", generate("abc"))
+			if err != nil || string(data) != "abc" || mime != "image/webp" {
+				t.Errorf("unexpected variant %q %s %v", data, mime, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if generated != 1 {
+		t.Errorf("generated %d times concurrently", generated)
+	}
+	if data, _, _ := c.Get("3,1a", "v1", "w=10", generate("xyz")); string(data) != "abc" || generated != 1 {
+		t.Errorf("variant %q is not cached", data)
+	}
+	if data, _, _ := c.Get("3,1a", "v2", "w=10", generate("def")); string(data) != "def" {
+		t.Errorf("variant %q of another version", data)
+	}
+	c.Get("3,1ab", "v1", "w=10", generate("ghi"))
+
+	c.Invalidate("3,1a")
+	if storage.count() != 1 {
+		t.Errorf("expected only the variant of 3,1ab left, got %d", storage.count())
+	}
+
+	// over the size limit, evicting the oldest variant in the background
+	c.Get("3,1c", "v1", "w=10", generate("0123456789"))
+	for i := 0; i < 100 && storage.count() > 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Lock()
+	size := c.size
+	c.Unlock()
+	if storage.count() != 1 || size != 10 {
+		t.Errorf("expected the oldest variant evicted, got %d variants of %d bytes", storage.count(), size)
+	}
+}
@@ -1,14 +1,12 @@
 package operation
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
-	"net/url"
-	"strconv"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
-	"github.com/chrislusf/seaweedfs/weed/util"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 )
 
 type VolumeAssignRequest struct {
@@ -30,39 +28,46 @@ type AssignResult struct {
 }
 
 func Assign(server string, r *VolumeAssignRequest) (*AssignResult, error) {
-	values := make(url.Values)
-	values.Add("count", strconv.FormatUint(r.Count, 10))
-	if r.Replication != "" {
-		values.Add("replication", r.Replication)
-	}
-	if r.Collection != "" {
-		values.Add("collection", r.Collection)
-	}
-	if r.Ttl != "" {
-		values.Add("ttl", r.Ttl)
-	}
-	if r.DataCenter != "" {
-		values.Add("dataCenter", r.DataCenter)
-	}
-	if r.Rack != "" {
-		values.Add("rack", r.Rack)
-	}
-	if r.DataNode != "" {
-		values.Add("dataNode", r.DataNode)
-	}
+	return AssignContext(context.Background(), server, r)
+}
+
+// AssignContext is like Assign, and traces the request as part of the trace in ctx.
+func AssignContext(ctx context.Context, server string, r *VolumeAssignRequest) (ret *AssignResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "operation.Assign", tracing.SpanKindClient)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	span.SetAttribute("master", server)
 
-	jsonBlob, err := util.Post("http://"+server+"/dir/assign", values)
-	glog.V(2).Info("assign result :", string(jsonBlob))
+	err = WithMasterClient(ctx, server, func(ctx context.Context, client master_pb.SeaweedClient) error {
+		resp, err := client.Assign(ctx, &master_pb.AssignRequest{
+			Count:       r.Count,
+			Replication: r.Replication,
+			Collection:  r.Collection,
+			Ttl:         r.Ttl,
+			DataCenter:  r.DataCenter,
+			Rack:        r.Rack,
+			DataNode:    r.DataNode,
+		})
+		if err != nil {
+			return err
+		}
+		ret = &AssignResult{
+			Fid:       resp.Fid,
+			Url:       resp.Url,
+			PublicUrl: resp.PublicUrl,
+			Count:     resp.Count,
+			Error:     resp.Error,
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	var ret AssignResult
-	err = json.Unmarshal(jsonBlob, &ret)
-	if err != nil {
-		return nil, fmt.Errorf("/dir/assign result JSON unmarshal error:%v, json:%s", err, string(jsonBlob))
-	}
+	glog.V(2).Infof("assign result: %+v", ret)
 	if ret.Count <= 0 {
 		return nil, errors.New(ret.Error)
 	}
-	return &ret, nil
+	return ret, nil
 }
@@ -1,6 +1,7 @@
 package operation
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -91,6 +92,7 @@ func readChunkNeedle(fileUrl string, w io.Writer, offset int64) (written int64,
 	if offset > 0 {
 		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
 	}
+	util.AddApiKey(req.Header)
 
 	resp, err := util.Do(req)
 	if err != nil {
@@ -150,7 +152,7 @@ func (cf *ChunkedFileReader) WriteTo(w io.Writer) (n int64, err error) {
 	for ; chunkIndex < cm.Chunks.Len(); chunkIndex++ {
 		ci := cm.Chunks[chunkIndex]
 		// if we need read date from local volume server first?
-		fileUrl, lookupError := LookupFileId(cf.Master, ci.Fid)
+		fileUrl, lookupError := LookupFileIdForRead(context.Background(), cf.Master, ci.Fid)
 		if lookupError != nil {
 			return n, lookupError
 		}
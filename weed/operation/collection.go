@@ -0,0 +1,29 @@
+package operation
+
+import (
+	"context"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func ListCollections(server string) (names []string, err error) {
+	err = WithMasterClient(context.Background(), server, func(ctx context.Context, client master_pb.SeaweedClient) error {
+		resp, err := client.CollectionList(ctx, &master_pb.CollectionListRequest{})
+		if err != nil {
+			return err
+		}
+		for _, c := range resp.Collections {
+			names = append(names, c.Name)
+		}
+		return nil
+	})
+	return
+}
+
+// DeleteCollection deletes the collection on all the volume servers.
+func DeleteCollection(server string, collection string) error {
+	return WithMasterClient(context.Background(), server, func(ctx context.Context, client master_pb.SeaweedClient) error {
+		_, err := client.CollectionDelete(ctx, &master_pb.CollectionDeleteRequest{Name: collection})
+		return err
+	})
+}
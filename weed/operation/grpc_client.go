@@ -0,0 +1,57 @@
+package operation
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
+	"github.com/chrislusf/seaweedfs/weed/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	grpcClients     = make(map[string]*grpc.ClientConn)
+	grpcClientsLock sync.Mutex
+)
+
+// grpcConnection returns the connection to the server, shared by all calls.
+// A broken connection is re-established by grpc itself.
+func grpcConnection(address string) (*grpc.ClientConn, error) {
+	grpcClientsLock.Lock()
+	defer grpcClientsLock.Unlock()
+	if conn, ok := grpcClients[address]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.Dial(address, security.GrpcDialOption(),
+		grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor()))
+	if err != nil {
+		return nil, err
+	}
+	grpcClients[address] = conn
+	return conn, nil
+}
+
+// MasterClient returns a gRPC client of the master, on a shared connection.
+func MasterClient(master string) (master_pb.SeaweedClient, error) {
+	conn, err := grpcConnection(master)
+	if err != nil {
+		return nil, err
+	}
+	return master_pb.NewSeaweedClient(conn), nil
+}
+
+// WithMasterClient calls fn with a gRPC client of the master. The context
+// passed to fn carries the api key set with util.SetApiKey.
+func WithMasterClient(ctx context.Context, master string, fn func(ctx context.Context, client master_pb.SeaweedClient) error) error {
+	client, err := MasterClient(master)
+	if err != nil {
+		return err
+	}
+	if apiKey := util.GetApiKey(); apiKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, security.GrpcApiKeyMetadata, apiKey)
+	}
+	return fn(ctx, client)
+}
@@ -0,0 +1,73 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"google.golang.org/grpc"
+)
+
+// KeepConnectedToMaster receives the volume location changes from the master
+// leader, and applies them to the cached lookups, so reads do not go to
+// volume servers that are gone. It follows the leader and reconnects on
+// errors, and never returns.
+func KeepConnectedToMaster(master string, clientName string) {
+	current := master
+	for {
+		leader, err := keepConnectedToMaster(current, clientName)
+		if leader != "" {
+			glog.V(0).Infof("%s follows master leader %s", clientName, leader)
+			current = leader
+			continue
+		}
+		glog.V(0).Infof("%s lost volume location updates from master %s: %v", clientName, current, err)
+		current = master
+		time.Sleep(time.Second)
+	}
+}
+
+func keepConnectedToMaster(master string, clientName string) (leader string, err error) {
+	grpcConnection, err := grpc.Dial(master, security.GrpcDialOption())
+	if err != nil {
+		return "", fmt.Errorf("fail to dial: %v", err)
+	}
+	defer grpcConnection.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := master_pb.NewSeaweedClient(grpcConnection).KeepConnected(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err = stream.Send(&master_pb.ClientListenRequest{Name: clientName}); err != nil {
+		return "", err
+	}
+
+	// changes missed while disconnected are unknown, start over with
+	// the locations sent by the master
+	vc.Reset()
+	for {
+		location, err := stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		if location.Leader != "" {
+			return location.Leader, nil
+		}
+		applyVolumeLocation(location)
+	}
+}
+
+func applyVolumeLocation(location *master_pb.VolumeLocation) {
+	loc := Location{Url: location.Url, PublicUrl: location.PublicUrl}
+	for _, vid := range location.NewVids {
+		vc.AddLocation(vid, loc)
+	}
+	for _, vid := range location.DeletedVids {
+		vc.DeleteLocation(vid, location.Url)
+	}
+}
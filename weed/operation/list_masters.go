@@ -2,6 +2,7 @@ package operation
 
 import (
 	"encoding/json"
+	"net/url"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/util"
@@ -30,3 +31,21 @@ func ListMasters(server string) (leader string, peers []string, err error) {
 	}
 	return ret.Leader, peers, nil
 }
+
+// AddMaster adds a running master to the raft cluster of the master server.
+func AddMaster(server string, peer string) error {
+	return changeMasters(server, "add", peer)
+}
+
+// RemoveMaster removes a master from the raft cluster of the master server.
+func RemoveMaster(server string, peer string) error {
+	return changeMasters(server, "remove", peer)
+}
+
+func changeMasters(server string, action string, peer string) error {
+	values := make(url.Values)
+	values.Add("peer", peer)
+	jsonBlob, err := util.Post("http://"+server+"/cluster/peer/"+action, values)
+	glog.V(2).Info(action, " master result :", string(jsonBlob))
+	return err
+}
@@ -1,15 +1,16 @@
 package operation
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/chrislusf/seaweedfs/weed/util"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 )
 
 type Location struct {
@@ -20,6 +21,7 @@ type LookupResult struct {
 	VolumeId  string     `json:"volumeId,omitempty"`
 	Locations []Location `json:"locations,omitempty"`
 	Error     string     `json:"error,omitempty"`
+	Jwt       string     `json:"jwt,omitempty"` // read token of a looked up file id, if the master requires read tokens
 }
 
 func (lr *LookupResult) String() string {
@@ -28,12 +30,23 @@ func (lr *LookupResult) String() string {
 
 var (
 	vc VidCache // caching of volume locations, re-check if after 10 minutes
+
+	// whether the file id lookups of each master returned read tokens. The masters
+	// returning them are asked again on every read for a fresh token, since the
+	// tokens expire long before the cached locations
+	readJwtMasters     = make(map[string]bool)
+	readJwtMastersLock sync.RWMutex
 )
 
 func Lookup(server string, vid string) (ret *LookupResult, err error) {
+	return LookupContext(context.Background(), server, vid)
+}
+
+// LookupContext is like Lookup, and traces the request as part of the trace in ctx.
+func LookupContext(ctx context.Context, server string, vid string) (ret *LookupResult, err error) {
 	locations, cache_err := vc.Get(vid)
 	if cache_err != nil {
-		if ret, err = do_lookup(server, vid); err == nil {
+		if ret, err = do_lookup(ctx, server, vid); err == nil {
 			vc.Set(vid, ret.Locations, 10*time.Minute)
 		}
 	} else {
@@ -42,30 +55,60 @@ func Lookup(server string, vid string) (ret *LookupResult, err error) {
 	return
 }
 
-func do_lookup(server string, vid string) (*LookupResult, error) {
-	values := make(url.Values)
-	values.Add("volumeId", vid)
-	jsonBlob, err := util.Post("http://"+server+"/dir/lookup", values)
-	if err != nil {
-		return nil, err
-	}
-	var ret LookupResult
-	err = json.Unmarshal(jsonBlob, &ret)
+func do_lookup(ctx context.Context, server string, vid string) (ret *LookupResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "operation.Lookup", tracing.SpanKindClient)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	span.SetAttribute("master", server)
+	span.SetAttribute("volumeId", vid)
+
+	results, err := lookupVolumes(ctx, server, []string{vid})
 	if err != nil {
 		return nil, err
 	}
+	ret = &results[0]
 	if ret.Error != "" {
 		return nil, errors.New(ret.Error)
 	}
-	return &ret, nil
+	return ret, nil
+}
+
+// lookupVolumes asks the master for the locations of the volume ids,
+// returning one result for each of them, in the same order.
+func lookupVolumes(ctx context.Context, server string, vids []string) (ret []LookupResult, err error) {
+	err = WithMasterClient(ctx, server, func(ctx context.Context, client master_pb.SeaweedClient) error {
+		resp, err := client.LookupVolume(ctx, &master_pb.LookupVolumeRequest{VolumeIds: vids})
+		if err != nil {
+			return err
+		}
+		if len(resp.VolumeIdLocations) != len(vids) {
+			return fmt.Errorf("lookup %d volumes, got %d results", len(vids), len(resp.VolumeIdLocations))
+		}
+		for _, vl := range resp.VolumeIdLocations {
+			result := LookupResult{VolumeId: vl.VolumeId, Error: vl.Error, Jwt: vl.Jwt}
+			for _, loc := range vl.Locations {
+				result.Locations = append(result.Locations, Location{Url: loc.Url, PublicUrl: loc.PublicUrl})
+			}
+			ret = append(ret, result)
+		}
+		return nil
+	})
+	return
 }
 
 func LookupFileId(server string, fileId string) (fullUrl string, err error) {
+	return LookupFileIdContext(context.Background(), server, fileId)
+}
+
+// LookupFileIdContext is like LookupFileId, and traces the request as part of the trace in ctx.
+func LookupFileIdContext(ctx context.Context, server string, fileId string) (fullUrl string, err error) {
 	parts := strings.Split(fileId, ",")
 	if len(parts) != 2 {
 		return "", errors.New("Invalid fileId " + fileId)
 	}
-	lookup, lookupError := Lookup(server, parts[0])
+	lookup, lookupError := LookupContext(ctx, server, parts[0])
 	if lookupError != nil {
 		return "", lookupError
 	}
@@ -75,6 +118,39 @@ func LookupFileId(server string, fileId string) (fullUrl string, err error) {
 	return "http://" + lookup.Locations[rand.Intn(len(lookup.Locations))].Url + "/" + fileId, nil
 }
 
+// LookupFileIdForRead is like LookupFileIdContext, and adds the read token
+// for the file id to the url, if the master requires read tokens.
+func LookupFileIdForRead(ctx context.Context, server string, fileId string) (fullUrl string, err error) {
+	parts := strings.Split(fileId, ",")
+	if len(parts) != 2 {
+		return "", errors.New("Invalid fileId " + fileId)
+	}
+	readJwtMastersLock.RLock()
+	requiresJwt, known := readJwtMasters[server]
+	readJwtMastersLock.RUnlock()
+	if known && !requiresJwt {
+		if locations, cacheErr := vc.Get(parts[0]); cacheErr == nil && len(locations) > 0 {
+			return "http://" + locations[rand.Intn(len(locations))].Url + "/" + fileId, nil
+		}
+	}
+	lookup, err := do_lookup(ctx, server, fileId)
+	if err != nil {
+		return "", err
+	}
+	vc.Set(parts[0], lookup.Locations, 10*time.Minute)
+	readJwtMastersLock.Lock()
+	readJwtMasters[server] = lookup.Jwt != ""
+	readJwtMastersLock.Unlock()
+	if len(lookup.Locations) == 0 {
+		return "", errors.New("File Not Found")
+	}
+	fullUrl = "http://" + lookup.Locations[rand.Intn(len(lookup.Locations))].Url + "/" + fileId
+	if lookup.Jwt != "" {
+		fullUrl += "?jwt=" + lookup.Jwt
+	}
+	return fullUrl, nil
+}
+
 // LookupVolumeIds find volume locations by cache and actual lookup
 func LookupVolumeIds(server string, vids []string) (map[string]LookupResult, error) {
 	ret := make(map[string]LookupResult)
@@ -95,17 +171,12 @@ func LookupVolumeIds(server string, vids []string) (map[string]LookupResult, err
 	}
 
 	//only query unknown_vids
-	values := make(url.Values)
-	for _, vid := range unknown_vids {
-		values.Add("volumeId", vid)
-	}
-	jsonBlob, err := util.Post("http://"+server+"/vol/lookup", values)
+	results, err := lookupVolumes(context.Background(), server, unknown_vids)
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(jsonBlob, &ret)
-	if err != nil {
-		return nil, errors.New(err.Error() + " " + string(jsonBlob))
+	for _, result := range results {
+		ret[result.VolumeId] = result
 	}
 
 	//set newly checked vids to cache
@@ -0,0 +1,106 @@
+package operation
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"google.golang.org/grpc"
+)
+
+// fakeMaster answers volume lookups with one location, and a read token
+// for each looked up file id if requireJwt is set.
+type fakeMaster struct {
+	master_pb.SeaweedServer
+	volumeServer string
+	requireJwt   bool
+	lookups      int32
+}
+
+func (m *fakeMaster) LookupVolume(ctx context.Context, req *master_pb.LookupVolumeRequest) (*master_pb.LookupVolumeResponse, error) {
+	atomic.AddInt32(&m.lookups, 1)
+	resp := &master_pb.LookupVolumeResponse{}
+	for _, id := range req.VolumeIds {
+		location := &master_pb.VolumeIdLocation{VolumeId: id,
+			Locations: []*master_pb.Location{{Url: m.volumeServer, PublicUrl: m.volumeServer}}}
+		if m.requireJwt && strings.Contains(id, ",") {
+			location.Jwt = "token-" + id
+		}
+		resp.VolumeIdLocations = append(resp.VolumeIdLocations, location)
+	}
+	return resp, nil
+}
+
+func startFakeMaster(t *testing.T, m *fakeMaster) (address string, stop func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	master_pb.RegisterSeaweedServer(s, m)
+	go s.Serve(listener)
+	return listener.Addr().String(), s.Stop
+}
+
+func TestChunkedFileReadTokens(t *testing.T) {
+	// the volume server only serves the chunks with their own read token
+	volumeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fid := strings.TrimPrefix(r.URL.Path, "/")
+		if r.URL.Query().Get("jwt") != "token-"+fid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(fid + ";"))
+	}))
+	defer volumeServer.Close()
+
+	m := &fakeMaster{volumeServer: strings.TrimPrefix(volumeServer.URL, "http://"), requireJwt: true}
+	master, stop := startFakeMaster(t, m)
+	defer stop()
+	defer vc.Reset()
+
+	// the cached locations of the volume do not carry a token
+	if _, err := LookupFileId(master, "7,01"); err != nil {
+		t.Fatal(err)
+	}
+	reader := &ChunkedFileReader{Master: master, Manifest: &ChunkManifest{Chunks: ChunkList{
+		{Fid: "7,01", Offset: 0, Size: 5},
+		{Fid: "7,02", Offset: 5, Size: 5},
+	}}}
+	var buf bytes.Buffer
+	if _, err := reader.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "7,01;7,02;" {
+		t.Errorf("read %q", buf.String())
+	}
+	if lookups := atomic.LoadInt32(&m.lookups); lookups != 3 {
+		t.Errorf("looked up %d times, expecting a fresh token for each chunk", lookups)
+	}
+}
+
+func TestLookupFileIdForReadCaches(t *testing.T) {
+	m := &fakeMaster{volumeServer: "localhost:8080"}
+	master, stop := startFakeMaster(t, m)
+	defer stop()
+	defer vc.Reset()
+
+	for i := 0; i < 3; i++ {
+		fileUrl, err := LookupFileIdForRead(context.Background(), master, "8,01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fileUrl != "http://localhost:8080/8,01" {
+			t.Errorf("file url %s", fileUrl)
+		}
+	}
+	if lookups := atomic.LoadInt32(&m.lookups); lookups != 1 {
+		t.Errorf("looked up %d times without read tokens", lookups)
+	}
+}
@@ -55,3 +55,48 @@ func (vc *VidCache) Set(vid string, locations []Location, duration time.Duration
 		vc.cache[id-1].NextRefreshTime = time.Now().Add(duration)
 	}
 }
+
+// AddLocation adds a volume server to the cached locations of a volume.
+// Only the volumes looked up already are updated, the others would be
+// cached with the new location alone.
+func (vc *VidCache) AddLocation(vid uint32, location Location) {
+	id := int(vid)
+	vc.Lock()
+	defer vc.Unlock()
+	if id <= 0 || id > len(vc.cache) || vc.cache[id-1].Locations == nil {
+		return
+	}
+	info := &vc.cache[id-1]
+	locations := make([]Location, 0, len(info.Locations)+1)
+	for _, loc := range info.Locations {
+		if loc.Url != location.Url {
+			locations = append(locations, loc)
+		}
+	}
+	info.Locations = append(locations, location)
+}
+
+// DeleteLocation removes a volume server from the cached locations of a volume.
+func (vc *VidCache) DeleteLocation(vid uint32, url string) {
+	id := int(vid)
+	vc.Lock()
+	defer vc.Unlock()
+	if id <= 0 || id > len(vc.cache) {
+		return
+	}
+	info := &vc.cache[id-1]
+	var locations []Location
+	for _, loc := range info.Locations {
+		if loc.Url != url {
+			locations = append(locations, loc)
+		}
+	}
+	info.Locations = locations
+}
+
+// Reset forgets all the cached locations.
+func (vc *VidCache) Reset() {
+	vc.Lock()
+	defer vc.Unlock()
+	vc.cache = nil
+}
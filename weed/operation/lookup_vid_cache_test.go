@@ -24,3 +24,31 @@ func TestCaching(t *testing.T) {
 		t.Fatal("Not found vid 123")
 	}
 }
+
+func TestLocationUpdates(t *testing.T) {
+	var (
+		vc VidCache
+	)
+	vc.AddLocation(3, Location{Url: "a.com:8080"})
+	if ret, err := vc.Get("3"); err == nil {
+		t.Fatalf("locations of vid 3 = %v before any lookup", ret)
+	}
+	vc.Set("3", []Location{{Url: "a.com:8080"}}, time.Minute)
+	vc.AddLocation(3, Location{Url: "b.com:8080"})
+	vc.AddLocation(3, Location{Url: "a.com:8080"})
+	vc.AddLocation(5, Location{Url: "a.com:8080"})
+	if ret, _ := vc.Get("3"); len(ret) != 2 {
+		t.Fatalf("locations of vid 3 = %v, expected 2", ret)
+	}
+	vc.DeleteLocation(3, "a.com:8080")
+	if ret, _ := vc.Get("3"); len(ret) != 1 || ret[0].Url != "b.com:8080" {
+		t.Fatalf("locations of vid 3 = %v, expected b.com:8080", ret)
+	}
+	vc.DeleteLocation(3, "b.com:8080")
+	if ret, err := vc.Get("3"); err == nil {
+		t.Fatalf("locations of vid 3 = %v, expected none", ret)
+	}
+	if ret, err := vc.Get("5"); err == nil {
+		t.Fatalf("locations of vid 5 = %v, never looked up", ret)
+	}
+}
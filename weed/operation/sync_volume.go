@@ -3,18 +3,22 @@ package operation
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
+	"strconv"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
 type SyncVolumeResponse struct {
+	Collection      string `json:"Collection,omitempty"`
 	Replication     string `json:"Replication,omitempty"`
 	Ttl             string `json:"Ttl,omitempty"`
 	TailOffset      uint64 `json:"TailOffset,omitempty"`
 	CompactRevision uint16 `json:"CompactRevision,omitempty"`
 	IdxFileSize     uint64 `json:"IdxFileSize,omitempty"`
+	DedupFileSize   uint64 `json:"DedupFileSize,omitempty"`
 	Error           string `json:"error,omitempty"`
 }
 
@@ -52,3 +56,70 @@ func GetVolumeIdxEntries(server string, vid string, eachEntryFn func(key uint64,
 	}
 	return nil
 }
+
+// DownloadVolumeFile writes the first size bytes of the ".dat" or ".idx" file of a volume.
+// It fails if the volume is compacted to another revision.
+func DownloadVolumeFile(server string, vid string, ext string, compactRevision uint16, size uint64, w io.Writer) error {
+	values := make(url.Values)
+	values.Add("volume", vid)
+	values.Add("ext", ext)
+	values.Add("revision", strconv.Itoa(int(compactRevision)))
+	values.Add("size", strconv.FormatUint(size, 10))
+	return util.GetUrlStream("http://"+server+"/admin/volume/file", values, func(r io.Reader) error {
+		n, err := io.Copy(w, r)
+		if err != nil {
+			return err
+		}
+		if uint64(n) != size {
+			return fmt.Errorf("volume %s%s: received %d of %d bytes", vid, ext, n, size)
+		}
+		return nil
+	})
+}
+
+// CopyVolume asks the volume server to pull the volume from the source volume server.
+func CopyVolume(server string, vid string, source string) error {
+	return volumeAdmin(server, "/admin/volume/copy", vid, source)
+}
+
+// SyncVolume asks the volume server to catch up its volume with the copy on the source volume server.
+func SyncVolume(server string, vid string, source string) error {
+	return volumeAdmin(server, "/admin/volume/sync", vid, source)
+}
+
+// MarkVolumeReadOnly stops or resumes the writes to the volume on the volume server.
+func MarkVolumeReadOnly(server string, vid string, readOnly bool) error {
+	values := make(url.Values)
+	values.Add("volume", vid)
+	values.Add("readonly", strconv.FormatBool(readOnly))
+	jsonBlob, err := util.Post("http://"+server+"/admin/volume/readonly", values)
+	glog.V(2).Info("mark volume ", vid, " read-only ", readOnly, " result :", string(jsonBlob))
+	return err
+}
+
+// DeleteVolume deletes the volume from the volume server.
+func DeleteVolume(server string, vid string) error {
+	return volumeAdmin(server, "/admin/volume/delete", vid, "")
+}
+
+// MoveVolume asks the master to move the volume from the source to the target volume server.
+func MoveVolume(master string, vid string, source string, target string) error {
+	values := make(url.Values)
+	values.Add("volume", vid)
+	values.Add("source", source)
+	values.Add("target", target)
+	jsonBlob, err := util.Post("http://"+master+"/vol/move", values)
+	glog.V(2).Info("move volume ", vid, " result :", string(jsonBlob))
+	return err
+}
+
+func volumeAdmin(server string, path string, vid string, source string) error {
+	values := make(url.Values)
+	values.Add("volume", vid)
+	if source != "" {
+		values.Add("source", source)
+	}
+	jsonBlob, err := util.Post("http://"+server+path, values)
+	glog.V(2).Info(path, " volume ", vid, " result :", string(jsonBlob))
+	return err
+}
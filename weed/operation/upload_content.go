@@ -2,6 +2,7 @@ package operation
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,6 +17,8 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
+	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
 type UploadResult struct {
@@ -24,25 +27,26 @@ type UploadResult struct {
 	Error string `json:"error,omitempty"`
 }
 
-var (
-	client *http.Client
-)
-
-func init() {
-	client = &http.Client{Transport: &http.Transport{
-		MaxIdleConnsPerHost: 1024,
-	}}
-}
-
 var fileNameEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"")
 
 func Upload(uploadUrl string, filename string, reader io.Reader, isGzipped bool, mtype string, pairMap map[string]string, jwt security.EncodedJwt) (*UploadResult, error) {
-	return upload_content(uploadUrl, func(w io.Writer) (err error) {
+	return UploadContext(context.Background(), uploadUrl, filename, reader, isGzipped, mtype, pairMap, jwt)
+}
+
+// UploadContext is like Upload, and traces the request as part of the trace in ctx.
+func UploadContext(ctx context.Context, uploadUrl string, filename string, reader io.Reader, isGzipped bool, mtype string, pairMap map[string]string, jwt security.EncodedJwt) (ret *UploadResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "operation.Upload", tracing.SpanKindClient)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	span.SetAttribute("url", uploadUrl)
+	return upload_content(ctx, uploadUrl, func(w io.Writer) (err error) {
 		_, err = io.Copy(w, reader)
 		return
 	}, filename, isGzipped, mtype, pairMap, jwt)
 }
-func upload_content(uploadUrl string, fillBufferFunction func(w io.Writer) error, filename string, isGzipped bool, mtype string, pairMap map[string]string, jwt security.EncodedJwt) (*UploadResult, error) {
+func upload_content(ctx context.Context, uploadUrl string, fillBufferFunction func(w io.Writer) error, filename string, isGzipped bool, mtype string, pairMap map[string]string, jwt security.EncodedJwt) (*UploadResult, error) {
 	body_buf := bytes.NewBufferString("")
 	body_writer := multipart.NewWriter(body_buf)
 	h := make(textproto.MIMEHeader)
@@ -84,7 +88,9 @@ func upload_content(uploadUrl string, fillBufferFunction func(w io.Writer) error
 	for k, v := range pairMap {
 		req.Header.Set(k, v)
 	}
-	resp, post_err := client.Do(req)
+	util.AddApiKey(req.Header)
+	tracing.Inject(ctx, req.Header)
+	resp, post_err := util.Do(req)
 	if post_err != nil {
 		glog.V(0).Infoln("failing to upload to", uploadUrl, post_err.Error())
 		return nil, post_err
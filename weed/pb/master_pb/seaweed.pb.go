@@ -12,6 +12,19 @@ It has these top-level messages:
 	Heartbeat
 	HeartbeatResponse
 	VolumeInformationMessage
+	ClientListenRequest
+	VolumeLocation
+	AssignRequest
+	AssignResponse
+	LookupVolumeRequest
+	LookupVolumeResponse
+	VolumeIdLocation
+	Location
+	CollectionListRequest
+	CollectionListResponse
+	Collection
+	CollectionDeleteRequest
+	CollectionDeleteResponse
 */
 package master_pb
 
@@ -36,15 +49,17 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Heartbeat struct {
-	Ip             string                      `protobuf:"bytes,1,opt,name=ip" json:"ip,omitempty"`
-	Port           uint32                      `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
-	PublicUrl      string                      `protobuf:"bytes,3,opt,name=public_url,json=publicUrl" json:"public_url,omitempty"`
-	MaxVolumeCount uint32                      `protobuf:"varint,4,opt,name=max_volume_count,json=maxVolumeCount" json:"max_volume_count,omitempty"`
-	MaxFileKey     uint64                      `protobuf:"varint,5,opt,name=max_file_key,json=maxFileKey" json:"max_file_key,omitempty"`
-	DataCenter     string                      `protobuf:"bytes,6,opt,name=data_center,json=dataCenter" json:"data_center,omitempty"`
-	Rack           string                      `protobuf:"bytes,7,opt,name=rack" json:"rack,omitempty"`
-	AdminPort      uint32                      `protobuf:"varint,8,opt,name=admin_port,json=adminPort" json:"admin_port,omitempty"`
-	Volumes        []*VolumeInformationMessage `protobuf:"bytes,9,rep,name=volumes" json:"volumes,omitempty"`
+	Ip               string                      `protobuf:"bytes,1,opt,name=ip" json:"ip,omitempty"`
+	Port             uint32                      `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	PublicUrl        string                      `protobuf:"bytes,3,opt,name=public_url,json=publicUrl" json:"public_url,omitempty"`
+	MaxVolumeCount   uint32                      `protobuf:"varint,4,opt,name=max_volume_count,json=maxVolumeCount" json:"max_volume_count,omitempty"`
+	MaxFileKey       uint64                      `protobuf:"varint,5,opt,name=max_file_key,json=maxFileKey" json:"max_file_key,omitempty"`
+	DataCenter       string                      `protobuf:"bytes,6,opt,name=data_center,json=dataCenter" json:"data_center,omitempty"`
+	Rack             string                      `protobuf:"bytes,7,opt,name=rack" json:"rack,omitempty"`
+	AdminPort        uint32                      `protobuf:"varint,8,opt,name=admin_port,json=adminPort" json:"admin_port,omitempty"`
+	Volumes          []*VolumeInformationMessage `protobuf:"bytes,9,rep,name=volumes" json:"volumes,omitempty"`
+	IsDelta          bool                        `protobuf:"varint,10,opt,name=is_delta,json=isDelta" json:"is_delta,omitempty"`
+	DeletedVolumeIds []uint32                    `protobuf:"varint,11,rep,name=deleted_volume_ids,json=deletedVolumeIds" json:"deleted_volume_ids,omitempty"`
 }
 
 func (m *Heartbeat) Reset()                    { *m = Heartbeat{} }
@@ -115,10 +130,26 @@ func (m *Heartbeat) GetVolumes() []*VolumeInformationMessage {
 	return nil
 }
 
+func (m *Heartbeat) GetIsDelta() bool {
+	if m != nil {
+		return m.IsDelta
+	}
+	return false
+}
+
+func (m *Heartbeat) GetDeletedVolumeIds() []uint32 {
+	if m != nil {
+		return m.DeletedVolumeIds
+	}
+	return nil
+}
+
 type HeartbeatResponse struct {
-	VolumeSizeLimit uint64 `protobuf:"varint,1,opt,name=volumeSizeLimit" json:"volumeSizeLimit,omitempty"`
-	SecretKey       string `protobuf:"bytes,2,opt,name=secretKey" json:"secretKey,omitempty"`
-	Leader          string `protobuf:"bytes,3,opt,name=leader" json:"leader,omitempty"`
+	VolumeSizeLimit    uint64   `protobuf:"varint,1,opt,name=volumeSizeLimit" json:"volumeSizeLimit,omitempty"`
+	SecretKey          string   `protobuf:"bytes,2,opt,name=secretKey" json:"secretKey,omitempty"`
+	Leader             string   `protobuf:"bytes,3,opt,name=leader" json:"leader,omitempty"`
+	ReadSecretKey      string   `protobuf:"bytes,4,opt,name=readSecretKey" json:"readSecretKey,omitempty"`
+	PrivateCollections []string `protobuf:"bytes,5,rep,name=privateCollections" json:"privateCollections,omitempty"`
 }
 
 func (m *HeartbeatResponse) Reset()                    { *m = HeartbeatResponse{} }
@@ -147,6 +178,20 @@ func (m *HeartbeatResponse) GetLeader() string {
 	return ""
 }
 
+func (m *HeartbeatResponse) GetReadSecretKey() string {
+	if m != nil {
+		return m.ReadSecretKey
+	}
+	return ""
+}
+
+func (m *HeartbeatResponse) GetPrivateCollections() []string {
+	if m != nil {
+		return m.PrivateCollections
+	}
+	return nil
+}
+
 type VolumeInformationMessage struct {
 	Id               uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
 	Size             uint64 `protobuf:"varint,2,opt,name=size" json:"size,omitempty"`
@@ -158,6 +203,7 @@ type VolumeInformationMessage struct {
 	ReplicaPlacement uint32 `protobuf:"varint,8,opt,name=replica_placement,json=replicaPlacement" json:"replica_placement,omitempty"`
 	Version          uint32 `protobuf:"varint,9,opt,name=version" json:"version,omitempty"`
 	Ttl              uint32 `protobuf:"varint,10,opt,name=ttl" json:"ttl,omitempty"`
+	ModifiedAtSecond uint64 `protobuf:"varint,11,opt,name=modified_at_second,json=modifiedAtSecond" json:"modified_at_second,omitempty"`
 }
 
 func (m *VolumeInformationMessage) Reset()                    { *m = VolumeInformationMessage{} }
@@ -235,10 +281,374 @@ func (m *VolumeInformationMessage) GetTtl() uint32 {
 	return 0
 }
 
+func (m *VolumeInformationMessage) GetModifiedAtSecond() uint64 {
+	if m != nil {
+		return m.ModifiedAtSecond
+	}
+	return 0
+}
+
+type ClientListenRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *ClientListenRequest) Reset()                    { *m = ClientListenRequest{} }
+func (m *ClientListenRequest) String() string            { return proto.CompactTextString(m) }
+func (*ClientListenRequest) ProtoMessage()               {}
+func (*ClientListenRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *ClientListenRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type VolumeLocation struct {
+	Url         string   `protobuf:"bytes,1,opt,name=url" json:"url,omitempty"`
+	PublicUrl   string   `protobuf:"bytes,2,opt,name=public_url,json=publicUrl" json:"public_url,omitempty"`
+	NewVids     []uint32 `protobuf:"varint,3,rep,name=new_vids,json=newVids" json:"new_vids,omitempty"`
+	DeletedVids []uint32 `protobuf:"varint,4,rep,name=deleted_vids,json=deletedVids" json:"deleted_vids,omitempty"`
+	Leader      string   `protobuf:"bytes,5,opt,name=leader" json:"leader,omitempty"`
+}
+
+func (m *VolumeLocation) Reset()                    { *m = VolumeLocation{} }
+func (m *VolumeLocation) String() string            { return proto.CompactTextString(m) }
+func (*VolumeLocation) ProtoMessage()               {}
+func (*VolumeLocation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *VolumeLocation) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *VolumeLocation) GetPublicUrl() string {
+	if m != nil {
+		return m.PublicUrl
+	}
+	return ""
+}
+
+func (m *VolumeLocation) GetNewVids() []uint32 {
+	if m != nil {
+		return m.NewVids
+	}
+	return nil
+}
+
+func (m *VolumeLocation) GetDeletedVids() []uint32 {
+	if m != nil {
+		return m.DeletedVids
+	}
+	return nil
+}
+
+func (m *VolumeLocation) GetLeader() string {
+	if m != nil {
+		return m.Leader
+	}
+	return ""
+}
+
+type AssignRequest struct {
+	Count       uint64 `protobuf:"varint,1,opt,name=count" json:"count,omitempty"`
+	Replication string `protobuf:"bytes,2,opt,name=replication" json:"replication,omitempty"`
+	Collection  string `protobuf:"bytes,3,opt,name=collection" json:"collection,omitempty"`
+	Ttl         string `protobuf:"bytes,4,opt,name=ttl" json:"ttl,omitempty"`
+	DataCenter  string `protobuf:"bytes,5,opt,name=data_center,json=dataCenter" json:"data_center,omitempty"`
+	Rack        string `protobuf:"bytes,6,opt,name=rack" json:"rack,omitempty"`
+	DataNode    string `protobuf:"bytes,7,opt,name=data_node,json=dataNode" json:"data_node,omitempty"`
+}
+
+func (m *AssignRequest) Reset()                    { *m = AssignRequest{} }
+func (m *AssignRequest) String() string            { return proto.CompactTextString(m) }
+func (*AssignRequest) ProtoMessage()               {}
+func (*AssignRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *AssignRequest) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *AssignRequest) GetReplication() string {
+	if m != nil {
+		return m.Replication
+	}
+	return ""
+}
+
+func (m *AssignRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *AssignRequest) GetTtl() string {
+	if m != nil {
+		return m.Ttl
+	}
+	return ""
+}
+
+func (m *AssignRequest) GetDataCenter() string {
+	if m != nil {
+		return m.DataCenter
+	}
+	return ""
+}
+
+func (m *AssignRequest) GetRack() string {
+	if m != nil {
+		return m.Rack
+	}
+	return ""
+}
+
+func (m *AssignRequest) GetDataNode() string {
+	if m != nil {
+		return m.DataNode
+	}
+	return ""
+}
+
+type AssignResponse struct {
+	Fid       string `protobuf:"bytes,1,opt,name=fid" json:"fid,omitempty"`
+	Url       string `protobuf:"bytes,2,opt,name=url" json:"url,omitempty"`
+	PublicUrl string `protobuf:"bytes,3,opt,name=public_url,json=publicUrl" json:"public_url,omitempty"`
+	Count     uint64 `protobuf:"varint,4,opt,name=count" json:"count,omitempty"`
+	Error     string `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *AssignResponse) Reset()                    { *m = AssignResponse{} }
+func (m *AssignResponse) String() string            { return proto.CompactTextString(m) }
+func (*AssignResponse) ProtoMessage()               {}
+func (*AssignResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *AssignResponse) GetFid() string {
+	if m != nil {
+		return m.Fid
+	}
+	return ""
+}
+
+func (m *AssignResponse) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *AssignResponse) GetPublicUrl() string {
+	if m != nil {
+		return m.PublicUrl
+	}
+	return ""
+}
+
+func (m *AssignResponse) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *AssignResponse) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type LookupVolumeRequest struct {
+	VolumeIds  []string `protobuf:"bytes,1,rep,name=volume_ids,json=volumeIds" json:"volume_ids,omitempty"`
+	Collection string   `protobuf:"bytes,2,opt,name=collection" json:"collection,omitempty"`
+}
+
+func (m *LookupVolumeRequest) Reset()                    { *m = LookupVolumeRequest{} }
+func (m *LookupVolumeRequest) String() string            { return proto.CompactTextString(m) }
+func (*LookupVolumeRequest) ProtoMessage()               {}
+func (*LookupVolumeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *LookupVolumeRequest) GetVolumeIds() []string {
+	if m != nil {
+		return m.VolumeIds
+	}
+	return nil
+}
+
+func (m *LookupVolumeRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+type LookupVolumeResponse struct {
+	VolumeIdLocations []*VolumeIdLocation `protobuf:"bytes,1,rep,name=volume_id_locations,json=volumeIdLocations" json:"volume_id_locations,omitempty"`
+}
+
+func (m *LookupVolumeResponse) Reset()                    { *m = LookupVolumeResponse{} }
+func (m *LookupVolumeResponse) String() string            { return proto.CompactTextString(m) }
+func (*LookupVolumeResponse) ProtoMessage()               {}
+func (*LookupVolumeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *LookupVolumeResponse) GetVolumeIdLocations() []*VolumeIdLocation {
+	if m != nil {
+		return m.VolumeIdLocations
+	}
+	return nil
+}
+
+type VolumeIdLocation struct {
+	VolumeId  string      `protobuf:"bytes,1,opt,name=volume_id,json=volumeId" json:"volume_id,omitempty"`
+	Locations []*Location `protobuf:"bytes,2,rep,name=locations" json:"locations,omitempty"`
+	Error     string      `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	Jwt       string      `protobuf:"bytes,4,opt,name=jwt" json:"jwt,omitempty"`
+}
+
+func (m *VolumeIdLocation) Reset()                    { *m = VolumeIdLocation{} }
+func (m *VolumeIdLocation) String() string            { return proto.CompactTextString(m) }
+func (*VolumeIdLocation) ProtoMessage()               {}
+func (*VolumeIdLocation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *VolumeIdLocation) GetVolumeId() string {
+	if m != nil {
+		return m.VolumeId
+	}
+	return ""
+}
+
+func (m *VolumeIdLocation) GetLocations() []*Location {
+	if m != nil {
+		return m.Locations
+	}
+	return nil
+}
+
+func (m *VolumeIdLocation) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *VolumeIdLocation) GetJwt() string {
+	if m != nil {
+		return m.Jwt
+	}
+	return ""
+}
+
+type Location struct {
+	Url       string `protobuf:"bytes,1,opt,name=url" json:"url,omitempty"`
+	PublicUrl string `protobuf:"bytes,2,opt,name=public_url,json=publicUrl" json:"public_url,omitempty"`
+}
+
+func (m *Location) Reset()                    { *m = Location{} }
+func (m *Location) String() string            { return proto.CompactTextString(m) }
+func (*Location) ProtoMessage()               {}
+func (*Location) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *Location) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *Location) GetPublicUrl() string {
+	if m != nil {
+		return m.PublicUrl
+	}
+	return ""
+}
+
+type CollectionListRequest struct {
+}
+
+func (m *CollectionListRequest) Reset()                    { *m = CollectionListRequest{} }
+func (m *CollectionListRequest) String() string            { return proto.CompactTextString(m) }
+func (*CollectionListRequest) ProtoMessage()               {}
+func (*CollectionListRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+type CollectionListResponse struct {
+	Collections []*Collection `protobuf:"bytes,1,rep,name=collections" json:"collections,omitempty"`
+}
+
+func (m *CollectionListResponse) Reset()                    { *m = CollectionListResponse{} }
+func (m *CollectionListResponse) String() string            { return proto.CompactTextString(m) }
+func (*CollectionListResponse) ProtoMessage()               {}
+func (*CollectionListResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *CollectionListResponse) GetCollections() []*Collection {
+	if m != nil {
+		return m.Collections
+	}
+	return nil
+}
+
+type Collection struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *Collection) Reset()                    { *m = Collection{} }
+func (m *Collection) String() string            { return proto.CompactTextString(m) }
+func (*Collection) ProtoMessage()               {}
+func (*Collection) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *Collection) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type CollectionDeleteRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *CollectionDeleteRequest) Reset()                    { *m = CollectionDeleteRequest{} }
+func (m *CollectionDeleteRequest) String() string            { return proto.CompactTextString(m) }
+func (*CollectionDeleteRequest) ProtoMessage()               {}
+func (*CollectionDeleteRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *CollectionDeleteRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type CollectionDeleteResponse struct {
+}
+
+func (m *CollectionDeleteResponse) Reset()                    { *m = CollectionDeleteResponse{} }
+func (m *CollectionDeleteResponse) String() string            { return proto.CompactTextString(m) }
+func (*CollectionDeleteResponse) ProtoMessage()               {}
+func (*CollectionDeleteResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
 func init() {
 	proto.RegisterType((*Heartbeat)(nil), "master_pb.Heartbeat")
 	proto.RegisterType((*HeartbeatResponse)(nil), "master_pb.HeartbeatResponse")
 	proto.RegisterType((*VolumeInformationMessage)(nil), "master_pb.VolumeInformationMessage")
+	proto.RegisterType((*ClientListenRequest)(nil), "master_pb.ClientListenRequest")
+	proto.RegisterType((*VolumeLocation)(nil), "master_pb.VolumeLocation")
+	proto.RegisterType((*AssignRequest)(nil), "master_pb.AssignRequest")
+	proto.RegisterType((*AssignResponse)(nil), "master_pb.AssignResponse")
+	proto.RegisterType((*LookupVolumeRequest)(nil), "master_pb.LookupVolumeRequest")
+	proto.RegisterType((*LookupVolumeResponse)(nil), "master_pb.LookupVolumeResponse")
+	proto.RegisterType((*VolumeIdLocation)(nil), "master_pb.VolumeIdLocation")
+	proto.RegisterType((*Location)(nil), "master_pb.Location")
+	proto.RegisterType((*CollectionListRequest)(nil), "master_pb.CollectionListRequest")
+	proto.RegisterType((*CollectionListResponse)(nil), "master_pb.CollectionListResponse")
+	proto.RegisterType((*Collection)(nil), "master_pb.Collection")
+	proto.RegisterType((*CollectionDeleteRequest)(nil), "master_pb.CollectionDeleteRequest")
+	proto.RegisterType((*CollectionDeleteResponse)(nil), "master_pb.CollectionDeleteResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -253,6 +663,11 @@ const _ = grpc.SupportPackageIsVersion4
 
 type SeaweedClient interface {
 	SendHeartbeat(ctx context.Context, opts ...grpc.CallOption) (Seaweed_SendHeartbeatClient, error)
+	KeepConnected(ctx context.Context, opts ...grpc.CallOption) (Seaweed_KeepConnectedClient, error)
+	Assign(ctx context.Context, in *AssignRequest, opts ...grpc.CallOption) (*AssignResponse, error)
+	LookupVolume(ctx context.Context, in *LookupVolumeRequest, opts ...grpc.CallOption) (*LookupVolumeResponse, error)
+	CollectionList(ctx context.Context, in *CollectionListRequest, opts ...grpc.CallOption) (*CollectionListResponse, error)
+	CollectionDelete(ctx context.Context, in *CollectionDeleteRequest, opts ...grpc.CallOption) (*CollectionDeleteResponse, error)
 }
 
 type seaweedClient struct {
@@ -294,10 +709,82 @@ func (x *seaweedSendHeartbeatClient) Recv() (*HeartbeatResponse, error) {
 	return m, nil
 }
 
+func (c *seaweedClient) KeepConnected(ctx context.Context, opts ...grpc.CallOption) (Seaweed_KeepConnectedClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Seaweed_serviceDesc.Streams[1], c.cc, "/master_pb.Seaweed/KeepConnected", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &seaweedKeepConnectedClient{stream}
+	return x, nil
+}
+
+type Seaweed_KeepConnectedClient interface {
+	Send(*ClientListenRequest) error
+	Recv() (*VolumeLocation, error)
+	grpc.ClientStream
+}
+
+type seaweedKeepConnectedClient struct {
+	grpc.ClientStream
+}
+
+func (x *seaweedKeepConnectedClient) Send(m *ClientListenRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *seaweedKeepConnectedClient) Recv() (*VolumeLocation, error) {
+	m := new(VolumeLocation)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *seaweedClient) Assign(ctx context.Context, in *AssignRequest, opts ...grpc.CallOption) (*AssignResponse, error) {
+	out := new(AssignResponse)
+	err := grpc.Invoke(ctx, "/master_pb.Seaweed/Assign", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *seaweedClient) LookupVolume(ctx context.Context, in *LookupVolumeRequest, opts ...grpc.CallOption) (*LookupVolumeResponse, error) {
+	out := new(LookupVolumeResponse)
+	err := grpc.Invoke(ctx, "/master_pb.Seaweed/LookupVolume", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *seaweedClient) CollectionList(ctx context.Context, in *CollectionListRequest, opts ...grpc.CallOption) (*CollectionListResponse, error) {
+	out := new(CollectionListResponse)
+	err := grpc.Invoke(ctx, "/master_pb.Seaweed/CollectionList", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *seaweedClient) CollectionDelete(ctx context.Context, in *CollectionDeleteRequest, opts ...grpc.CallOption) (*CollectionDeleteResponse, error) {
+	out := new(CollectionDeleteResponse)
+	err := grpc.Invoke(ctx, "/master_pb.Seaweed/CollectionDelete", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Seaweed service
 
 type SeaweedServer interface {
 	SendHeartbeat(Seaweed_SendHeartbeatServer) error
+	KeepConnected(Seaweed_KeepConnectedServer) error
+	Assign(context.Context, *AssignRequest) (*AssignResponse, error)
+	LookupVolume(context.Context, *LookupVolumeRequest) (*LookupVolumeResponse, error)
+	CollectionList(context.Context, *CollectionListRequest) (*CollectionListResponse, error)
+	CollectionDelete(context.Context, *CollectionDeleteRequest) (*CollectionDeleteResponse, error)
 }
 
 func RegisterSeaweedServer(s *grpc.Server, srv SeaweedServer) {
@@ -330,10 +817,125 @@ func (x *seaweedSendHeartbeatServer) Recv() (*Heartbeat, error) {
 	return m, nil
 }
 
+func _Seaweed_KeepConnected_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SeaweedServer).KeepConnected(&seaweedKeepConnectedServer{stream})
+}
+
+type Seaweed_KeepConnectedServer interface {
+	Send(*VolumeLocation) error
+	Recv() (*ClientListenRequest, error)
+	grpc.ServerStream
+}
+
+type seaweedKeepConnectedServer struct {
+	grpc.ServerStream
+}
+
+func (x *seaweedKeepConnectedServer) Send(m *VolumeLocation) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *seaweedKeepConnectedServer) Recv() (*ClientListenRequest, error) {
+	m := new(ClientListenRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Seaweed_Assign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SeaweedServer).Assign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/master_pb.Seaweed/Assign",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SeaweedServer).Assign(ctx, req.(*AssignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Seaweed_LookupVolume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupVolumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SeaweedServer).LookupVolume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/master_pb.Seaweed/LookupVolume",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SeaweedServer).LookupVolume(ctx, req.(*LookupVolumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Seaweed_CollectionList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SeaweedServer).CollectionList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/master_pb.Seaweed/CollectionList",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SeaweedServer).CollectionList(ctx, req.(*CollectionListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Seaweed_CollectionDelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionDeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SeaweedServer).CollectionDelete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/master_pb.Seaweed/CollectionDelete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SeaweedServer).CollectionDelete(ctx, req.(*CollectionDeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Seaweed_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "master_pb.Seaweed",
 	HandlerType: (*SeaweedServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Assign",
+			Handler:    _Seaweed_Assign_Handler,
+		},
+		{
+			MethodName: "LookupVolume",
+			Handler:    _Seaweed_LookupVolume_Handler,
+		},
+		{
+			MethodName: "CollectionList",
+			Handler:    _Seaweed_CollectionList_Handler,
+		},
+		{
+			MethodName: "CollectionDelete",
+			Handler:    _Seaweed_CollectionDelete_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "SendHeartbeat",
@@ -341,6 +943,12 @@ var _Seaweed_serviceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "KeepConnected",
+			Handler:       _Seaweed_KeepConnected_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "seaweed.proto",
 }
@@ -348,37 +956,72 @@ var _Seaweed_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("seaweed.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 504 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x93, 0xdf, 0x6e, 0xd3, 0x30,
-	0x14, 0xc6, 0x49, 0x16, 0xda, 0xfa, 0x74, 0x1d, 0x9d, 0x85, 0x90, 0x05, 0x03, 0x4a, 0xb9, 0x89,
-	0x04, 0xaa, 0xd0, 0xb8, 0xe6, 0x86, 0x49, 0x88, 0x69, 0x20, 0x26, 0x17, 0xb8, 0x8d, 0xdc, 0xe4,
-	0x0c, 0x59, 0x73, 0xfe, 0xc8, 0x76, 0x47, 0xb3, 0x77, 0xe2, 0x2d, 0x78, 0x30, 0xe4, 0x93, 0xa6,
-	0x9d, 0x10, 0xdc, 0x1d, 0xff, 0xce, 0xe7, 0xf8, 0xe4, 0xfb, 0x6c, 0x98, 0x38, 0x54, 0x3f, 0x11,
-	0x8b, 0x45, 0x63, 0x6b, 0x5f, 0x73, 0x56, 0x2a, 0xe7, 0xd1, 0x66, 0xcd, 0x6a, 0xfe, 0x2b, 0x06,
-	0xf6, 0x11, 0x95, 0xf5, 0x2b, 0x54, 0x9e, 0x1f, 0x41, 0xac, 0x1b, 0x11, 0xcd, 0xa2, 0x94, 0xc9,
-	0x58, 0x37, 0x9c, 0x43, 0xd2, 0xd4, 0xd6, 0x8b, 0x78, 0x16, 0xa5, 0x13, 0x49, 0x35, 0x7f, 0x0a,
-	0xd0, 0xac, 0x57, 0x46, 0xe7, 0xd9, 0xda, 0x1a, 0x71, 0x40, 0x5a, 0xd6, 0x91, 0x6f, 0xd6, 0xf0,
-	0x14, 0xa6, 0xa5, 0xda, 0x64, 0x37, 0xb5, 0x59, 0x97, 0x98, 0xe5, 0xf5, 0xba, 0xf2, 0x22, 0xa1,
-	0xed, 0x47, 0xa5, 0xda, 0x7c, 0x27, 0x7c, 0x16, 0x28, 0x9f, 0xc1, 0x61, 0x50, 0x5e, 0x69, 0x83,
-	0xd9, 0x35, 0xb6, 0xe2, 0xfe, 0x2c, 0x4a, 0x13, 0x09, 0xa5, 0xda, 0x7c, 0xd0, 0x06, 0x2f, 0xb0,
-	0xe5, 0xcf, 0x61, 0x5c, 0x28, 0xaf, 0xb2, 0x1c, 0x2b, 0x8f, 0x56, 0x0c, 0xe8, 0x2c, 0x08, 0xe8,
-	0x8c, 0x48, 0x98, 0xcf, 0xaa, 0xfc, 0x5a, 0x0c, 0xa9, 0x43, 0x75, 0x98, 0x4f, 0x15, 0xa5, 0xae,
-	0x32, 0x9a, 0x7c, 0x44, 0x47, 0x33, 0x22, 0x97, 0x61, 0xfc, 0x77, 0x30, 0xec, 0x66, 0x73, 0x82,
-	0xcd, 0x0e, 0xd2, 0xf1, 0xe9, 0xcb, 0xc5, 0xce, 0x8d, 0x45, 0x37, 0xde, 0x79, 0x75, 0x55, 0xdb,
-	0x52, 0x79, 0x5d, 0x57, 0x9f, 0xd1, 0x39, 0xf5, 0x03, 0x65, 0xbf, 0x67, 0xee, 0xe0, 0x78, 0x67,
-	0x97, 0x44, 0xd7, 0xd4, 0x95, 0x43, 0x9e, 0xc2, 0x83, 0xae, 0xbf, 0xd4, 0xb7, 0xf8, 0x49, 0x97,
-	0xda, 0x93, 0x87, 0x89, 0xfc, 0x1b, 0xf3, 0x13, 0x60, 0x0e, 0x73, 0x8b, 0xfe, 0x02, 0x5b, 0x72,
-	0x95, 0xc9, 0x3d, 0xe0, 0x8f, 0x60, 0x60, 0x50, 0x15, 0x68, 0xb7, 0xb6, 0x6e, 0x57, 0xf3, 0xdf,
-	0x31, 0x88, 0xff, 0x8d, 0x46, 0x99, 0x15, 0x74, 0xde, 0x44, 0xc6, 0xba, 0x08, 0x9e, 0x38, 0x7d,
-	0x8b, 0xf4, 0xf5, 0x44, 0x52, 0xcd, 0x9f, 0x01, 0xe4, 0xb5, 0x31, 0x98, 0x87, 0x8d, 0xdb, 0x8f,
-	0xdf, 0x21, 0xc1, 0x33, 0x8a, 0x61, 0x1f, 0x57, 0x22, 0x59, 0x20, 0x5d, 0x52, 0x2f, 0xe0, 0xb0,
-	0x40, 0x83, 0xbe, 0x17, 0x74, 0x49, 0x8d, 0x3b, 0xd6, 0x49, 0x5e, 0x03, 0xef, 0x96, 0x45, 0xb6,
-	0x6a, 0x77, 0xc2, 0x01, 0x09, 0xa7, 0xdb, 0xce, 0xfb, 0xb6, 0x57, 0x3f, 0x01, 0x66, 0x51, 0x15,
-	0x59, 0x5d, 0x99, 0x96, 0xc2, 0x1b, 0xc9, 0x51, 0x00, 0x5f, 0x2a, 0xd3, 0xf2, 0x57, 0x70, 0x6c,
-	0xb1, 0x31, 0x3a, 0x57, 0x59, 0x63, 0x54, 0x8e, 0x25, 0x56, 0x7d, 0x8e, 0xd3, 0x6d, 0xe3, 0xb2,
-	0xe7, 0x5c, 0xc0, 0xf0, 0x06, 0xad, 0x0b, 0xbf, 0xc5, 0x48, 0xd2, 0x2f, 0xf9, 0x14, 0x0e, 0xbc,
-	0x37, 0x02, 0x88, 0x86, 0xf2, 0xf4, 0x2b, 0x0c, 0x97, 0xdd, 0x3b, 0xe0, 0xe7, 0x30, 0x59, 0x62,
-	0x55, 0xec, 0x6f, 0xfe, 0xc3, 0x3b, 0xb7, 0x60, 0x47, 0x1f, 0x9f, 0xfc, 0x8b, 0xf6, 0xb1, 0xcf,
-	0xef, 0xa5, 0xd1, 0x9b, 0x68, 0x35, 0xa0, 0x37, 0xf5, 0xf6, 0x4f, 0x00, 0x00, 0x00, 0xff, 0xff,
-	0x01, 0x14, 0xbb, 0x3a, 0x64, 0x03, 0x00, 0x00,
+	// 1065 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x9c, 0x56, 0x4b, 0x6f, 0x1c, 0x45,
+	0x10, 0x66, 0xf6, 0x3d, 0xb5, 0x59, 0xb3, 0x6e, 0x3b, 0xc9, 0x78, 0x9d, 0xc7, 0x64, 0xc2, 0x61,
+	0x11, 0x60, 0x81, 0x39, 0x70, 0x40, 0x08, 0x05, 0x47, 0x88, 0xc8, 0x06, 0x9c, 0x31, 0x84, 0x13,
+	0x1a, 0xb5, 0xa7, 0xcb, 0x51, 0xc7, 0xf3, 0x62, 0xba, 0x77, 0xed, 0xcd, 0x85, 0x3b, 0x7f, 0x80,
+	0xdf, 0x84, 0xf8, 0x37, 0x39, 0x70, 0x46, 0xdd, 0x3d, 0xaf, 0x7d, 0xd8, 0x48, 0xdc, 0x7a, 0xbe,
+	0xfa, 0xba, 0xbb, 0xfa, 0xab, 0xaf, 0x4a, 0x03, 0x23, 0x81, 0xf4, 0x0a, 0x91, 0x1d, 0x64, 0x79,
+	0x2a, 0x53, 0x62, 0xc7, 0x54, 0x48, 0xcc, 0x83, 0xec, 0xdc, 0xfb, 0xa7, 0x05, 0xf6, 0x77, 0x48,
+	0x73, 0x79, 0x8e, 0x54, 0x92, 0x2d, 0x68, 0xf1, 0xcc, 0xb1, 0x5c, 0x6b, 0x6a, 0xfb, 0x2d, 0x9e,
+	0x11, 0x02, 0x9d, 0x2c, 0xcd, 0xa5, 0xd3, 0x72, 0xad, 0xe9, 0xc8, 0xd7, 0x6b, 0xf2, 0x10, 0x20,
+	0x9b, 0x9d, 0x47, 0x3c, 0x0c, 0x66, 0x79, 0xe4, 0xb4, 0x35, 0xd7, 0x36, 0xc8, 0xcf, 0x79, 0x44,
+	0xa6, 0x30, 0x8e, 0xe9, 0x75, 0x30, 0x4f, 0xa3, 0x59, 0x8c, 0x41, 0x98, 0xce, 0x12, 0xe9, 0x74,
+	0xf4, 0xf6, 0xad, 0x98, 0x5e, 0xbf, 0xd2, 0xf0, 0x91, 0x42, 0x89, 0x0b, 0x77, 0x14, 0xf3, 0x82,
+	0x47, 0x18, 0x5c, 0xe2, 0xc2, 0xe9, 0xba, 0xd6, 0xb4, 0xe3, 0x43, 0x4c, 0xaf, 0xbf, 0xe5, 0x11,
+	0x1e, 0xe3, 0x82, 0x3c, 0x86, 0x21, 0xa3, 0x92, 0x06, 0x21, 0x26, 0x12, 0x73, 0xa7, 0xa7, 0xef,
+	0x02, 0x05, 0x1d, 0x69, 0x44, 0xe5, 0x97, 0xd3, 0xf0, 0xd2, 0xe9, 0xeb, 0x88, 0x5e, 0xab, 0xfc,
+	0x28, 0x8b, 0x79, 0x12, 0xe8, 0xcc, 0x07, 0xfa, 0x6a, 0x5b, 0x23, 0xa7, 0x2a, 0xfd, 0xaf, 0xa0,
+	0x6f, 0x72, 0x13, 0x8e, 0xed, 0xb6, 0xa7, 0xc3, 0xc3, 0xa7, 0x07, 0x95, 0x1a, 0x07, 0x26, 0xbd,
+	0x17, 0xc9, 0x45, 0x9a, 0xc7, 0x54, 0xf2, 0x34, 0xf9, 0x1e, 0x85, 0xa0, 0xaf, 0xd1, 0x2f, 0xf7,
+	0x90, 0x3d, 0x18, 0x70, 0x11, 0x30, 0x8c, 0x24, 0x75, 0xc0, 0xb5, 0xa6, 0x03, 0xbf, 0xcf, 0xc5,
+	0x73, 0xf5, 0x49, 0x3e, 0x06, 0xc2, 0x30, 0x42, 0x89, 0xac, 0x7c, 0x3d, 0x67, 0xc2, 0x19, 0xba,
+	0xed, 0xe9, 0xc8, 0x1f, 0x17, 0x91, 0xe2, 0x02, 0x26, 0xbc, 0xbf, 0x2c, 0xd8, 0xae, 0x84, 0xf7,
+	0x51, 0x64, 0x69, 0x22, 0x90, 0x4c, 0xe1, 0x7d, 0xb3, 0xf7, 0x8c, 0xbf, 0xc5, 0x13, 0x1e, 0x73,
+	0xa9, 0xab, 0xd1, 0xf1, 0x57, 0x61, 0xf2, 0x00, 0x6c, 0x81, 0x61, 0x8e, 0xf2, 0x18, 0x17, 0xba,
+	0x3e, 0xb6, 0x5f, 0x03, 0xe4, 0x1e, 0xf4, 0x22, 0xa4, 0x0c, 0xf3, 0xa2, 0x40, 0xc5, 0x17, 0xf9,
+	0x00, 0x46, 0x39, 0x52, 0x76, 0x56, 0xed, 0xec, 0xe8, 0xf0, 0x32, 0x48, 0x0e, 0x80, 0x64, 0x39,
+	0x9f, 0x53, 0x89, 0x47, 0x69, 0x14, 0x61, 0xa8, 0x94, 0x10, 0x4e, 0xd7, 0x6d, 0x4f, 0x6d, 0x7f,
+	0x43, 0xc4, 0x7b, 0xd7, 0x02, 0xe7, 0x26, 0xe9, 0xb4, 0xa7, 0x98, 0x7e, 0xc5, 0xc8, 0x6f, 0x71,
+	0xa6, 0x6a, 0x26, 0xf8, 0x5b, 0xd4, 0x39, 0x77, 0x7c, 0xbd, 0x26, 0x8f, 0x00, 0xc2, 0xea, 0xbc,
+	0x22, 0xe5, 0x06, 0xa2, 0x6a, 0xaa, 0x6d, 0x52, 0xdb, 0xa9, 0xe3, 0xdb, 0x0a, 0x31, 0x4e, 0x7a,
+	0x02, 0x77, 0x8c, 0xbe, 0x05, 0xc1, 0x38, 0x69, 0x68, 0x30, 0x43, 0x69, 0x14, 0xe7, 0x7c, 0x51,
+	0x11, 0x7b, 0x9a, 0x58, 0x16, 0xe7, 0x9b, 0x45, 0xc9, 0xde, 0x07, 0x5b, 0x29, 0x12, 0xa4, 0x49,
+	0xb4, 0xd0, 0xe6, 0x1a, 0xf8, 0x03, 0x05, 0xfc, 0x98, 0x44, 0x0b, 0xf2, 0x11, 0x6c, 0xe7, 0x98,
+	0x45, 0x3c, 0xa4, 0x41, 0x16, 0xd1, 0x10, 0x63, 0x4c, 0x4a, 0x9f, 0x8d, 0x8b, 0xc0, 0x69, 0x89,
+	0x13, 0x07, 0xfa, 0x73, 0xcc, 0x85, 0x7a, 0x96, 0xad, 0x29, 0xe5, 0x27, 0x19, 0x43, 0x5b, 0xca,
+	0x48, 0x9b, 0x68, 0xe4, 0xab, 0xa5, 0xca, 0x31, 0x4e, 0x19, 0xbf, 0xe0, 0xc8, 0x02, 0x2a, 0x03,
+	0x81, 0x61, 0x9a, 0x30, 0x67, 0x68, 0x72, 0x2c, 0x23, 0xcf, 0xe4, 0x99, 0xc6, 0xbd, 0x0f, 0x61,
+	0xe7, 0x28, 0xe2, 0x98, 0xc8, 0x13, 0x2e, 0x24, 0x26, 0x3e, 0xfe, 0x36, 0x43, 0x21, 0x95, 0xbc,
+	0x09, 0x8d, 0xb1, 0x68, 0x62, 0xbd, 0xf6, 0xfe, 0xb4, 0x60, 0xcb, 0xd4, 0xe7, 0x24, 0x0d, 0xa9,
+	0x2c, 0x6e, 0x57, 0xed, 0x6b, 0x58, 0x6a, 0xb9, 0xd2, 0xd7, 0xad, 0xd5, 0xbe, 0xde, 0x83, 0x41,
+	0x82, 0x57, 0xc1, 0x5c, 0x79, 0xba, 0xad, 0x3d, 0xdd, 0x4f, 0xf0, 0xea, 0x15, 0x67, 0xa2, 0x96,
+	0x9f, 0x99, 0x70, 0x47, 0x87, 0x87, 0xa5, 0xe5, 0x15, 0xa5, 0xf6, 0x63, 0xb7, 0xe9, 0x47, 0xef,
+	0x6f, 0x0b, 0x46, 0xcf, 0x84, 0xe0, 0xaf, 0xab, 0xfc, 0x77, 0xa1, 0x6b, 0x6a, 0x63, 0x7c, 0x6f,
+	0x3e, 0x88, 0x0b, 0xc3, 0x42, 0x5a, 0xed, 0x10, 0x93, 0x5d, 0x13, 0xfa, 0x4f, 0x0b, 0x15, 0x72,
+	0x1b, 0xbf, 0xab, 0xe5, 0xea, 0x74, 0xe9, 0xde, 0x38, 0x5d, 0x7a, 0x8d, 0xe9, 0xb2, 0x0f, 0xb6,
+	0xde, 0x94, 0xa4, 0x0c, 0x8b, 0xb1, 0x33, 0x50, 0xc0, 0x0f, 0x29, 0x43, 0xef, 0x77, 0xd8, 0x2a,
+	0x1f, 0x53, 0xf4, 0xf3, 0x18, 0xda, 0x17, 0x85, 0xfb, 0x6d, 0x5f, 0x2d, 0x4b, 0xe1, 0x5b, 0x37,
+	0x09, 0xbf, 0x36, 0x50, 0x2b, 0x41, 0x3a, 0x4d, 0x41, 0x76, 0xa1, 0x8b, 0x79, 0x9e, 0x96, 0x69,
+	0x9b, 0x0f, 0xef, 0x27, 0xd8, 0x39, 0x49, 0xd3, 0xcb, 0x59, 0x66, 0xaa, 0x5d, 0x6a, 0xfa, 0x10,
+	0xa0, 0x31, 0x91, 0x2c, 0xdd, 0xc7, 0xf6, 0xbc, 0x1c, 0x45, 0x2b, 0xd2, 0xb5, 0x56, 0xa5, 0xf3,
+	0x42, 0xd8, 0x5d, 0x3e, 0xb5, 0x78, 0xdc, 0x31, 0xec, 0x54, 0xc7, 0x06, 0x51, 0xe1, 0x2c, 0x73,
+	0xfe, 0xf0, 0x70, 0x7f, 0x7d, 0xac, 0xb2, 0xd2, 0x7d, 0xfe, 0xf6, 0x7c, 0x05, 0x11, 0xde, 0x1f,
+	0x16, 0x8c, 0x57, 0x79, 0x4a, 0xed, 0xea, 0x86, 0x42, 0xc4, 0x41, 0xb9, 0x95, 0x7c, 0x06, 0x76,
+	0x7d, 0x69, 0x4b, 0x5f, 0xba, 0xd3, 0xb8, 0xb4, 0xba, 0xac, 0x66, 0xd5, 0xaa, 0xb5, 0x1b, 0xaa,
+	0xa9, 0x92, 0xbc, 0xb9, 0x92, 0xa5, 0x35, 0xde, 0x5c, 0x49, 0xef, 0x4b, 0x18, 0xfc, 0xef, 0x4e,
+	0xf1, 0xee, 0xc3, 0xdd, 0x7a, 0x38, 0xaa, 0xe6, 0x2c, 0xca, 0xe0, 0xbd, 0x84, 0x7b, 0xab, 0x81,
+	0x42, 0xc9, 0x2f, 0x60, 0x18, 0x36, 0x26, 0xad, 0x51, 0xf0, 0x6e, 0xe3, 0x31, 0xf5, 0x3e, 0xbf,
+	0xc9, 0xf4, 0x5c, 0x80, 0x3a, 0xb4, 0xb1, 0xf7, 0x3f, 0x81, 0xfb, 0x35, 0xe3, 0xb9, 0x6e, 0xc9,
+	0xdb, 0x46, 0xc5, 0x04, 0x9c, 0x75, 0xba, 0xc9, 0xf2, 0xf0, 0x5d, 0x1b, 0xfa, 0x67, 0xe6, 0x47,
+	0x82, 0xbc, 0x80, 0xd1, 0x19, 0x26, 0xac, 0xfe, 0x75, 0xd8, 0x6d, 0x64, 0x5b, 0xa1, 0x93, 0x07,
+	0x9b, 0xd0, 0xf2, 0x40, 0xef, 0xbd, 0xa9, 0xf5, 0xa9, 0x45, 0x4e, 0x61, 0x74, 0x8c, 0x98, 0x1d,
+	0xa5, 0x49, 0x82, 0xa1, 0x44, 0x46, 0x1e, 0x35, 0x1f, 0xbe, 0x3e, 0xe2, 0x26, 0x7b, 0x6b, 0xd6,
+	0x2a, 0x8b, 0x55, 0x9c, 0xf8, 0x35, 0xf4, 0x4c, 0x1f, 0x12, 0xa7, 0x41, 0x5d, 0x9a, 0x33, 0x93,
+	0xbd, 0x0d, 0x91, 0x32, 0x2d, 0xf2, 0x12, 0xee, 0x34, 0x1d, 0xbf, 0x94, 0xd1, 0x86, 0x06, 0x9b,
+	0x3c, 0xbe, 0x31, 0x5e, 0x1d, 0xf9, 0x0b, 0x6c, 0x2d, 0x17, 0x9f, 0xb8, 0x1b, 0xeb, 0xdb, 0x30,
+	0xcc, 0xe4, 0xc9, 0x2d, 0x8c, 0xea, 0xe0, 0x5f, 0x61, 0xbc, 0x5a, 0x31, 0xe2, 0x6d, 0xdc, 0xb8,
+	0x54, 0xfd, 0xc9, 0xd3, 0x5b, 0x39, 0xe5, 0xf1, 0xe7, 0x3d, 0xfd, 0xcb, 0xf8, 0xf9, 0xbf, 0x03,
+	0x00, 0x84, 0x35, 0x1e, 0x6e, 0x43, 0x0a, 0x00, 0x00,
 }
@@ -0,0 +1,208 @@
+package security
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// ApiKeyHeader is the request header carrying the api key of a credential.
+// The key can also be passed as the url parameter "apiKey".
+const ApiKeyHeader = "X-Api-Key"
+
+type Permission string
+
+const (
+	PermissionAssign Permission = "assign"
+	PermissionRead   Permission = "read"
+	PermissionWrite  Permission = "write"
+	PermissionDelete Permission = "delete"
+	// PermissionAdmin allows the admin operations, and implies all other permissions
+	PermissionAdmin Permission = "admin"
+)
+
+var (
+	ErrNoApiKey      = errors.New("missing api key")
+	ErrUnknownApiKey = errors.New("unknown api key")
+)
+
+/*
+Credential is one named api key, loaded from the credentials file:
+
+	{
+	  "credentials": [
+	    {
+	      "name": "photo-app",
+	      "key": "a-long-random-string",
+	      "permissions": ["assign", "read", "write"],
+	      "collections": ["photos"],
+	      "pathPrefixes": ["/photos/"]
+	    }
+	  ]
+	}
+
+Empty collections or pathPrefixes are not restricted.
+The master and volume servers check the collection, the filer checks
+both its collection and the file path.
+*/
+type Credential struct {
+	Name         string       `json:"name"`
+	Key          string       `json:"key"`
+	Permissions  []Permission `json:"permissions"`
+	Collections  []string     `json:"collections,omitempty"`
+	PathPrefixes []string     `json:"pathPrefixes,omitempty"`
+}
+
+func (c *Credential) hasPermission(permission Permission) bool {
+	for _, p := range c.Permissions {
+		if p == permission || p == PermissionAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Credential) allowsCollection(collection string) bool {
+	if len(c.Collections) == 0 {
+		return true
+	}
+	for _, col := range c.Collections {
+		if col == collection {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Credential) allowsPath(path string) bool {
+	if path == "" || len(c.PathPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range c.PathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type credentialsConf struct {
+	Credentials []*Credential `json:"credentials"`
+}
+
+// CredentialStore holds the credentials of a file, and reloads them
+// when the file changes.
+type CredentialStore struct {
+	sync.RWMutex
+	path        string
+	modTime     time.Time
+	credentials []*Credential
+}
+
+func LoadCredentialStore(path string) (*CredentialStore, error) {
+	cs := &CredentialStore{path: path}
+	if err := cs.Reload(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// Reload reads the credentials file again.
+// The current credentials are kept if the file can not be parsed.
+func (cs *CredentialStore) Reload() error {
+	info, err := os.Stat(cs.path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(cs.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var conf credentialsConf
+	if err = json.NewDecoder(file).Decode(&conf); err != nil {
+		return fmt.Errorf("parse credentials file %s: %v", cs.path, err)
+	}
+	for i, c := range conf.Credentials {
+		if c.Key == "" {
+			return fmt.Errorf("credential %d %q in %s has no key", i, c.Name, cs.path)
+		}
+	}
+
+	cs.Lock()
+	cs.credentials = conf.Credentials
+	cs.modTime = info.ModTime()
+	cs.Unlock()
+	glog.V(0).Infof("loaded %d credentials from %s", len(conf.Credentials), cs.path)
+	return nil
+}
+
+// WatchForChanges reloads the credentials file whenever its modification
+// time changes, checking every interval.
+func (cs *CredentialStore) WatchForChanges(interval time.Duration) {
+	for range time.Tick(interval) {
+		info, err := os.Stat(cs.path)
+		if err != nil {
+			glog.V(0).Infof("check credentials file %s: %v", cs.path, err)
+			continue
+		}
+		cs.RLock()
+		changed := !info.ModTime().Equal(cs.modTime)
+		cs.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := cs.Reload(); err != nil {
+			glog.Warningf("keep previous credentials: %v", err)
+		}
+	}
+}
+
+func (cs *CredentialStore) lookup(apiKey string) *Credential {
+	cs.RLock()
+	defer cs.RUnlock()
+	for _, c := range cs.credentials {
+		if subtle.ConstantTimeCompare([]byte(c.Key), []byte(apiKey)) == 1 {
+			return c
+		}
+	}
+	return nil
+}
+
+// Authorize checks the api key belongs to a credential having the permission
+// on the collection and the filer path. An empty path is not checked.
+func (cs *CredentialStore) Authorize(apiKey string, permission Permission, collection string, path string) error {
+	if apiKey == "" {
+		return ErrNoApiKey
+	}
+	c := cs.lookup(apiKey)
+	if c == nil {
+		return ErrUnknownApiKey
+	}
+	if !c.hasPermission(permission) {
+		return fmt.Errorf("credential %s has no %s permission", c.Name, permission)
+	}
+	if !c.allowsCollection(collection) {
+		return fmt.Errorf("credential %s has no access to collection %q", c.Name, collection)
+	}
+	if !c.allowsPath(path) {
+		return fmt.Errorf("credential %s has no access to %s", c.Name, path)
+	}
+	return nil
+}
+
+func GetApiKey(r *http.Request) string {
+	if key := r.Header.Get(ApiKeyHeader); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("apiKey")
+}
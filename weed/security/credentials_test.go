@@ -0,0 +1,72 @@
+package security
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials.json")
+	if err := ioutil.WriteFile(path, []byte(`{"credentials": [
+		{"name": "photos", "key": "k1", "permissions": ["read", "write"], "collections": ["photos"], "pathPrefixes": ["/photos/"]},
+		{"name": "ops", "key": "k2", "permissions": ["admin"]}
+	]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cs, err := LoadCredentialStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cs.Authorize("k1", PermissionWrite, "photos", "/photos/a.jpg"); err != nil {
+		t.Errorf("write rejected: %v", err)
+	}
+	if err := cs.Authorize("k1", PermissionDelete, "photos", ""); err == nil {
+		t.Errorf("delete accepted without the permission")
+	}
+	if err := cs.Authorize("k1", PermissionRead, "docs", ""); err == nil {
+		t.Errorf("read accepted on another collection")
+	}
+	if err := cs.Authorize("k1", PermissionRead, "photos", "/docs/a.txt"); err == nil {
+		t.Errorf("read accepted on another path")
+	}
+	if err := cs.Authorize("k2", PermissionDelete, "docs", "/docs/a.txt"); err != nil {
+		t.Errorf("admin rejected: %v", err)
+	}
+	if err := cs.Authorize("k3", PermissionRead, "photos", ""); err != ErrUnknownApiKey {
+		t.Errorf("unknown key: %v", err)
+	}
+	if err := cs.Authorize("", PermissionRead, "photos", ""); err != ErrNoApiKey {
+		t.Errorf("missing key: %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"credentials": [{"name": "ops", "key": "k2", "permissions": ["read"]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.Authorize("k1", PermissionRead, "photos", ""); err != ErrUnknownApiKey {
+		t.Errorf("removed key still accepted: %v", err)
+	}
+	if err := cs.Authorize("k2", PermissionDelete, "docs", ""); err == nil {
+		t.Errorf("reloaded permissions not applied")
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"credentials": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.Reload(); err == nil {
+		t.Errorf("broken file accepted")
+	}
+	if err := cs.Authorize("k2", PermissionRead, "docs", ""); err != nil {
+		t.Errorf("previous credentials lost after a failed reload: %v", err)
+	}
+}
@@ -1,13 +1,18 @@
 package security
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 var (
@@ -16,9 +21,10 @@ var (
 
 /*
 Guard is to ensure data access security.
-There are 2 ways to check access:
+There are 3 ways to check access:
 1. white list. It's checking request ip address.
 2. JSON Web Token(JWT) generated from secretKey.
+3. api keys of named credentials, see Credential.
   The jwt can come from:
   1. url parameter jwt=...
   2. request header "Authorization"
@@ -36,6 +42,17 @@ Generating JWT:
 2. optionally set "exp", "nbf" fields, in Unix time,
    the number of seconds elapsed since January 1, 1970 UTC.
 
+Reads are only checked if a read secret is configured on the master.
+Read tokens are signed with the separate read secret, and are scoped
+to one file id, see ReadJwtClaims.
+
+The client address is the address of the connection. The address forwarded
+in the X-Forwarded-For header, or in the gRPC metadata, is only taken from
+the ForwardingPeers.
+
+If credentials are configured, hosts not in the white list need the api key
+of a credential with the permission for the operation, see CheckPermission.
+
 Referenced:
 https://github.com/pkieltyka/jwtauth/blob/master/jwtauth.go
 
@@ -43,10 +60,26 @@ https://github.com/pkieltyka/jwtauth/blob/master/jwtauth.go
 type Guard struct {
 	whiteList []string
 	SecretKey Secret
+	// ReadSecretKey signs the optional read tokens
+	ReadSecretKey Secret
+	// Credentials, if set, are required from hosts not in the white list.
+	// Set it before wrapping handlers with Permit.
+	Credentials *CredentialStore
+	// ForwardingPeers, if set, returns the host:port of the servers trusted to
+	// forward the address of their clients, i.e. the other masters.
+	ForwardingPeers func() []string
 
 	isActive bool
+
+	// the addresses of the ForwardingPeers, resolved at most every forwardingPeersTTL
+	peersLock     sync.Mutex
+	peersKey      string
+	peerAddrs     map[string]bool
+	peersResolved time.Time
 }
 
+const forwardingPeersTTL = time.Minute
+
 func NewGuard(whiteList []string, secretKey string) *Guard {
 	g := &Guard{whiteList: whiteList, SecretKey: Secret(secretKey)}
 	g.isActive = len(g.whiteList) != 0 || len(g.SecretKey) != 0
@@ -81,58 +114,223 @@ func (g *Guard) Secure(f func(w http.ResponseWriter, r *http.Request)) func(w ht
 	}
 }
 
-func GetActualRemoteHost(r *http.Request) (host string, err error) {
-	host = r.Header.Get("HTTP_X_FORWARDED_FOR")
-	if host == "" {
-		host = r.Header.Get("X-FORWARDED-FOR")
+// Permit wraps f to check the permission on the collection
+// given in the "collection" parameter.
+func (g *Guard) Permit(permission Permission, f func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	if !g.isActive && g.Credentials == nil {
+		//if no security needed, just skip all checkings
+		return f
 	}
-	if strings.Contains(host, ",") {
-		host = host[0:strings.Index(host, ",")]
+	return func(w http.ResponseWriter, r *http.Request) {
+		// multipart bodies are left for the handler to parse, which takes
+		// the collection from the url query too
+		collection := r.URL.Query().Get("collection")
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			collection = r.FormValue("collection")
+		}
+		if err := g.CheckPermission(r, permission, collection, ""); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f(w, r)
 	}
-	if host == "" {
-		host, _, err = net.SplitHostPort(r.RemoteAddr)
+}
+
+// CheckPermission lets white listed hosts pass. Other hosts need the api key
+// of a credential with the permission on the collection, and on the filer
+// path if not empty. Without credentials, only the white list is checked.
+func (g *Guard) CheckPermission(r *http.Request, permission Permission, collection string, path string) error {
+	if g.Credentials == nil {
+		return g.checkWhiteList(nil, r)
 	}
-	return
+	if len(g.whiteList) != 0 && g.checkWhiteList(nil, r) == nil {
+		return nil
+	}
+	if err := g.Credentials.Authorize(GetApiKey(r), permission, collection, path); err != nil {
+		glog.V(1).Infof("No %s permission from %s: %v", permission, r.RemoteAddr, err)
+		return err
+	}
+	return nil
 }
 
-func (g *Guard) checkWhiteList(w http.ResponseWriter, r *http.Request) error {
-	if len(g.whiteList) == 0 {
+// GrpcApiKeyMetadata and GrpcForwardedForMetadata are the gRPC metadata keys
+// of the api key, and of the client address if forwarded by another master.
+const (
+	GrpcApiKeyMetadata       = "x-api-key"
+	GrpcForwardedForMetadata = "x-forwarded-for"
+)
+
+// CheckGrpcPermission is like CheckPermission for a gRPC call, with the api key
+// in the call metadata.
+func (g *Guard) CheckGrpcPermission(ctx context.Context, permission Permission, collection string) error {
+	host := g.GrpcRemoteHost(ctx)
+	if g.Credentials == nil {
+		if len(g.whiteList) == 0 || g.isWhiteListed(host) {
+			return nil
+		}
+		glog.V(0).Infof("Not in whitelist: %s", host)
+		return fmt.Errorf("Not in whitelist: %s", host)
+	}
+	if len(g.whiteList) != 0 && g.isWhiteListed(host) {
 		return nil
 	}
+	apiKey := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(GrpcApiKeyMetadata); len(values) > 0 {
+			apiKey = values[0]
+		}
+	}
+	if err := g.Credentials.Authorize(apiKey, permission, collection, ""); err != nil {
+		glog.V(1).Infof("No %s permission from %s: %v", permission, host, err)
+		return err
+	}
+	return nil
+}
 
-	host, err := GetActualRemoteHost(r)
-	if strings.Contains(host, ":") {
-		host, _, err = net.SplitHostPort(host)
-		panic(err)
-	}
-	if err == nil {
-		for _, ip := range g.whiteList {
-
-			// If the whitelist entry contains a "/" it
-			// is a CIDR range, and we should check the
-			// remote host is within it
-			if strings.Contains(ip, "/") {
-				_, cidrnet, err := net.ParseCIDR(ip)
-				if err != nil {
-					panic(err)
-				}
-				remote := net.ParseIP(host)
-				if cidrnet.Contains(remote) {
-					return nil
-				}
+// GrpcRemoteHost returns the client host of a gRPC call. The address forwarded
+// by a master proxying the call is only taken from one of the ForwardingPeers.
+func (g *Guard) GrpcRemoteHost(ctx context.Context) string {
+	host := ""
+	if pr, ok := peer.FromContext(ctx); ok && pr.Addr != nil {
+		host = pr.Addr.String()
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(GrpcForwardedForMetadata); len(values) > 0 && values[0] != "" {
+			if g.isForwardingPeer(host) {
+				return values[0]
 			}
+			glog.V(1).Infof("Ignore the client address %s forwarded by %s", values[0], host)
+		}
+	}
+	return host
+}
 
-			//
-			// Otherwise we're looking for a literal match.
-			//
-			if ip == host {
-				return nil
-			}
+func (g *Guard) isForwardingPeer(host string) bool {
+	if g.ForwardingPeers == nil || host == "" {
+		return false
+	}
+	peers := g.ForwardingPeers()
+	key := strings.Join(peers, ",")
+	g.peersLock.Lock()
+	defer g.peersLock.Unlock()
+	if key != g.peersKey || time.Since(g.peersResolved) > forwardingPeersTTL {
+		g.peerAddrs = resolvePeers(peers)
+		g.peersKey, g.peersResolved = key, time.Now()
+	}
+	return g.peerAddrs[host]
+}
+
+// resolvePeers returns the addresses of the host:port peers.
+func resolvePeers(peers []string) map[string]bool {
+	addrs := make(map[string]bool)
+	for _, p := range peers {
+		peerHost, _, err := net.SplitHostPort(p)
+		if err != nil {
+			peerHost = p
 		}
+		addrs[peerHost] = true
+		if net.ParseIP(peerHost) != nil {
+			continue
+		}
+		resolved, err := net.LookupHost(peerHost)
+		if err != nil {
+			glog.V(1).Infof("Failed to resolve the master peer %s: %v", peerHost, err)
+			continue
+		}
+		for _, addr := range resolved {
+			addrs[addr] = true
+		}
+	}
+	return addrs
+}
+
+// CheckReadJwt lets white listed hosts read, and otherwise requires a read
+// token signed with the read secret, issued for the file id.
+func (g *Guard) CheckReadJwt(r *http.Request, readSecret Secret, fileId string) error {
+	if len(g.whiteList) != 0 && g.checkWhiteList(nil, r) == nil {
+		return nil
+	}
+	if err := VerifyReadJwt(readSecret, GetJwt(r), fileId); err != nil {
+		glog.V(1).Infof("No read permission for %s from %s", fileId, r.RemoteAddr)
+		return err
+	}
+	return nil
+}
+
+// RemoteHost returns the client host of a request. The address in the
+// X-Forwarded-For header is only taken from one of the ForwardingPeers.
+func (g *Guard) RemoteHost(r *http.Request) (string, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "", err
+	}
+	forwarded := r.Header.Get("HTTP_X_FORWARDED_FOR")
+	if forwarded == "" {
+		forwarded = r.Header.Get("X-FORWARDED-FOR")
+	}
+	if i := strings.Index(forwarded, ","); i >= 0 {
+		forwarded = forwarded[:i]
+	}
+	forwarded = strings.TrimSpace(forwarded)
+	if forwarded == "" {
+		return host, nil
+	}
+	if !g.isForwardingPeer(host) {
+		glog.V(1).Infof("Ignore the client address %s forwarded by %s", forwarded, host)
+		return host, nil
+	}
+	if h, _, err := net.SplitHostPort(forwarded); err == nil {
+		forwarded = h
+	}
+	return forwarded, nil
+}
+
+func (g *Guard) checkWhiteList(w http.ResponseWriter, r *http.Request) error {
+	if len(g.whiteList) == 0 {
+		return nil
+	}
+
+	host, err := g.RemoteHost(r)
+	if err != nil {
+		glog.V(0).Infof("Unknown remote address %s: %v", r.RemoteAddr, err)
+		return fmt.Errorf("Unknown remote address %s: %v", r.RemoteAddr, err)
+	}
+	if g.isWhiteListed(host) {
+		return nil
 	}
 
 	glog.V(0).Infof("Not in whitelist: %s", host)
-	return fmt.Errorf("Not in whitelis: %s", host)
+	return fmt.Errorf("Not in whitelist: %s", host)
+}
+
+func (g *Guard) isWhiteListed(host string) bool {
+	for _, ip := range g.whiteList {
+
+		// If the whitelist entry contains a "/" it
+		// is a CIDR range, and we should check the
+		// remote host is within it
+		if strings.Contains(ip, "/") {
+			_, cidrnet, err := net.ParseCIDR(ip)
+			if err != nil {
+				panic(err)
+			}
+			remote := net.ParseIP(host)
+			if cidrnet.Contains(remote) {
+				return true
+			}
+		}
+
+		//
+		// Otherwise we're looking for a literal match.
+		//
+		if ip == host {
+			return true
+		}
+	}
+	return false
 }
 
 func (g *Guard) checkJwt(w http.ResponseWriter, r *http.Request) error {
@@ -0,0 +1,98 @@
+package security
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestGrpcForwardedFor(t *testing.T) {
+	g := NewGuard([]string{"10.0.0.9"}, "")
+	g.ForwardingPeers = func() []string { return []string{"10.0.0.2:9333"} }
+
+	call := func(from string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(from), Port: 40000}})
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(GrpcForwardedForMetadata, "10.0.0.9"))
+	}
+
+	if host := g.GrpcRemoteHost(call("10.0.0.2")); host != "10.0.0.9" {
+		t.Errorf("address forwarded by a master peer: %s", host)
+	}
+	if err := g.CheckGrpcPermission(call("10.0.0.2"), PermissionWrite, ""); err != nil {
+		t.Errorf("white listed client forwarded by a master peer: %v", err)
+	}
+	if host := g.GrpcRemoteHost(call("10.0.0.5")); host != "10.0.0.5" {
+		t.Errorf("address forwarded by another host: %s", host)
+	}
+	if err := g.CheckGrpcPermission(call("10.0.0.5"), PermissionWrite, ""); err == nil {
+		t.Errorf("spoofed white listed address accepted")
+	}
+}
+
+func TestReadForwardedFor(t *testing.T) {
+	g := NewGuard([]string{"10.0.0.9", "::1"}, "")
+	g.ForwardingPeers = func() []string { return []string{"10.0.0.2:9333"} }
+	secret := Secret("read secret")
+
+	read := func(from string, forwardedFor string) error {
+		r := httptest.NewRequest("GET", "/3,01637037d6", nil)
+		r.RemoteAddr = from
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		return g.CheckReadJwt(r, secret, "3,01637037d6")
+	}
+
+	if err := read("10.0.0.2:40000", "10.0.0.9"); err != nil {
+		t.Errorf("white listed client forwarded by a master peer: %v", err)
+	}
+	if err := read("10.0.0.5:40000", "10.0.0.9"); err == nil {
+		t.Errorf("spoofed white listed address accepted")
+	}
+	if err := read("10.0.0.2:40000", "10.0.0.9:51000, 10.0.0.2"); err != nil {
+		t.Errorf("forwarded address with a port: %v", err)
+	}
+	if err := read("[::1]:40000", ""); err != nil {
+		t.Errorf("white listed ipv6 client: %v", err)
+	}
+	if err := read("[::2]:40000", ""); err == nil {
+		t.Errorf("ipv6 client not in the white list accepted")
+	}
+}
+
+func TestPermissionForwardedFor(t *testing.T) {
+	g := NewGuard([]string{"10.0.0.9"}, "")
+	g.ForwardingPeers = func() []string { return []string{"10.0.0.2:9333"} }
+	g.Credentials = &CredentialStore{credentials: []*Credential{
+		{Name: "writer", Key: "k1", Permissions: []Permission{PermissionWrite}},
+	}}
+
+	write := func(from string, forwardedFor string, apiKey string) error {
+		r := httptest.NewRequest("POST", "/submit", nil)
+		r.RemoteAddr = from
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		if apiKey != "" {
+			r.Header.Set(ApiKeyHeader, apiKey)
+		}
+		return g.CheckPermission(r, PermissionWrite, "", "")
+	}
+
+	if err := write("10.0.0.9:40000", "", ""); err != nil {
+		t.Errorf("white listed client: %v", err)
+	}
+	if err := write("10.0.0.2:40000", "10.0.0.9", ""); err != nil {
+		t.Errorf("white listed client forwarded by a master peer: %v", err)
+	}
+	if err := write("10.0.0.5:40000", "10.0.0.9", ""); err == nil {
+		t.Errorf("spoofed white listed address accepted without an api key")
+	}
+	if err := write("10.0.0.5:40000", "10.0.0.9", "k1"); err != nil {
+		t.Errorf("api key rejected: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package security
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -72,3 +73,56 @@ func DecodeJwt(secret Secret, tokenString EncodedJwt) (token *jwt.Token, err err
 		return secret, nil
 	})
 }
+
+// ReadScopeFileId is the scope of read tokens, which authorize reading
+// one file id, set as the subject.
+const ReadScopeFileId = "fid"
+
+type ReadJwtClaims struct {
+	jwt.StandardClaims
+	Scope string `json:"scope"`
+}
+
+func GenReadJwtForFileId(secret Secret, expiresAfterSec int, fileId string) EncodedJwt {
+	return genReadJwt(secret, &ReadJwtClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Second * time.Duration(expiresAfterSec)).Unix(),
+			Subject:   fileId,
+		},
+		Scope: ReadScopeFileId,
+	})
+}
+
+func genReadJwt(secret Secret, claims *ReadJwtClaims) EncodedJwt {
+	if secret == "" {
+		return ""
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	encoded, e := t.SignedString([]byte(secret))
+	if e != nil {
+		glog.V(0).Infof("Failed to sign read claims %+v: %v", claims, e)
+		return ""
+	}
+	return EncodedJwt(encoded)
+}
+
+// VerifyReadJwt checks the token is not expired, and authorizes reading the file id.
+func VerifyReadJwt(secret Secret, tokenString EncodedJwt, fileId string) error {
+	if tokenString == "" {
+		return ErrUnauthorized
+	}
+	claims := &ReadJwtClaims{}
+	token, err := jwt.ParseWithClaims(string(tokenString), claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return ErrUnauthorized
+	}
+	if claims.Scope != ReadScopeFileId || claims.Subject != fileId {
+		return ErrUnauthorized
+	}
+	return nil
+}
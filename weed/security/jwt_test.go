@@ -0,0 +1,29 @@
+package security
+
+import (
+	"testing"
+)
+
+func TestReadJwtScopes(t *testing.T) {
+	secret := Secret("read secret")
+
+	fileToken := GenReadJwtForFileId(secret, 60, "3,01637037d6")
+	if err := VerifyReadJwt(secret, fileToken, "3,01637037d6"); err != nil {
+		t.Errorf("file id token rejected: %v", err)
+	}
+	if err := VerifyReadJwt(secret, fileToken, "3,01637037d7"); err == nil {
+		t.Errorf("file id token accepted for another file id")
+	}
+
+	if err := VerifyReadJwt(Secret("another secret"), fileToken, "3,01637037d6"); err == nil {
+		t.Errorf("token accepted with a wrong secret")
+	}
+	if err := VerifyReadJwt(secret, "", "3,01637037d6"); err == nil {
+		t.Errorf("missing token accepted")
+	}
+
+	expiredToken := GenReadJwtForFileId(secret, -10, "3,01637037d6")
+	if err := VerifyReadJwt(secret, expiredToken, "3,01637037d6"); err == nil {
+		t.Errorf("expired token accepted")
+	}
+}
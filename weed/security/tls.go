@@ -0,0 +1,103 @@
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+/*
+TLS protects the traffic between masters, volume servers, filers and clients.
+
+Each server is configured with a certificate and its private key.
+If a CA certificate is also given, it is used both to verify the servers
+this process connects to, and to require and verify client certificates
+on the incoming inter-component connections, i.e., mutual TLS.
+Public read ports only use the server certificate.
+
+The certificates should contain the ip addresses or host names
+the servers are reached with, e.g., the -ip and -peers values.
+*/
+
+var clientTLSConfig *tls.Config
+
+// LoadServerTLSConfig loads the certificate used by a server to accept connections.
+// With a non-empty caFile, clients must present a certificate signed by that CA.
+func LoadServerTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load key pair %s %s: %v", certFile, keyFile, err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		// prefer http/1.1 for browsers, while grpc clients only offer h2
+		NextProtos: []string{"http/1.1", "h2"},
+		MinVersion: tls.VersionTLS12,
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// LoadClientTLSConfig loads the certificate presented when connecting to other servers.
+// With a non-empty caFile, only servers with certificates signed by that CA are trusted,
+// otherwise the system roots are used.
+func LoadClientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load key pair %s %s: %v", certFile, keyFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read ca certificate %s: %v", caFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// SetClientTLSConfig makes the grpc connections of this process use TLS.
+func SetClientTLSConfig(config *tls.Config) {
+	clientTLSConfig = config
+}
+
+func IsTLSEnabled() bool {
+	return clientTLSConfig != nil
+}
+
+// GrpcDialOption returns the transport option for dialing masters and filers.
+func GrpcDialOption() grpc.DialOption {
+	if clientTLSConfig == nil {
+		return grpc.WithInsecure()
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(clientTLSConfig))
+}
@@ -0,0 +1,105 @@
+package sequence
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// ReserveStep is how many file ids the file and raft sequencers reserve
+// ahead, to persist the reservation only once in a while.
+const ReserveStep = 10000
+
+// FileSequencer reserves ranges of file ids in a file, so file ids are not
+// reused after a restart, even before volume servers report their max file key.
+// The ids reserved but not used before a restart are skipped.
+type FileSequencer struct {
+	sync.Mutex
+	path     string
+	counter  uint64
+	reserved uint64 // ids below are reserved in the file
+}
+
+func NewFileSequencer(dir string) (*FileSequencer, error) {
+	s := &FileSequencer{path: filepath.Join(dir, "max_file_id"), counter: 1}
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.reserved, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", s.path, err)
+	}
+	if s.counter < s.reserved {
+		s.counter = s.reserved
+	}
+	glog.V(0).Infof("file id sequence starts from %d", s.counter)
+	return s, nil
+}
+
+// NextFileId returns 0 ids if the reservation can not be saved.
+func (s *FileSequencer) NextFileId(count uint64) (uint64, uint64) {
+	s.Lock()
+	defer s.Unlock()
+	if s.counter+count > s.reserved {
+		reserved := s.counter + count + ReserveStep
+		if err := s.save(reserved); err != nil {
+			glog.Errorf("reserve file ids up to %d: %v", reserved, err)
+			return 0, 0
+		}
+		s.reserved = reserved
+	}
+	ret := s.counter
+	s.counter += count
+	return ret, count
+}
+
+// save writes the value to a temporary file and renames it over the
+// sequence file, so a crash leaves either the old or the new value.
+func (s *FileSequencer) save(reserved uint64) error {
+	tmpPath := s.path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(strconv.FormatUint(reserved, 10)); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(s.path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func (s *FileSequencer) SetMax(seenValue uint64) {
+	s.Lock()
+	defer s.Unlock()
+	if s.counter <= seenValue {
+		s.counter = seenValue + 1
+	}
+}
+
+func (s *FileSequencer) Peek() uint64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.counter
+}
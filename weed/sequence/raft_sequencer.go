@@ -0,0 +1,94 @@
+package sequence
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// ReserveFunc reserves count file ids, not below min, through the raft log,
+// and returns the first one.
+type ReserveFunc func(min, count uint64) (start uint64, err error)
+
+// RaftSequencer hands out file ids from ranges reserved through the raft log.
+// All masters apply the reservations, so a new leader never hands out ids
+// reserved by the previous one.
+type RaftSequencer struct {
+	sync.Mutex
+	counter uint64
+	end     uint64 // end of the range reserved by this master
+	reserve ReserveFunc
+
+	reservedLock sync.Mutex
+	reserved     uint64 // ids below are reserved, replicated on all masters
+}
+
+func NewRaftSequencer() *RaftSequencer {
+	return &RaftSequencer{counter: 1}
+}
+
+// SetReserveFunc is called once the raft server is started.
+func (s *RaftSequencer) SetReserveFunc(reserve ReserveFunc) {
+	s.Lock()
+	defer s.Unlock()
+	s.reserve = reserve
+}
+
+// ApplyReservation applies a reservation from the raft log.
+func (s *RaftSequencer) ApplyReservation(min, count uint64) (start uint64) {
+	s.reservedLock.Lock()
+	defer s.reservedLock.Unlock()
+	start = s.reserved
+	if start < min {
+		start = min
+	}
+	s.reserved = start + count
+	return start
+}
+
+// Reserved returns the end of the reserved ids, saved in raft snapshots.
+func (s *RaftSequencer) Reserved() uint64 {
+	s.reservedLock.Lock()
+	defer s.reservedLock.Unlock()
+	return s.reserved
+}
+
+// NextFileId returns 0 ids if the leader can not reserve more ids.
+func (s *RaftSequencer) NextFileId(count uint64) (uint64, uint64) {
+	s.Lock()
+	defer s.Unlock()
+	if s.counter+count > s.end {
+		start, err := s.reserveRange(count + ReserveStep)
+		if err != nil {
+			glog.Errorf("reserve %d file ids: %v", count+ReserveStep, err)
+			return 0, 0
+		}
+		s.counter, s.end = start, start+count+ReserveStep
+	}
+	ret := s.counter
+	s.counter += count
+	return ret, count
+}
+
+func (s *RaftSequencer) reserveRange(count uint64) (uint64, error) {
+	if s.reserve == nil {
+		return 0, errors.New("raft server is not started")
+	}
+	return s.reserve(s.counter, count)
+}
+
+// SetMax makes the next reservation start after seenValue.
+func (s *RaftSequencer) SetMax(seenValue uint64) {
+	s.Lock()
+	defer s.Unlock()
+	if s.counter <= seenValue {
+		s.counter = seenValue + 1
+	}
+}
+
+func (s *RaftSequencer) Peek() uint64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.counter
+}
@@ -0,0 +1,68 @@
+package sequence
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFileSequencerRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sequence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	seq, err := NewFileSequencer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := seq.NextFileId(1)
+	last, count := seq.NextFileId(100)
+	if count != 100 || last <= first {
+		t.Fatalf("unexpected ids %d, %d of %d", first, last, count)
+	}
+
+	restarted, err := NewFileSequencer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next, _ := restarted.NextFileId(1); next < last+count {
+		t.Errorf("id %d after restart reuses ids below %d", next, last+count)
+	}
+}
+
+func TestRaftSequencerRanges(t *testing.T) {
+	// two masters sharing the same replicated reservations
+	var log RaftSequencer
+	reserve := func(min, count uint64) (uint64, error) {
+		return log.ApplyReservation(min, count), nil
+	}
+	a, b := NewRaftSequencer(), NewRaftSequencer()
+	a.SetReserveFunc(reserve)
+	b.SetReserveFunc(reserve)
+
+	idA, _ := a.NextFileId(10)
+	idB, _ := b.NextFileId(10)
+	if idB < idA+10+ReserveStep {
+		t.Errorf("new leader id %d is in the range of the previous leader from %d", idB, idA)
+	}
+}
+
+func TestSnowflakeSequencerIsIncreasing(t *testing.T) {
+	seq, err := NewSnowflakeSequencer(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, lastCount := seq.NextFileId(1)
+	for i := 0; i < 10000; i++ {
+		id, count := seq.NextFileId(uint64(i%7 + 1))
+		if id < last+lastCount {
+			t.Fatalf("id %d is not after %d", id, last+lastCount-1)
+		}
+		last, lastCount = id, count
+	}
+	if _, err := NewSnowflakeSequencer(1024); err == nil {
+		t.Errorf("node id 1024 accepted")
+	}
+}
@@ -0,0 +1,83 @@
+package sequence
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// snowflake file ids have 41 bits of milliseconds since the epoch,
+// 10 bits of node id and 12 bits of sequence within the millisecond.
+const (
+	snowflakeEpoch        = 1514764800000 // 2018-01-01 UTC, in milliseconds
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNodeId    = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+// SnowflakeSequencer generates file ids from the time and a node id unique
+// among the masters, so it needs neither storage nor coordination.
+// The ids are far above the ones of the other sequencers.
+type SnowflakeSequencer struct {
+	sync.Mutex
+	nodeId     uint64
+	lastMillis int64
+	sequence   uint64
+}
+
+// NewSnowflakeSequencer uses the node id, which must be unique among the masters.
+func NewSnowflakeSequencer(nodeId int) (*SnowflakeSequencer, error) {
+	if nodeId < 0 || nodeId > snowflakeMaxNodeId {
+		return nil, fmt.Errorf("snowflake node id %d is not between 0 and %d", nodeId, snowflakeMaxNodeId)
+	}
+	return &SnowflakeSequencer{nodeId: uint64(nodeId)}, nil
+}
+
+// NextFileId returns at most 4096 ids at once, all in the same millisecond.
+func (s *SnowflakeSequencer) NextFileId(count uint64) (uint64, uint64) {
+	s.Lock()
+	defer s.Unlock()
+	if count > snowflakeMaxSequence+1 {
+		count = snowflakeMaxSequence + 1
+	}
+	now := currentMillis()
+	if now < s.lastMillis {
+		// the clock moved back, continue with the last millisecond
+		now = s.lastMillis
+	}
+	if now == s.lastMillis && s.sequence+count > snowflakeMaxSequence+1 {
+		for now <= s.lastMillis {
+			time.Sleep(100 * time.Microsecond)
+			now = currentMillis()
+		}
+	}
+	if now != s.lastMillis {
+		s.lastMillis, s.sequence = now, 0
+	}
+	ret := s.id(now, s.sequence)
+	s.sequence += count
+	return ret, count
+}
+
+func (s *SnowflakeSequencer) id(millis int64, sequence uint64) uint64 {
+	return uint64(millis-snowflakeEpoch)<<(snowflakeNodeBits+snowflakeSequenceBits) |
+		s.nodeId<<snowflakeSequenceBits | sequence
+}
+
+// SetMax is not needed, the ids only depend on the time.
+func (s *SnowflakeSequencer) SetMax(seenValue uint64) {
+}
+
+func (s *SnowflakeSequencer) Peek() uint64 {
+	s.Lock()
+	defer s.Unlock()
+	if now := currentMillis(); now > s.lastMillis {
+		return s.id(now, 0)
+	}
+	return s.id(s.lastMillis, s.sequence)
+}
+
+func currentMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
@@ -21,6 +21,7 @@ import (
 
 var serverStats *stats.ServerStats
 var startTime = time.Now()
+var metricsHandler = stats.MetricsHandler()
 
 func init() {
 	serverStats = stats.NewServerStats()
@@ -74,7 +75,7 @@ func writeJsonError(w http.ResponseWriter, r *http.Request, httpStatus int, err
 }
 
 func debug(params ...interface{}) {
-	glog.V(4).Infoln(params)
+	glog.V(4).Infoln(params...)
 }
 
 func submitForClientHandler(w http.ResponseWriter, r *http.Request, masterUrl string) {
@@ -93,12 +94,14 @@ func submitForClientHandler(w http.ResponseWriter, r *http.Request, masterUrl st
 	}
 
 	debug("assigning file id for", fname)
-	r.ParseForm()
+	// the parameters are only taken from the url, the collection being
+	// the one the write permission is checked on
+	query := r.URL.Query()
 	ar := &operation.VolumeAssignRequest{
 		Count:       1,
-		Replication: r.FormValue("replication"),
-		Collection:  r.FormValue("collection"),
-		Ttl:         r.FormValue("ttl"),
+		Replication: query.Get("replication"),
+		Collection:  query.Get("collection"),
+		Ttl:         query.Get("ttl"),
 	}
 	assignResult, ae := operation.Assign(masterUrl, ar)
 	if ae != nil {
@@ -182,6 +185,10 @@ func statsCounterHandler(w http.ResponseWriter, r *http.Request) {
 	writeJsonQuiet(w, r, http.StatusOK, m)
 }
 
+func statsMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	metricsHandler.ServeHTTP(w, r)
+}
+
 func statsMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	m := make(map[string]interface{})
 	m["Version"] = util.VERSION
@@ -62,7 +62,7 @@ func (fs *FilerServer) GetFileAttributes(ctx context.Context, req *filer_pb.GetF
 
 	attributes := &filer_pb.FuseAttributes{}
 
-	server, err := operation.LookupFileId(fs.getMasterNode(), req.FileId)
+	server, err := fs.lookupFileIdForRead(ctx, req.FileId)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +82,7 @@ func (fs *FilerServer) GetFileAttributes(ctx context.Context, req *filer_pb.GetF
 
 func (fs *FilerServer) GetFileContent(ctx context.Context, req *filer_pb.GetFileContentRequest) (*filer_pb.GetFileContentResponse, error) {
 
-	server, err := operation.LookupFileId(fs.getMasterNode(), req.FileId)
+	server, err := fs.lookupFileIdForRead(ctx, req.FileId)
 	if err != nil {
 		return nil, err
 	}
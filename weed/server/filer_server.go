@@ -1,11 +1,15 @@
 package weed_server
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,10 +17,13 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/filer/cassandra_store"
 	"github.com/chrislusf/seaweedfs/weed/filer/embedded_filer"
 	"github.com/chrislusf/seaweedfs/weed/filer/flat_namespace"
+	"github.com/chrislusf/seaweedfs/weed/filer/leveldb_store"
 	"github.com/chrislusf/seaweedfs/weed/filer/mysql_store"
 	"github.com/chrislusf/seaweedfs/weed/filer/postgres_store"
 	"github.com/chrislusf/seaweedfs/weed/filer/redis_store"
+	"github.com/chrislusf/seaweedfs/weed/filer/sqlite_store"
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 	"github.com/chrislusf/seaweedfs/weed/util"
@@ -26,6 +33,7 @@ type filerConf struct {
 	MysqlConf []mysql_store.MySqlConf `json:"mysql"`
 	mysql_store.ShardingConf
 	PostgresConf *postgres_store.PostgresConf `json:"postgres"`
+	SqliteConf   *sqlite_store.SqliteConf     `json:"sqlite"`
 }
 
 func parseConfFile(confPath string) (*filerConf, error) {
@@ -43,6 +51,53 @@ func parseConfFile(confPath string) (*filerConf, error) {
 	return &setting, nil
 }
 
+// NewFilerStore opens the store in the conf file if any, or else the cassandra,
+// redis or leveldb store in the options, defaulting to the embedded filer in dir.
+func NewFilerStore(master string, dir string, confFile string,
+	cassandra_server string, cassandra_keyspace string,
+	redis_server string, redis_password string, redis_database int,
+	leveldbStore bool) (storeName string, store filer.Filer, err error) {
+	setting := new(filerConf)
+	if confFile != "" {
+		if setting, err = parseConfFile(confFile); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if setting.MysqlConf != nil && len(setting.MysqlConf) != 0 {
+		mysql_store, err := mysql_store.NewMysqlStore(setting.MysqlConf, setting.IsSharding, setting.ShardCount)
+		if err != nil {
+			return "", nil, fmt.Errorf("Can not open the mysql store: %v", err)
+		}
+		return "mysql", flat_namespace.NewFlatNamespaceFiler(master, mysql_store), nil
+	} else if setting.PostgresConf != nil {
+		return "postgres", postgres_store.NewPostgresStore(master, *setting.PostgresConf), nil
+	} else if setting.SqliteConf != nil {
+		if store, err = sqlite_store.NewSqliteStore(master, *setting.SqliteConf); err != nil {
+			return "", nil, err
+		}
+		return "sqlite", store, nil
+	} else if cassandra_server != "" {
+		cassandra_store, err := cassandra_store.NewCassandraStore(cassandra_keyspace, cassandra_server)
+		if err != nil {
+			return "", nil, fmt.Errorf("Can not connect to cassandra server %s with keyspace %s: %v", cassandra_server, cassandra_keyspace, err)
+		}
+		return "cassandra", flat_namespace.NewFlatNamespaceFiler(master, cassandra_store), nil
+	} else if redis_server != "" {
+		redis_store := redis_store.NewRedisStore(redis_server, redis_password, redis_database)
+		return "redis", flat_namespace.NewFlatNamespaceFiler(master, redis_store), nil
+	} else if leveldbStore {
+		if store, err = leveldb_store.NewLevelDbStore(master, filepath.Join(dir, "leveldb")); err != nil {
+			return "", nil, err
+		}
+		return "leveldb", store, nil
+	}
+	if store, err = embedded_filer.NewFilerEmbedded(master, dir); err != nil {
+		return "", nil, err
+	}
+	return "embedded", store, nil
+}
+
 type FilerServer struct {
 	port               string
 	master             string
@@ -52,6 +107,9 @@ type FilerServer struct {
 	redirectOnRead     bool
 	disableDirListing  bool
 	secret             security.Secret
+	readSecret         security.Secret
+	readExpireSeconds  int
+	guard              *security.Guard
 	filer              filer.Filer
 	maxMB              int
 	masterNodes        *storage.MasterNodes
@@ -63,8 +121,11 @@ func NewFilerServer(defaultMux, readonlyMux *http.ServeMux, ip string, port int,
 	confFile string,
 	maxMB int,
 	secret string,
+	readSecret string, readExpireSeconds int,
+	credentials *security.CredentialStore,
 	cassandra_server string, cassandra_keyspace string,
-	redis_server string, redis_password string, redis_database int, syncFile string,
+	redis_server string, redis_password string, redis_database int,
+	leveldbStore bool, syncFile string,
 ) (fs *FilerServer, err error) {
 	fs = &FilerServer{
 		master:             master,
@@ -75,47 +136,30 @@ func NewFilerServer(defaultMux, readonlyMux *http.ServeMux, ip string, port int,
 		maxMB:              maxMB,
 		port:               ip + ":" + strconv.Itoa(port),
 		syncFile:           syncFile,
+		readSecret:         security.Secret(readSecret),
+		readExpireSeconds:  readExpireSeconds,
 	}
+	fs.guard = security.NewGuard(nil, "")
+	fs.guard.Credentials = credentials
 
-	var setting *filerConf
-	if confFile != "" {
-		setting, err = parseConfFile(confFile)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		setting = new(filerConf)
-	}
-
-	if setting.MysqlConf != nil && len(setting.MysqlConf) != 0 {
-		mysql_store := mysql_store.NewMysqlStore(setting.MysqlConf, setting.IsSharding, setting.ShardCount)
-		fs.filer = flat_namespace.NewFlatNamespaceFiler(master, mysql_store)
-	} else if setting.PostgresConf != nil {
-		fs.filer = postgres_store.NewPostgresStore(master, *setting.PostgresConf)
-	} else if cassandra_server != "" {
-		cassandra_store, err := cassandra_store.NewCassandraStore(cassandra_keyspace, cassandra_server)
-		if err != nil {
-			glog.Fatalf("Can not connect to cassandra server %s with keyspace %s: %v", cassandra_server, cassandra_keyspace, err)
-		}
-		fs.filer = flat_namespace.NewFlatNamespaceFiler(master, cassandra_store)
-	} else if redis_server != "" {
-		redis_store := redis_store.NewRedisStore(redis_server, redis_password, redis_database)
-		fs.filer = flat_namespace.NewFlatNamespaceFiler(master, redis_store)
-	} else {
-		if fs.filer, err = embedded_filer.NewFilerEmbedded(master, dir); err != nil {
-			glog.Fatalf("Can not start filer in dir %s : %v", dir, err)
-			return
-		}
-
-		defaultMux.HandleFunc("/admin/mv", fs.moveHandler)
+	storeName, store, err := NewFilerStore(master, dir, confFile,
+		cassandra_server, cassandra_keyspace,
+		redis_server, redis_password, redis_database,
+		leveldbStore)
+	if err != nil {
+		return nil, err
 	}
+	fs.filer = filer.NewFilerWithMetrics(storeName, store)
+	defaultMux.HandleFunc("/admin/mv", fs.guard.Permit(security.PermissionAdmin, fs.moveHandler))
 
-	defaultMux.HandleFunc("/admin/register", fs.registerHandler)
+	defaultMux.HandleFunc("/admin/register", fs.guard.Permit(security.PermissionAdmin, fs.registerHandler))
 	defaultMux.HandleFunc("/", fs.filerHandler)
 	if defaultMux != readonlyMux {
 		readonlyMux.HandleFunc("/", fs.readonlyFilerHandler)
 	}
 
+	go operation.KeepConnectedToMaster(master, "filer")
+
 	go func() {
 		connected := true
 
@@ -154,6 +198,31 @@ func (fs *FilerServer) jwt(fileId string) security.EncodedJwt {
 	return security.GenJwt(fs.secret, fileId)
 }
 
+// lookupFileIdForRead returns the volume server url to read the file id from,
+// with a read token signed by the filer, or else issued by the master, if required.
+func (fs *FilerServer) lookupFileIdForRead(ctx context.Context, fileId string) (string, error) {
+	if fs.readSecret == "" {
+		return operation.LookupFileIdForRead(ctx, fs.getMasterNode(), fileId)
+	}
+	fileUrl, err := operation.LookupFileIdContext(ctx, fs.getMasterNode(), fileId)
+	if err != nil {
+		return "", err
+	}
+	return fs.withReadJwt(fileUrl, fileId), nil
+}
+
+// withReadJwt adds a read token for the file id to the volume server url, if read tokens are enabled.
+func (fs *FilerServer) withReadJwt(fileUrl string, fileId string) string {
+	if fs.readSecret == "" {
+		return fileUrl
+	}
+	jwt := security.GenReadJwtForFileId(fs.readSecret, fs.readExpireSeconds, fileId)
+	if strings.Contains(fileUrl, "?") {
+		return fileUrl + "&jwt=" + string(jwt)
+	}
+	return fileUrl + "?jwt=" + string(jwt)
+}
+
 func (fs *FilerServer) getMasterNode() string {
 	fs.mnLock.RLock()
 	defer fs.mnLock.RUnlock()
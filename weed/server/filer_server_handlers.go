@@ -2,9 +2,24 @@ package weed_server
 
 import (
 	"net/http"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 )
 
 func (fs *FilerServer) filerHandler(w http.ResponseWriter, r *http.Request) {
+	requestType := stats.RequestType(r.Method)
+	stats.FilerRequestCounter.WithLabelValues(requestType).Inc()
+	start := time.Now()
+	defer func() { stats.FilerRequestHistogram.WithLabelValues(requestType).Observe(time.Since(start).Seconds()) }()
+	r, span := tracing.StartServerSpan(r, "filer."+requestType)
+	defer span.End()
+	if err := fs.checkPermission(r); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
 	switch r.Method {
 	case "GET":
 		fs.GetOrHeadHandler(w, r, true)
@@ -20,6 +35,18 @@ func (fs *FilerServer) filerHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (fs *FilerServer) readonlyFilerHandler(w http.ResponseWriter, r *http.Request) {
+	requestType := stats.RequestType(r.Method)
+	stats.FilerRequestCounter.WithLabelValues(requestType).Inc()
+	start := time.Now()
+	defer func() { stats.FilerRequestHistogram.WithLabelValues(requestType).Observe(time.Since(start).Seconds()) }()
+	r, span := tracing.StartServerSpan(r, "filer."+requestType)
+	defer span.End()
+	if r.Method == "GET" || r.Method == "HEAD" {
+		if err := fs.checkPermission(r); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
 	switch r.Method {
 	case "GET":
 		fs.GetOrHeadHandler(w, r, true)
@@ -27,3 +54,24 @@ func (fs *FilerServer) readonlyFilerHandler(w http.ResponseWriter, r *http.Reque
 		fs.GetOrHeadHandler(w, r, false)
 	}
 }
+
+// checkPermission checks the credentials for the method on the file path,
+// in the collection the request reads or writes.
+func (fs *FilerServer) checkPermission(r *http.Request) error {
+	if fs.guard.Credentials == nil {
+		return nil
+	}
+	permission := security.PermissionRead
+	collection := r.URL.Query().Get("collection")
+	if collection == "" {
+		collection = fs.collection
+	}
+	switch r.Method {
+	case "DELETE":
+		permission = security.PermissionDelete
+	case "PUT", "POST":
+		permission = security.PermissionWrite
+		collection = fs.writeCollection(r)
+	}
+	return fs.guard.CheckPermission(r, permission, collection, r.URL.Path)
+}
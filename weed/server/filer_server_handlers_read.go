@@ -10,8 +10,8 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/filer"
 	"github.com/chrislusf/seaweedfs/weed/glog"
-	"github.com/chrislusf/seaweedfs/weed/operation"
 	ui "github.com/chrislusf/seaweedfs/weed/server/filer_ui"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 	"github.com/syndtr/goleveldb/leveldb"
 )
@@ -121,46 +121,21 @@ func (fs *FilerServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request,
 		}
 	}
 
-	reqUrl := r.URL.Path
-	var reqQuery string
-	if r.FormValue("w") != "" {
-		reqQuery += "&w=" + r.FormValue("w")
-	}
-	if r.FormValue("h") != "" {
-		reqQuery += "&h=" + r.FormValue("h")
-	}
-	if r.FormValue("r") != "" {
-		reqQuery += "&r=" + r.FormValue("r")
-	}
-	if r.FormValue("w") != "" && r.FormValue("h") != "" && r.FormValue("f") != "" {
-		reqQuery += "&f=" + r.FormValue("f")
-	}
-	if reqQuery = strings.TrimLeft(reqQuery, "&"); reqQuery != "" {
-		reqUrl += "?" + reqQuery
-	}
-	fileId, err = fs.filer.FindFile(reqUrl)
 	if err == filer.ErrNotFound {
-		glog.V(0).Infoln(reqUrl, "not exist")
-		r.Header.Add("exist", "0")
-		r.Header.Add("path", r.URL.Path)
-		fileId, err = fs.filer.FindFile(r.URL.Path)
-		if err == filer.ErrNotFound {
+		// the file may have been synced
+		if fileId, err = fs.filer.FindFile(r.URL.Path); err == filer.ErrNotFound {
 			glog.V(0).Infoln(r.URL.Path, "not exist")
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
-	} else {
-		glog.V(0).Infoln(reqUrl, "exist")
-		r.Header.Add("exist", "1")
 	}
 
-	urlLocation, err := operation.LookupFileId(fs.getMasterNode(), fileId)
+	urlString, err := fs.lookupFileIdForRead(r.Context(), fileId)
 	if err != nil {
-		glog.V(1).Infoln("operation LookupFileId %s failed, err is %s", fileId, err.Error())
+		glog.V(1).Infof("operation LookupFileId %s failed, err is %s", fileId, err.Error())
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-	urlString := urlLocation
 	if fs.redirectOnRead {
 		http.Redirect(w, r, urlString, http.StatusFound)
 		return
@@ -184,6 +159,7 @@ func (fs *FilerServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request,
 		Host:          r.Host,
 		ContentLength: r.ContentLength,
 	}
+	tracing.Inject(r.Context(), request.Header)
 	glog.V(3).Infoln("retrieving from", u)
 	resp, do_err := util.Do(request)
 	if do_err != nil {
@@ -21,6 +21,7 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/operation"
 	"github.com/chrislusf/seaweedfs/weed/storage"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
@@ -77,9 +78,9 @@ func (fs *FilerServer) queryFileInfoByPath(w http.ResponseWriter, r *http.Reques
 		glog.V(0).Infoln("failing to find path in filer store", path, err.Error())
 		writeJsonError(w, r, http.StatusInternalServerError, err)
 	} else if fileId != "" && err == nil {
-		urlLocation, err = operation.LookupFileId(fs.getMasterNode(), fileId)
+		urlLocation, err = operation.LookupFileIdContext(r.Context(), fs.getMasterNode(), fileId)
 		if err != nil {
-			glog.V(1).Infoln("operation LookupFileId %s failed, err is %s", fileId, err.Error())
+			glog.V(1).Infof("operation LookupFileId %s failed, err is %s", fileId, err.Error())
 			w.WriteHeader(http.StatusNotFound)
 		}
 	} else if fileId == "" && err == filer.ErrNotFound {
@@ -95,7 +96,7 @@ func (fs *FilerServer) assignNewFileInfo(w http.ResponseWriter, r *http.Request,
 		Collection:  collection,
 		Ttl:         r.URL.Query().Get("ttl"),
 	}
-	assignResult, ae := operation.Assign(fs.getMasterNode(), ar)
+	assignResult, ae := operation.AssignContext(r.Context(), fs.getMasterNode(), ar)
 	if ae != nil {
 		glog.V(0).Infoln("failing to assign a file id", ae.Error())
 		writeJsonError(w, r, http.StatusInternalServerError, ae)
@@ -193,7 +194,7 @@ func (fs *FilerServer) monolithicUploadAnalyzer(w http.ResponseWriter, r *http.R
 	*/
 	lastPos := strings.LastIndex(r.URL.Path, "/")
 	if lastPos == -1 || lastPos == 0 || lastPos == len(r.URL.Path)-1 {
-		glog.V(0).Infoln("URL Path [%s] is invalid, could not retrieve file name", r.URL.Path)
+		glog.V(0).Infof("URL Path [%s] is invalid, could not retrieve file name", r.URL.Path)
 		err = fmt.Errorf("URL Path is invalid")
 		writeJsonError(w, r, http.StatusInternalServerError, err)
 		return
@@ -208,8 +209,6 @@ func (fs *FilerServer) monolithicUploadAnalyzer(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	secondPos := strings.Index(r.URL.Path[1:], "/") + 1
-	collection = r.URL.Path[1:secondPos]
 	path := r.URL.Path
 
 	if fileId, urlLocation, err = fs.queryFileInfoByPath(w, r, path); err == nil && fileId == "" {
@@ -218,6 +217,21 @@ func (fs *FilerServer) monolithicUploadAnalyzer(w http.ResponseWriter, r *http.R
 	return
 }
 
+// writeCollection returns the collection an upload goes to: the one named by
+// the first path segment, the S3 bucket, for a non multipart upload, or else
+// the "collection" parameter, defaulting to the filer's collection.
+func (fs *FilerServer) writeCollection(r *http.Request) string {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data; boundary=") {
+		if secondPos := strings.Index(r.URL.Path[1:], "/") + 1; secondPos > 1 {
+			return r.URL.Path[1:secondPos]
+		}
+	}
+	if collection := r.URL.Query().Get("collection"); collection != "" {
+		return collection
+	}
+	return fs.collection
+}
+
 func (fs *FilerServer) PostHandler(w http.ResponseWriter, r *http.Request) {
 
 	query := r.URL.Query()
@@ -225,10 +239,7 @@ func (fs *FilerServer) PostHandler(w http.ResponseWriter, r *http.Request) {
 	if replication == "" {
 		replication = fs.defaultReplication
 	}
-	collection := query.Get("collection")
-	if collection == "" {
-		collection = fs.collection
-	}
+	collection := fs.writeCollection(r)
 
 	if autoChunked := fs.autoChunk(w, r, replication, collection); autoChunked {
 		return
@@ -272,6 +283,7 @@ func (fs *FilerServer) PostHandler(w http.ResponseWriter, r *http.Request) {
 		Host:          r.Host,
 		ContentLength: r.ContentLength,
 	}
+	tracing.Inject(r.Context(), request.Header)
 	resp, do_err := util.Do(request)
 	if do_err != nil {
 		glog.V(0).Infoln("failing to connect to volume server", r.RequestURI, do_err.Error())
@@ -521,7 +533,7 @@ func (fs *FilerServer) doUpload(urlLocation string, w http.ResponseWriter, r *ht
 	err = nil
 
 	ioReader := ioutil.NopCloser(bytes.NewBuffer(chunkBuf))
-	uploadResult, uploadError := operation.Upload(urlLocation, fileName, ioReader, false, contentType, nil, fs.jwt(fileId))
+	uploadResult, uploadError := operation.UploadContext(r.Context(), urlLocation, fileName, ioReader, false, contentType, nil, fs.jwt(fileId))
 	if uploadResult != nil {
 		glog.V(0).Infoln("Chunk upload result. Name:", uploadResult.Name, "Fid:", fileId, "Size:", uploadResult.Size)
 	}
@@ -1,22 +1,26 @@
 package weed_server
 
 import (
+	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 	"github.com/chrislusf/seaweedfs/weed/topology"
 	"google.golang.org/grpc/peer"
 )
 
-func (ms MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServer) error {
+func (ms *MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServer) error {
 	var dn *topology.DataNode
 	t := ms.Topo
 	for {
 		heartbeat, err := stream.Recv()
 		if err == nil {
+			stats.MasterReceivedHeartbeatCounter.WithLabelValues("total").Inc()
 			if dn == nil {
 				t.Sequence.SetMax(heartbeat.MaxFileKey)
 				if heartbeat.Ip == "" {
@@ -35,8 +39,10 @@ func (ms MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServe
 					int(heartbeat.MaxVolumeCount))
 				glog.V(0).Infof("added volume server %v:%d", heartbeat.GetIp(), heartbeat.GetPort())
 				if err := stream.Send(&master_pb.HeartbeatResponse{
-					VolumeSizeLimit: uint64(ms.volumeSizeLimitMB) * 1024 * 1024,
-					SecretKey:       string(ms.guard.SecretKey),
+					VolumeSizeLimit:    uint64(ms.volumeSizeLimitMB) * 1024 * 1024,
+					SecretKey:          string(ms.guard.SecretKey),
+					ReadSecretKey:      string(ms.guard.ReadSecretKey),
+					PrivateCollections: ms.privateCollections,
 				}); err != nil {
 					return err
 				}
@@ -50,18 +56,48 @@ func (ms MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServe
 					glog.V(0).Infof("Fail to convert joined volume information: %v", err)
 				}
 			}
-			deletedVolumes := dn.UpdateVolumes(volumeInfos)
+			var newVolumes, deletedVolumes []storage.VolumeInfo
+			message := &master_pb.VolumeLocation{Url: dn.Url(), PublicUrl: dn.PublicUrl}
+			if heartbeat.IsDelta {
+				var deletedIds []storage.VolumeId
+				for _, id := range heartbeat.DeletedVolumeIds {
+					deletedIds = append(deletedIds, storage.VolumeId(id))
+				}
+				newVolumes, deletedVolumes = dn.DeltaUpdateVolumes(volumeInfos, deletedIds)
+				// including volumes already removed by the master, e.g. expired ttl volumes
+				message.DeletedVids = heartbeat.DeletedVolumeIds
+			} else {
+				newVolumes, deletedVolumes = dn.UpdateVolumes(volumeInfos)
+				for _, v := range deletedVolumes {
+					message.DeletedVids = append(message.DeletedVids, uint32(v.Id))
+				}
+			}
+			for _, v := range newVolumes {
+				message.NewVids = append(message.NewVids, uint32(v.Id))
+			}
 			for _, v := range volumeInfos {
 				t.RegisterVolumeLayout(v, dn)
 			}
 			for _, v := range deletedVolumes {
 				t.UnRegisterVolumeLayout(v, dn)
 			}
+			updateDataNodeMetrics(dn)
+			if len(message.NewVids) > 0 || len(message.DeletedVids) > 0 {
+				ms.broadcastToClients(message)
+			}
 
 		} else {
 			if dn != nil {
 				glog.V(0).Infof("lost volume server %s:%d", dn.Ip, dn.Port)
+				message := &master_pb.VolumeLocation{Url: dn.Url(), PublicUrl: dn.PublicUrl}
+				for _, v := range dn.GetVolumes() {
+					message.DeletedVids = append(message.DeletedVids, uint32(v.Id))
+				}
+				removeDataNodeMetrics(dn)
 				t.UnRegisterDataNode(dn)
+				if len(message.DeletedVids) > 0 {
+					ms.broadcastToClients(message)
+				}
 			}
 			return err
 		}
@@ -77,3 +113,116 @@ func (ms MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServe
 		}
 	}
 }
+
+// KeepConnected streams the volume locations to a client, first all of them,
+// then their changes as the volume servers report them.
+// A master that is not the leader only sends the leader address.
+func (ms *MasterServer) KeepConnected(stream master_pb.Seaweed_KeepConnectedServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if !ms.Topo.IsLeader() {
+		return ms.informNewLeader(stream)
+	}
+
+	clientName := req.Name
+	if pr, ok := peer.FromContext(stream.Context()); ok {
+		clientName = req.Name + "@" + pr.Addr.String()
+	}
+	glog.V(0).Infof("volume location client %s connected", clientName)
+
+	// listen before sending the current locations, so no change is missed
+	messageChan := make(chan *master_pb.VolumeLocation, clientChanBufferSize)
+	ms.clientChansLock.Lock()
+	ms.clientChans[clientName] = messageChan
+	ms.clientChansLock.Unlock()
+	defer func() {
+		ms.clientChansLock.Lock()
+		if ms.clientChans[clientName] == messageChan {
+			delete(ms.clientChans, clientName)
+		}
+		ms.clientChansLock.Unlock()
+		glog.V(0).Infof("volume location client %s disconnected", clientName)
+	}()
+
+	for _, dn := range ms.Topo.DataNodes() {
+		message := &master_pb.VolumeLocation{Url: dn.Url(), PublicUrl: dn.PublicUrl}
+		for _, v := range dn.GetVolumes() {
+			message.NewVids = append(message.NewVids, uint32(v.Id))
+		}
+		if err := stream.Send(message); err != nil {
+			return err
+		}
+	}
+
+	stopChan := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := stream.Recv(); err != nil {
+				stopChan <- err
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Duration(ms.pulseSeconds) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case message, ok := <-messageChan:
+			if !ok {
+				return fmt.Errorf("volume location client %s is too slow", clientName)
+			}
+			if err := stream.Send(message); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if !ms.Topo.IsLeader() {
+				return ms.informNewLeader(stream)
+			}
+		case <-stopChan:
+			return nil
+		}
+	}
+}
+
+func (ms *MasterServer) informNewLeader(stream master_pb.Seaweed_KeepConnectedServer) error {
+	leader, err := ms.Topo.Leader()
+	if err != nil {
+		return err
+	}
+	return stream.Send(&master_pb.VolumeLocation{Leader: leader})
+}
+
+// clientChanBufferSize is how many volume location changes can wait
+// to be sent to a client, before it is disconnected to catch up again.
+const clientChanBufferSize = 1024
+
+func (ms *MasterServer) broadcastToClients(message *master_pb.VolumeLocation) {
+	ms.clientChansLock.Lock()
+	defer ms.clientChansLock.Unlock()
+	for clientName, ch := range ms.clientChans {
+		select {
+		case ch <- message:
+		default:
+			glog.V(0).Infof("disconnect slow volume location client %s", clientName)
+			delete(ms.clientChans, clientName)
+			close(ch)
+		}
+	}
+}
+
+func updateDataNodeMetrics(dn *topology.DataNode) {
+	dcName, rackName, nodeName := string(dn.GetDataCenter().Id()), string(dn.GetRack().Id()), dn.Url()
+	stats.MasterDataNodeVolumeGauge.WithLabelValues(dcName, rackName, nodeName, "volumes").Set(float64(dn.GetVolumeCount()))
+	stats.MasterDataNodeVolumeGauge.WithLabelValues(dcName, rackName, nodeName, "active").Set(float64(dn.GetActiveVolumeCount()))
+	stats.MasterDataNodeVolumeGauge.WithLabelValues(dcName, rackName, nodeName, "free").Set(float64(dn.FreeSpace()))
+}
+
+func removeDataNodeMetrics(dn *topology.DataNode) {
+	dcName, rackName, nodeName := string(dn.GetDataCenter().Id()), string(dn.GetRack().Id()), dn.Url()
+	for _, t := range []string{"volumes", "active", "free"} {
+		stats.MasterDataNodeVolumeGauge.DeleteLabelValues(dcName, rackName, nodeName, t)
+	}
+}
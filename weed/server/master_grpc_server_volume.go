@@ -0,0 +1,162 @@
+package weed_server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"google.golang.org/grpc/metadata"
+)
+
+func (ms *MasterServer) Assign(ctx context.Context, req *master_pb.AssignRequest) (*master_pb.AssignResponse, error) {
+	if !ms.Topo.IsLeader() {
+		var resp *master_pb.AssignResponse
+		err := ms.withLeaderClient(ctx, func(ctx context.Context, client master_pb.SeaweedClient) (err error) {
+			resp, err = client.Assign(ctx, req)
+			return
+		})
+		return resp, err
+	}
+	if err := ms.guard.CheckGrpcPermission(ctx, security.PermissionAssign, req.Collection); err != nil {
+		return nil, err
+	}
+
+	stats.AssignRequest()
+	stats.MasterRequestCounter.WithLabelValues("assign").Inc()
+	start := time.Now()
+	defer func() { stats.MasterRequestHistogram.WithLabelValues("assign").Observe(time.Since(start).Seconds()) }()
+
+	requestedCount := req.Count
+	if requestedCount == 0 {
+		requestedCount = 1
+	}
+	option, err := ms.newVolumeGrowOption(req.Collection, req.Replication, req.Ttl,
+		req.DataCenter, req.Rack, req.DataNode, ms.preallocate)
+	if err != nil {
+		return &master_pb.AssignResponse{Error: err.Error()}, nil
+	}
+	if err = ms.ensureWritableVolume(option); err != nil {
+		return &master_pb.AssignResponse{Error: err.Error()}, nil
+	}
+	fid, count, dn, err := ms.Topo.PickForWrite(requestedCount, option)
+	if err != nil {
+		return &master_pb.AssignResponse{Error: err.Error()}, nil
+	}
+	return &master_pb.AssignResponse{
+		Fid:       fid,
+		Url:       dn.Url(),
+		PublicUrl: dn.PublicUrl,
+		Count:     count,
+	}, nil
+}
+
+// LookupVolume finds the locations of the volumes. For a file id instead of
+// a volume id, the read token, if read tokens are required, is for the file.
+func (ms *MasterServer) LookupVolume(ctx context.Context, req *master_pb.LookupVolumeRequest) (*master_pb.LookupVolumeResponse, error) {
+	if !ms.Topo.IsLeader() {
+		var resp *master_pb.LookupVolumeResponse
+		err := ms.withLeaderClient(ctx, func(ctx context.Context, client master_pb.SeaweedClient) (err error) {
+			resp, err = client.LookupVolume(ctx, req)
+			return
+		})
+		return resp, err
+	}
+
+	stats.MasterRequestCounter.WithLabelValues("batchLookup").Inc()
+	start := time.Now()
+	defer func() {
+		stats.MasterRequestHistogram.WithLabelValues("batchLookup").Observe(time.Since(start).Seconds())
+	}()
+
+	resp := &master_pb.LookupVolumeResponse{}
+	for _, volumeOrFileId := range req.VolumeIds {
+		vid, fileId := volumeOrFileId, ""
+		if commaSep := strings.Index(volumeOrFileId, ","); commaSep > 0 {
+			vid, fileId = volumeOrFileId[0:commaSep], volumeOrFileId
+		}
+		location := &master_pb.VolumeIdLocation{VolumeId: volumeOrFileId}
+		resp.VolumeIdLocations = append(resp.VolumeIdLocations, location)
+		if err := ms.guard.CheckGrpcPermission(ctx, security.PermissionRead, ms.volumeCollection(vid, req.Collection)); err != nil {
+			location.Error = err.Error()
+			continue
+		}
+		result := ms.lookupVolumeId([]string{vid}, req.Collection)[vid]
+		if result.Error != "" {
+			location.Error = result.Error
+			continue
+		}
+		for _, loc := range result.Locations {
+			location.Locations = append(location.Locations, &master_pb.Location{Url: loc.Url, PublicUrl: loc.PublicUrl})
+		}
+		location.Jwt = string(ms.readJwt(fileId))
+	}
+	return resp, nil
+}
+
+func (ms *MasterServer) CollectionList(ctx context.Context, req *master_pb.CollectionListRequest) (*master_pb.CollectionListResponse, error) {
+	if !ms.Topo.IsLeader() {
+		var resp *master_pb.CollectionListResponse
+		err := ms.withLeaderClient(ctx, func(ctx context.Context, client master_pb.SeaweedClient) (err error) {
+			resp, err = client.CollectionList(ctx, req)
+			return
+		})
+		return resp, err
+	}
+	if err := ms.guard.CheckGrpcPermission(ctx, security.PermissionAdmin, ""); err != nil {
+		return nil, err
+	}
+	resp := &master_pb.CollectionListResponse{}
+	for _, name := range ms.Topo.ListCollectionNames() {
+		resp.Collections = append(resp.Collections, &master_pb.Collection{Name: name})
+	}
+	return resp, nil
+}
+
+func (ms *MasterServer) CollectionDelete(ctx context.Context, req *master_pb.CollectionDeleteRequest) (*master_pb.CollectionDeleteResponse, error) {
+	if !ms.Topo.IsLeader() {
+		var resp *master_pb.CollectionDeleteResponse
+		err := ms.withLeaderClient(ctx, func(ctx context.Context, client master_pb.SeaweedClient) (err error) {
+			resp, err = client.CollectionDelete(ctx, req)
+			return
+		})
+		return resp, err
+	}
+	if err := ms.guard.CheckGrpcPermission(ctx, security.PermissionAdmin, req.Name); err != nil {
+		return nil, err
+	}
+	if err := ms.deleteCollection(req.Name); err != nil {
+		return nil, err
+	}
+	return &master_pb.CollectionDeleteResponse{}, nil
+}
+
+// withLeaderClient forwards a call to the leader, like proxyToLeader does for
+// http requests. The leader checks the permission with the api key and the
+// address of the original client.
+func (ms *MasterServer) withLeaderClient(ctx context.Context, fn func(ctx context.Context, client master_pb.SeaweedClient) error) error {
+	if ms.Topo.RaftServer == nil || ms.Topo.RaftServer.Leader() == "" {
+		return errors.New("leader is not known yet")
+	}
+	leader := ms.Topo.RaftServer.Leader()
+	ms.bounedLeaderChan <- 1
+	defer func() { <-ms.bounedLeaderChan }()
+
+	client, err := operation.MasterClient(leader)
+	if err != nil {
+		return err
+	}
+	md := metadata.Pairs(security.GrpcForwardedForMetadata, ms.guard.GrpcRemoteHost(ctx))
+	if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+		if apiKeys := incoming.Get(security.GrpcApiKeyMetadata); len(apiKeys) > 0 {
+			md.Set(security.GrpcApiKeyMetadata, apiKeys[0])
+		}
+	}
+	glog.V(4).Infoln("forwarding to leader", leader)
+	return fn(metadata.NewOutgoingContext(ctx, md), client)
+}
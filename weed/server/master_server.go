@@ -9,8 +9,10 @@ import (
 
 	"github.com/chrislusf/raft"
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/sequence"
+	"github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/topology"
 	"github.com/chrislusf/seaweedfs/weed/util"
 	"github.com/gorilla/mux"
@@ -23,24 +25,36 @@ type MasterServer struct {
 	preallocate             int64
 	pulseSeconds            int
 	defaultReplicaPlacement string
-	garbageThreshold        string
 	guard                   *security.Guard
+	readExpireSeconds       int
+	privateCollections      []string
 
-	Topo   *topology.Topology
-	vg     *topology.VolumeGrowth
-	vgLock sync.Mutex
+	Topo       *topology.Topology
+	vg         *topology.VolumeGrowth
+	vgLock     sync.Mutex
+	raftServer *RaftServer
 
 	bounedLeaderChan chan int
+
+	// clients listening to volume location changes
+	clientChansLock sync.RWMutex
+	clientChans     map[string]chan *master_pb.VolumeLocation
 }
 
 func NewMasterServer(r *mux.Router, port int, metaFolder string,
+	seq sequence.Sequencer,
 	volumeSizeLimitMB uint,
 	preallocate bool,
 	pulseSeconds int,
 	defaultReplicaPlacement string,
-	garbageThreshold string,
+	placement topology.PlacementStrategy,
+	vacuumOptions topology.VacuumOptions,
 	whiteList []string,
 	secureKey string,
+	readSecureKey string,
+	readExpireSeconds int,
+	privateCollections []string,
+	credentials *security.CredentialStore,
 ) *MasterServer {
 
 	var preallocateSize int64
@@ -53,46 +67,68 @@ func NewMasterServer(r *mux.Router, port int, metaFolder string,
 		preallocate:             preallocateSize,
 		pulseSeconds:            pulseSeconds,
 		defaultReplicaPlacement: defaultReplicaPlacement,
-		garbageThreshold:        garbageThreshold,
+		readExpireSeconds:       readExpireSeconds,
+		privateCollections:      privateCollections,
 	}
 	ms.bounedLeaderChan = make(chan int, 16)
-	seq := sequence.NewMemorySequencer()
+	ms.clientChans = make(map[string]chan *master_pb.VolumeLocation)
 	ms.Topo = topology.NewTopology("topo", seq, uint64(volumeSizeLimitMB)*1024*1024, pulseSeconds)
-	ms.vg = topology.NewDefaultVolumeGrowth()
+	ms.vg = topology.NewVolumeGrowth(placement)
 	glog.V(0).Infoln("Volume Size Limit is", volumeSizeLimitMB, "MB")
 
 	ms.guard = security.NewGuard(whiteList, secureKey)
+	ms.guard.ReadSecretKey = security.Secret(readSecureKey)
+	ms.guard.Credentials = credentials
 
 	r.HandleFunc("/", ms.uiStatusHandler)
 	r.HandleFunc("/ui/index.html", ms.uiStatusHandler)
-	r.HandleFunc("/dir/assign", ms.proxyToLeader(ms.guard.WhiteList(ms.dirAssignHandler)))
-	r.HandleFunc("/dir/lookup", ms.proxyToLeader(ms.guard.WhiteList(ms.dirLookupHandler)))
-	r.HandleFunc("/dir/status", ms.proxyToLeader(ms.guard.WhiteList(ms.dirStatusHandler)))
-	r.HandleFunc("/col/delete", ms.proxyToLeader(ms.guard.WhiteList(ms.collectionDeleteHandler)))
-	r.HandleFunc("/vol/lookup", ms.proxyToLeader(ms.guard.WhiteList(ms.volumeLookupHandler)))
-	r.HandleFunc("/vol/grow", ms.proxyToLeader(ms.guard.WhiteList(ms.volumeGrowHandler)))
-	r.HandleFunc("/vol/status", ms.proxyToLeader(ms.guard.WhiteList(ms.volumeStatusHandler)))
-	r.HandleFunc("/vol/vacuum", ms.proxyToLeader(ms.guard.WhiteList(ms.volumeVacuumHandler)))
-	r.HandleFunc("/submit", ms.guard.WhiteList(ms.submitFromMasterServerHandler))
-	r.HandleFunc("/delete", ms.guard.WhiteList(ms.deleteFromMasterServerHandler))
+	r.HandleFunc("/dir/assign", ms.proxyToLeader(ms.guard.Permit(security.PermissionAssign, ms.dirAssignHandler)))
+	r.HandleFunc("/dir/lookup", ms.proxyToLeader(ms.dirLookupHandler))
+	r.HandleFunc("/dir/status", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.dirStatusHandler)))
+	r.HandleFunc("/col/settings", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.collectionSettingsHandler)))
+	r.HandleFunc("/col/delete", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.collectionDeleteHandler)))
+	r.HandleFunc("/cluster/peer/add", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.clusterPeerAddHandler)))
+	r.HandleFunc("/cluster/peer/remove", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.clusterPeerRemoveHandler)))
+	r.HandleFunc("/node/state", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.nodeStateHandler)))
+	r.HandleFunc("/vol/lookup", ms.proxyToLeader(ms.guard.Permit(security.PermissionRead, ms.volumeLookupHandler)))
+	r.HandleFunc("/vol/grow", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.volumeGrowHandler)))
+	r.HandleFunc("/vol/move", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.volumeMoveHandler)))
+	r.HandleFunc("/vol/status", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.volumeStatusHandler)))
+	r.HandleFunc("/vol/vacuum", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.volumeVacuumHandler)))
+	r.HandleFunc("/vol/vacuum/history", ms.proxyToLeader(ms.guard.Permit(security.PermissionAdmin, ms.volumeVacuumHistoryHandler)))
+	r.HandleFunc("/submit", ms.guard.Permit(security.PermissionWrite, ms.submitFromMasterServerHandler))
+	r.HandleFunc("/delete", ms.guard.Permit(security.PermissionDelete, ms.deleteFromMasterServerHandler))
 	r.HandleFunc("/stats/health", ms.guard.WhiteList(statsHealthHandler))
 	r.HandleFunc("/stats/counter", ms.guard.WhiteList(statsCounterHandler))
 	r.HandleFunc("/stats/memory", ms.guard.WhiteList(statsMemoryHandler))
+	r.HandleFunc("/metrics", ms.guard.WhiteList(statsMetricsHandler))
 	r.HandleFunc("/{fileId}", ms.proxyToLeader(ms.redirectHandler))
 
-	ms.Topo.StartRefreshWritableVolumes(garbageThreshold, ms.preallocate)
+	vacuumOptions.Preallocate = ms.preallocate
+	ms.Topo.StartRefreshWritableVolumes(vacuumOptions)
 
 	return ms
 }
 
 func (ms *MasterServer) SetRaftServer(raftServer *RaftServer) {
+	ms.raftServer = raftServer
 	ms.Topo.RaftServer = raftServer.raftServer
+	ms.guard.ForwardingPeers = raftServer.Peers
+	if seq, ok := ms.Topo.Sequence.(*sequence.RaftSequencer); ok {
+		seq.SetReserveFunc(ms.Topo.ReserveFileIds)
+	}
 	ms.Topo.RaftServer.AddEventListener(raft.LeaderChangeEventType, func(e raft.Event) {
 		if ms.Topo.RaftServer.Leader() != "" {
 			glog.V(0).Infoln("[", ms.Topo.RaftServer.Name(), "]", ms.Topo.RaftServer.Leader(), "becomes leader.")
 		}
+		if ms.Topo.IsLeader() {
+			stats.MasterRaftIsLeader.Set(1)
+		} else {
+			stats.MasterRaftIsLeader.Set(0)
+		}
 	})
 	if ms.Topo.IsLeader() {
+		stats.MasterRaftIsLeader.Set(1)
 		glog.V(0).Infoln("[", ms.Topo.RaftServer.Name(), "]", "I am the leader!")
 	} else {
 		if ms.Topo.RaftServer.Leader() != "" {
@@ -118,7 +154,7 @@ func (ms *MasterServer) proxyToLeader(f func(w http.ResponseWriter, r *http.Requ
 			proxy := httputil.NewSingleHostReverseProxy(targetUrl)
 			director := proxy.Director
 			proxy.Director = func(req *http.Request) {
-				actualHost, err := security.GetActualRemoteHost(req)
+				actualHost, err := ms.guard.RemoteHost(req)
 				if err == nil {
 					req.Header.Set("HTTP_X_FORWARDED_FOR", actualHost)
 				}
@@ -1,14 +1,19 @@
 package weed_server
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 )
 
 func (ms *MasterServer) lookupVolumeId(vids []string, collection string) (volumeLocations map[string]operation.LookupResult) {
@@ -42,24 +47,66 @@ func (ms *MasterServer) lookupVolumeId(vids []string, collection string) (volume
 
 // Takes one volumeId only, can not do batch lookup
 func (ms *MasterServer) dirLookupHandler(w http.ResponseWriter, r *http.Request) {
+	stats.MasterRequestCounter.WithLabelValues("lookup").Inc()
+	start := time.Now()
+	defer func() { stats.MasterRequestHistogram.WithLabelValues("lookup").Observe(time.Since(start).Seconds()) }()
+	r, span := tracing.StartServerSpan(r, "master.lookup")
+	defer span.End()
 	vid := r.FormValue("volumeId")
+	fileId := ""
 	commaSep := strings.Index(vid, ",")
 	if commaSep > 0 {
+		fileId = vid
 		vid = vid[0:commaSep]
 	}
 	vids := []string{vid}
 	collection := r.FormValue("collection") //optional, but can be faster if too many collections
+	if err := ms.guard.CheckPermission(r, security.PermissionRead, ms.volumeCollection(vid, collection), ""); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
 	volumeLocations := ms.lookupVolumeId(vids, collection)
 	location := volumeLocations[vid]
 	httpStatus := http.StatusOK
 	if location.Error != "" {
 		httpStatus = http.StatusNotFound
+	} else {
+		location.Jwt = string(ms.readJwt(fileId))
 	}
 	writeJsonQuiet(w, r, httpStatus, location)
 }
 
+// readJwt returns a read token for the file id, if read tokens are required.
+// Lookups of a bare volume id get no token, they would read the whole collection.
+func (ms *MasterServer) readJwt(fileId string) security.EncodedJwt {
+	if fileId == "" || ms.guard.ReadSecretKey == "" {
+		return ""
+	}
+	return security.GenReadJwtForFileId(ms.guard.ReadSecretKey, ms.readExpireSeconds, fileId)
+}
+
+// volumeCollection returns the collection the volume belongs to,
+// or the given collection if the volume is not found.
+func (ms *MasterServer) volumeCollection(vid string, collection string) string {
+	volumeId, err := storage.NewVolumeId(vid)
+	if err != nil {
+		return collection
+	}
+	if machines := ms.Topo.Lookup(collection, volumeId); len(machines) > 0 {
+		if vi, err := machines[0].GetVolumesById(volumeId); err == nil {
+			return vi.Collection
+		}
+	}
+	return collection
+}
+
 // This can take batched volumeIds, &volumeId=x&volumeId=y&volumeId=z
 func (ms *MasterServer) volumeLookupHandler(w http.ResponseWriter, r *http.Request) {
+	stats.MasterRequestCounter.WithLabelValues("batchLookup").Inc()
+	start := time.Now()
+	defer func() {
+		stats.MasterRequestHistogram.WithLabelValues("batchLookup").Observe(time.Since(start).Seconds())
+	}()
 	r.ParseForm()
 	vids := r.Form["volumeId"]
 	collection := r.FormValue("collection") //optional, but can be faster if too many collections
@@ -69,6 +116,11 @@ func (ms *MasterServer) volumeLookupHandler(w http.ResponseWriter, r *http.Reque
 
 func (ms *MasterServer) dirAssignHandler(w http.ResponseWriter, r *http.Request) {
 	stats.AssignRequest()
+	stats.MasterRequestCounter.WithLabelValues("assign").Inc()
+	start := time.Now()
+	defer func() { stats.MasterRequestHistogram.WithLabelValues("assign").Observe(time.Since(start).Seconds()) }()
+	r, span := tracing.StartServerSpan(r, "master.assign")
+	defer span.End()
 	requestedCount, e := strconv.ParseUint(r.FormValue("count"), 10, 64)
 	if e != nil || requestedCount == 0 {
 		requestedCount = 1
@@ -80,20 +132,12 @@ func (ms *MasterServer) dirAssignHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if !ms.Topo.HasWritableVolume(option) {
-		if ms.Topo.FreeSpace() <= 0 {
-			writeJsonQuiet(w, r, http.StatusNotFound, operation.AssignResult{Error: "No free volumes left!"})
-			return
-		}
-		ms.vgLock.Lock()
-		defer ms.vgLock.Unlock()
-		if !ms.Topo.HasWritableVolume(option) {
-			if _, err = ms.vg.AutomaticGrowByType(option, ms.Topo); err != nil {
-				writeJsonError(w, r, http.StatusInternalServerError,
-					fmt.Errorf("Cannot grow volume group! %v", err))
-				return
-			}
-		}
+	if err = ms.ensureWritableVolume(option); err == errNoFreeVolumes {
+		writeJsonQuiet(w, r, http.StatusNotFound, operation.AssignResult{Error: err.Error()})
+		return
+	} else if err != nil {
+		writeJsonError(w, r, http.StatusInternalServerError, err)
+		return
 	}
 	fid, count, dn, err := ms.Topo.PickForWrite(requestedCount, option)
 	if err == nil {
@@ -102,3 +146,23 @@ func (ms *MasterServer) dirAssignHandler(w http.ResponseWriter, r *http.Request)
 		writeJsonQuiet(w, r, http.StatusNotAcceptable, operation.AssignResult{Error: err.Error()})
 	}
 }
+
+var errNoFreeVolumes = errors.New("No free volumes left!")
+
+// ensureWritableVolume grows the volumes of the option if none is writable.
+func (ms *MasterServer) ensureWritableVolume(option *topology.VolumeGrowOption) error {
+	if ms.Topo.HasWritableVolume(option) {
+		return nil
+	}
+	if ms.Topo.FreeSpace() <= 0 {
+		return errNoFreeVolumes
+	}
+	ms.vgLock.Lock()
+	defer ms.vgLock.Unlock()
+	if !ms.Topo.HasWritableVolume(option) {
+		if _, err := ms.vg.AutomaticGrowByType(option, ms.Topo); err != nil {
+			return fmt.Errorf("Cannot grow volume group! %v", err)
+		}
+	}
+	return nil
+}
@@ -8,25 +8,36 @@ import (
 	"strconv"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 	"github.com/chrislusf/seaweedfs/weed/topology"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
 func (ms *MasterServer) collectionDeleteHandler(w http.ResponseWriter, r *http.Request) {
-	collection, ok := ms.Topo.FindCollection(r.FormValue("collection"))
-	if !ok {
+	if _, ok := ms.Topo.FindCollection(r.FormValue("collection")); !ok {
 		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("collection %s does not exist", r.FormValue("collection")))
 		return
 	}
+	if err := ms.deleteCollection(r.FormValue("collection")); err != nil {
+		writeJsonError(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// deleteCollection deletes the collection on all its volume servers.
+func (ms *MasterServer) deleteCollection(name string) error {
+	collection, ok := ms.Topo.FindCollection(name)
+	if !ok {
+		return fmt.Errorf("collection %s does not exist", name)
+	}
 	for _, server := range collection.ListVolumeServers() {
-		_, err := util.Get("http://" + server.Ip + ":" + strconv.Itoa(server.Port) + "/admin/delete_collection?collection=" + r.FormValue("collection"))
+		_, err := util.Get("http://" + server.Ip + ":" + strconv.Itoa(server.Port) + "/admin/delete_collection?collection=" + name)
 		if err != nil {
-			writeJsonError(w, r, http.StatusInternalServerError, err)
-			return
+			return err
 		}
 	}
-	ms.Topo.DeleteCollection(r.FormValue("collection"))
+	ms.Topo.DeleteCollection(name)
+	return nil
 }
 
 func (ms *MasterServer) dirStatusHandler(w http.ResponseWriter, r *http.Request) {
@@ -38,14 +49,15 @@ func (ms *MasterServer) dirStatusHandler(w http.ResponseWriter, r *http.Request)
 
 func (ms *MasterServer) volumeVacuumHandler(w http.ResponseWriter, r *http.Request) {
 	gcThreshold := r.FormValue("garbageThreshold")
-	if gcThreshold == "" {
-		gcThreshold = ms.garbageThreshold
-	}
 	glog.Infoln("garbageThreshold =", gcThreshold)
-	ms.Topo.Vacuum(gcThreshold, ms.preallocate)
+	glog.V(0).Infof("vacuumed %d volumes", ms.Topo.Vacuum(gcThreshold))
 	ms.dirStatusHandler(w, r)
 }
 
+func (ms *MasterServer) volumeVacuumHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	writeJsonQuiet(w, r, http.StatusOK, map[string]interface{}{"History": ms.Topo.VacuumHistory()})
+}
+
 func (ms *MasterServer) volumeGrowHandler(w http.ResponseWriter, r *http.Request) {
 	count := 0
 	option, err := ms.getVolumeGrowOption(r)
@@ -71,6 +83,24 @@ func (ms *MasterServer) volumeGrowHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+func (ms *MasterServer) volumeMoveHandler(w http.ResponseWriter, r *http.Request) {
+	vid, err := storage.NewVolumeId(r.FormValue("volume"))
+	if err != nil {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("Unknown volume id %s", r.FormValue("volume")))
+		return
+	}
+	source, target, err := ms.Topo.MoveVolume(vid, r.FormValue("source"), r.FormValue("target"))
+	if target != nil {
+		ms.broadcastToClients(&master_pb.VolumeLocation{Url: target.Url(), PublicUrl: target.PublicUrl, NewVids: []uint32{uint32(vid)}})
+		ms.broadcastToClients(&master_pb.VolumeLocation{Url: source.Url(), PublicUrl: source.PublicUrl, DeletedVids: []uint32{uint32(vid)}})
+	}
+	if err != nil {
+		writeJsonError(w, r, http.StatusNotAcceptable, err)
+		return
+	}
+	writeJsonQuiet(w, r, http.StatusOK, map[string]interface{}{"volume": vid, "source": source.Url(), "target": target.Url()})
+}
+
 func (ms *MasterServer) volumeStatusHandler(w http.ResponseWriter, r *http.Request) {
 	m := make(map[string]interface{})
 	m["Version"] = util.VERSION
@@ -133,33 +163,98 @@ func (ms *MasterServer) HasWritableVolume(option *topology.VolumeGrowOption) boo
 }
 
 func (ms *MasterServer) getVolumeGrowOption(r *http.Request) (*topology.VolumeGrowOption, error) {
-	replicationString := r.FormValue("replication")
-	if replicationString == "" {
-		replicationString = ms.defaultReplicaPlacement
-	}
-	replicaPlacement, err := storage.NewReplicaPlacementFromString(replicationString)
-	if err != nil {
-		return nil, err
-	}
-	ttl, err := storage.ReadTTL(r.FormValue("ttl"))
-	if err != nil {
-		return nil, err
-	}
 	preallocate := ms.preallocate
 	if r.FormValue("preallocate") != "" {
+		var err error
 		preallocate, err = strconv.ParseInt(r.FormValue("preallocate"), 10, 64)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to parse int64 preallocate = %s: %v", r.FormValue("preallocate"), err)
 		}
 	}
+	return ms.newVolumeGrowOption(r.FormValue("collection"), r.FormValue("replication"), r.FormValue("ttl"),
+		r.FormValue("dataCenter"), r.FormValue("rack"), r.FormValue("dataNode"), preallocate)
+}
+
+func (ms *MasterServer) newVolumeGrowOption(collection, replication, ttlString, dataCenter, rack, dataNode string,
+	preallocate int64) (*topology.VolumeGrowOption, error) {
+	settings, _ := ms.Topo.ClusterConfig.CollectionSettings(collection)
+	if replication == "" {
+		replication = settings.Replication
+	}
+	if replication == "" {
+		replication = ms.defaultReplicaPlacement
+	}
+	if ttlString == "" {
+		ttlString = settings.Ttl
+	}
+	replicaPlacement, err := storage.NewReplicaPlacementFromString(replication)
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := storage.ReadTTL(ttlString)
+	if err != nil {
+		return nil, err
+	}
 	volumeGrowOption := &topology.VolumeGrowOption{
-		Collection:       r.FormValue("collection"),
+		Collection:       collection,
 		ReplicaPlacement: replicaPlacement,
 		Ttl:              ttl,
 		Prealloacte:      preallocate,
-		DataCenter:       r.FormValue("dataCenter"),
-		Rack:             r.FormValue("rack"),
-		DataNode:         r.FormValue("dataNode"),
+		DataCenter:       dataCenter,
+		Rack:             rack,
+		DataNode:         dataNode,
 	}
 	return volumeGrowOption, nil
 }
+
+// collectionSettingsHandler sets the default replication, ttl, volume placement and dedup of a collection,
+// or lists the settings of all collections without the collection parameter.
+func (ms *MasterServer) collectionSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if _, ok := r.Form["collection"]; !ok {
+		writeJsonQuiet(w, r, http.StatusOK, ms.Topo.ClusterConfig.AllCollectionSettings())
+		return
+	}
+	settings := topology.CollectionSettings{
+		Replication: r.FormValue("replication"),
+		Ttl:         r.FormValue("ttl"),
+		Placement:   r.FormValue("placement"),
+		Dedup:       r.FormValue("dedup") == "true",
+	}
+	if err := ms.Topo.SetCollectionSettings(r.FormValue("collection"), settings); err != nil {
+		writeJsonError(w, r, http.StatusNotAcceptable, err)
+		return
+	}
+	writeJsonQuiet(w, r, http.StatusOK, settings)
+}
+
+// nodeStateHandler sets the state of a volume server, like draining,
+// or lists the volume servers not active without the node parameter.
+func (ms *MasterServer) nodeStateHandler(w http.ResponseWriter, r *http.Request) {
+	node := r.FormValue("node")
+	if node == "" {
+		writeJsonQuiet(w, r, http.StatusOK, ms.Topo.ClusterConfig.AllNodeStates())
+		return
+	}
+	if err := ms.Topo.SetNodeState(node, r.FormValue("state")); err != nil {
+		writeJsonError(w, r, http.StatusNotAcceptable, err)
+		return
+	}
+	writeJsonQuiet(w, r, http.StatusOK, map[string]string{"node": node, "state": ms.Topo.ClusterConfig.NodeState(node)})
+}
+
+func (ms *MasterServer) clusterPeerAddHandler(w http.ResponseWriter, r *http.Request) {
+	if err := ms.raftServer.AddPeer(r.FormValue("peer")); err != nil {
+		writeJsonError(w, r, http.StatusNotAcceptable, err)
+		return
+	}
+	writeJsonQuiet(w, r, http.StatusOK, map[string]interface{}{"peers": ms.raftServer.Peers()})
+}
+
+func (ms *MasterServer) clusterPeerRemoveHandler(w http.ResponseWriter, r *http.Request) {
+	if err := ms.raftServer.RemovePeer(r.FormValue("peer")); err != nil {
+		writeJsonError(w, r, http.StatusNotAcceptable, err)
+		return
+	}
+	writeJsonQuiet(w, r, http.StatusOK, map[string]interface{}{"peers": ms.raftServer.Peers()})
+}
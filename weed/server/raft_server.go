@@ -19,25 +19,28 @@ import (
 	"github.com/chrislusf/raft"
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/topology"
+	"github.com/chrislusf/seaweedfs/weed/util"
 	"github.com/gorilla/mux"
 )
 
 type RaftServer struct {
-	peers      []string // initial peers to join with
-	raftServer raft.Server
-	dataDir    string
-	httpAddr   string
-	router     *mux.Router
-	topo       *topology.Topology
+	peers           []string // initial peers to join with
+	raftServer      raft.Server
+	dataDir         string
+	httpAddr        string
+	router          *mux.Router
+	topo            *topology.Topology
+	electionTimeout time.Duration
 }
 
 func NewRaftServer(r *mux.Router, peers []string, httpAddr string, dataDir string, topo *topology.Topology, pulseSeconds int) *RaftServer {
 	s := &RaftServer{
-		peers:    peers,
-		httpAddr: httpAddr,
-		dataDir:  dataDir,
-		router:   r,
-		topo:     topo,
+		peers:           peers,
+		httpAddr:        httpAddr,
+		dataDir:         dataDir,
+		router:          r,
+		topo:            topo,
+		electionTimeout: time.Duration(pulseSeconds) * 500 * time.Millisecond,
 	}
 
 	if glog.V(4) {
@@ -45,10 +48,14 @@ func NewRaftServer(r *mux.Router, peers []string, httpAddr string, dataDir strin
 	}
 
 	raft.RegisterCommand(&topology.MaxVolumeIdCommand{})
+	raft.RegisterCommand(&topology.ReserveFileIdsCommand{})
+	raft.RegisterCommand(&topology.SetCollectionSettingsCommand{})
+	raft.RegisterCommand(&topology.SetNodeStateCommand{})
 
 	var err error
 	transporter := raft.NewHTTPTransporter("/cluster", 0)
 	transporter.Transport.MaxIdleConnsPerHost = 1024
+	util.UpgradeToTLS(transporter.Transport)
 	glog.V(1).Infof("Starting RaftServer with IP:%v:", httpAddr)
 
 	// Clear old cluster configurations if peers are changed
@@ -58,16 +65,23 @@ func NewRaftServer(r *mux.Router, peers []string, httpAddr string, dataDir strin
 		os.RemoveAll(path.Join(s.dataDir, "log"))
 		os.RemoveAll(path.Join(s.dataDir, "snapshot"))
 	}
+	if err = savePeersFlag(s.dataDir, s.peers); err != nil {
+		glog.V(0).Infof("save peers flag: %v", err)
+	}
 
-	s.raftServer, err = raft.NewServer(s.httpAddr, s.dataDir, transporter, nil, topo, "")
+	s.raftServer, err = raft.NewServer(s.httpAddr, s.dataDir, transporter, topology.NewStateMachine(topo), topo, "")
 	if err != nil {
 		glog.V(0).Infoln(err)
 		return nil
 	}
 	transporter.Install(s.raftServer, s)
 	s.raftServer.SetHeartbeatInterval(500 * time.Millisecond)
-	s.raftServer.SetElectionTimeout(time.Duration(pulseSeconds) * 500 * time.Millisecond)
+	s.raftServer.SetElectionTimeout(s.electionTimeout)
+	if err = s.raftServer.LoadSnapshot(); err != nil {
+		glog.V(0).Infof("load raft snapshot: %v", err)
+	}
 	s.raftServer.Start()
+	go s.takeSnapshots()
 
 	s.router.HandleFunc("/cluster/join", s.joinHandler).Methods("POST")
 	s.router.HandleFunc("/cluster/status", s.statusHandler).Methods("GET")
@@ -114,6 +128,26 @@ func NewRaftServer(r *mux.Router, peers []string, httpAddr string, dataDir strin
 	return s
 }
 
+// raftSnapshotEntries is how many committed raft log entries trigger a new
+// snapshot, after which the log is compacted.
+const raftSnapshotEntries = 1000
+
+func (s *RaftServer) takeSnapshots() {
+	lastIndex := s.raftServer.CommitIndex()
+	for range time.Tick(time.Minute) {
+		commitIndex := s.raftServer.CommitIndex()
+		if commitIndex < lastIndex+raftSnapshotEntries {
+			continue
+		}
+		if err := s.raftServer.TakeSnapshot(); err != nil {
+			glog.V(0).Infof("take raft snapshot: %v", err)
+			continue
+		}
+		glog.V(0).Infof("took raft snapshot at commit index %d", commitIndex)
+		lastIndex = commitIndex
+	}
+}
+
 func (s *RaftServer) Peers() (members []string) {
 	peers := s.raftServer.Peers()
 
@@ -124,7 +158,26 @@ func (s *RaftServer) Peers() (members []string) {
 	return
 }
 
+// peersFlagFile keeps the -peers flag of the last start. Members added or
+// removed at runtime differ from the flag, and should survive a restart.
+const peersFlagFile = "peers"
+
+func savePeersFlag(dir string, peers []string) error {
+	sorted := append([]string(nil), peers...)
+	sort.Strings(sorted)
+	return ioutil.WriteFile(path.Join(dir, peersFlagFile), []byte(strings.Join(sorted, ",")), 0644)
+}
+
 func isPeersChanged(dir string, self string, peers []string) (oldPeers []string, changed bool) {
+	if b, err := ioutil.ReadFile(path.Join(dir, peersFlagFile)); err == nil {
+		if len(b) > 0 {
+			oldPeers = strings.Split(string(b), ",")
+		}
+		sorted := append([]string(nil), peers...)
+		sort.Strings(sorted)
+		return oldPeers, strings.Join(sorted, ",") != string(b)
+	}
+
 	confPath := path.Join(dir, "conf")
 	// open conf file
 	b, err := ioutil.ReadFile(confPath)
@@ -188,7 +241,8 @@ func (s *RaftServer) Join(peers []string) error {
 // a workaround because http POST following redirection misses request body
 func postFollowingOneRedirect(target string, contentType string, b bytes.Buffer) error {
 	backupReader := bytes.NewReader(b.Bytes())
-	resp, err := http.Post(target, contentType, &b)
+	client := &http.Client{Transport: util.Transport}
+	resp, err := client.Post(target, contentType, &b)
 	if err != nil {
 		return err
 	}
@@ -201,7 +255,7 @@ func postFollowingOneRedirect(target string, contentType string, b bytes.Buffer)
 		urlStr := reply[1: len(reply)-1]
 
 		glog.V(0).Infoln("Post redirected to ", urlStr)
-		resp2, err2 := http.Post(urlStr, contentType, backupReader)
+		resp2, err2 := client.Post(urlStr, contentType, backupReader)
 		if err2 != nil {
 			return err2
 		}
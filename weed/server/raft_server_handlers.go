@@ -26,6 +26,12 @@ func (s *RaftServer) joinHandler(w http.ResponseWriter, req *http.Request) {
 
 	glog.V(0).Infoln("join command from Name", command.Name, "Connection", command.ConnectionString)
 
+	if err := s.checkJoin(command); err != nil {
+		glog.V(0).Infoln("Refused join:", err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	if _, err := s.raftServer.Do(command); err != nil {
 		switch err {
 		case raft.NotLeaderError:
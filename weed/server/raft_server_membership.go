@@ -0,0 +1,108 @@
+package weed_server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/raft"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// AddPeer adds a running master to the raft cluster. It must be called on the leader.
+// The new member is refused if the cluster could not reach the larger quorum.
+func (s *RaftServer) AddPeer(peer string) error {
+	peer = strings.TrimPrefix(strings.TrimSpace(peer), "http://")
+	if peer == "" {
+		return fmt.Errorf("missing master address")
+	}
+	if s.isMember(peer) {
+		return fmt.Errorf("master %s is already a member", peer)
+	}
+	if _, err := util.Get("http://" + peer + "/cluster/status"); err != nil {
+		return fmt.Errorf("master %s is not reachable: %v", peer, err)
+	}
+	// the new member is reachable, so it counts as live
+	if err := checkQuorum(s.liveMembers("")+1, s.raftServer.MemberCount()+1); err != nil {
+		return err
+	}
+	glog.V(0).Infof("adding master %s to the cluster", peer)
+	_, err := s.raftServer.Do(&raft.DefaultJoinCommand{
+		Name:             peer,
+		ConnectionString: "http://" + peer,
+	})
+	return err
+}
+
+// RemovePeer removes a master from the raft cluster. It must be called on the leader.
+// The member is kept if the remaining live members could not reach the quorum.
+func (s *RaftServer) RemovePeer(peer string) error {
+	peer = strings.TrimPrefix(strings.TrimSpace(peer), "http://")
+	if peer == s.raftServer.Name() {
+		return fmt.Errorf("master %s is the leader, stop it to elect another leader first", peer)
+	}
+	if !s.isMember(peer) {
+		return fmt.Errorf("master %s is not a member", peer)
+	}
+	if err := checkQuorum(s.liveMembers(peer), s.raftServer.MemberCount()-1); err != nil {
+		return err
+	}
+	glog.V(0).Infof("removing master %s from the cluster", peer)
+	_, err := s.raftServer.Do(&raft.DefaultLeaveCommand{
+		Name: peer,
+	})
+	return err
+}
+
+// checkJoin only lets the masters of the -peers flag, or the current members,
+// join by themselves. Other masters are added with AddPeer, which checks the
+// admin permission and the quorum.
+func (s *RaftServer) checkJoin(command *raft.DefaultJoinCommand) error {
+	return checkJoin(command, s.peers, s.isMember)
+}
+
+func checkJoin(command *raft.DefaultJoinCommand, peers []string, isMember func(string) bool) error {
+	if command.ConnectionString != "http://"+command.Name {
+		return fmt.Errorf("master %s joins with another address %s", command.Name, command.ConnectionString)
+	}
+	for _, p := range peers {
+		if strings.TrimPrefix(strings.TrimSpace(p), "http://") == command.Name {
+			return nil
+		}
+	}
+	if isMember(command.Name) {
+		return nil
+	}
+	return fmt.Errorf("master %s is not in -peers, add it with cluster.add", command.Name)
+}
+
+// checkQuorum refuses a membership change leaving fewer live masters than the
+// majority of the members.
+func checkQuorum(live int, members int) error {
+	if quorum := members/2 + 1; live < quorum {
+		return fmt.Errorf("only %d of %d masters would be live, less than the quorum %d",
+			live, members, quorum)
+	}
+	return nil
+}
+
+func (s *RaftServer) isMember(peer string) bool {
+	if peer == s.raftServer.Name() {
+		return true
+	}
+	_, found := s.raftServer.Peers()[peer]
+	return found
+}
+
+// liveMembers counts this server and the peers heard from within the
+// election timeout, except the given peer.
+func (s *RaftServer) liveMembers(except string) (count int) {
+	count = 1
+	for name, p := range s.raftServer.Peers() {
+		if name != except && time.Since(p.LastActivity()) < s.electionTimeout {
+			count++
+		}
+	}
+	return
+}
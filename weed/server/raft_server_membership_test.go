@@ -0,0 +1,51 @@
+package weed_server
+
+import (
+	"testing"
+
+	"github.com/chrislusf/raft"
+)
+
+func TestCheckQuorum(t *testing.T) {
+	tests := []struct {
+		name    string
+		live    int
+		members int
+		ok      bool
+	}{
+		{"add a second master to a single one", 2, 2, true},
+		{"add a third master to two live ones", 3, 3, true},
+		{"add a master to three with one down", 3, 4, true},
+		{"add a master to three with two down", 2, 4, false},
+		{"remove a down master of three", 2, 2, true},
+		{"remove a live master of three with one down", 1, 2, false},
+		{"remove a master of five with one down", 3, 4, true},
+		{"remove a live master of five with two down", 2, 4, false},
+	}
+	for _, tt := range tests {
+		if err := checkQuorum(tt.live, tt.members); (err == nil) != tt.ok {
+			t.Errorf("%s: %d of %d live: %v", tt.name, tt.live, tt.members, err)
+		}
+	}
+}
+
+func TestCheckJoin(t *testing.T) {
+	peers := []string{"m1:9333", " m2:9333"}
+	isMember := func(name string) bool { return name == "m1:9333" || name == "m4:9333" }
+
+	join := func(name string, connection string) error {
+		return checkJoin(&raft.DefaultJoinCommand{Name: name, ConnectionString: connection}, peers, isMember)
+	}
+	if err := join("m2:9333", "http://m2:9333"); err != nil {
+		t.Errorf("configured peer refused: %v", err)
+	}
+	if err := join("m4:9333", "http://m4:9333"); err != nil {
+		t.Errorf("added member refused: %v", err)
+	}
+	if err := join("m3:9333", "http://m3:9333"); err == nil {
+		t.Errorf("master not in -peers accepted")
+	}
+	if err := join("m2:9333", "http://m3:9333"); err == nil {
+		t.Errorf("configured peer with another address accepted")
+	}
+}
@@ -12,6 +12,10 @@ import (
 	"google.golang.org/grpc"
 )
 
+// fullHeartbeatPulses is how often all the volumes are sent to the master,
+// to reconcile its view. Other heartbeats only carry the volume changes.
+const fullHeartbeatPulses = 60
+
 func (vs *VolumeServer) heartbeat() {
 
 	glog.V(0).Infof("Volume server bootstraps with master %s", vs.GetMasterNode())
@@ -36,7 +40,7 @@ func (vs *VolumeServer) doHeartbeat(sleepInterval time.Duration) error {
 		return fmt.Errorf("No master found: %v", err)
 	}
 
-	grpcConection, err := grpc.Dial(masterNode, grpc.WithInsecure())
+	grpcConection, err := grpc.Dial(masterNode, security.GrpcDialOption())
 	if err != nil {
 		return fmt.Errorf("fail to dial: %v", err)
 	}
@@ -51,9 +55,6 @@ func (vs *VolumeServer) doHeartbeat(sleepInterval time.Duration) error {
 	vs.SetMasterNode(masterNode)
 	glog.V(0).Infof("Heartbeat to %s", masterNode)
 
-	vs.store.Client = stream
-	defer func() { vs.store.Client = nil }()
-
 	doneChan := make(chan error, 1)
 
 	go func() {
@@ -69,6 +70,9 @@ func (vs *VolumeServer) doHeartbeat(sleepInterval time.Duration) error {
 			if in.GetSecretKey() != "" {
 				vs.guard.SecretKey = security.Secret(in.GetSecretKey())
 			}
+			if in.GetReadSecretKey() != "" {
+				vs.setReadSettings(security.Secret(in.GetReadSecretKey()), in.GetPrivateCollections())
+			}
 			if in.GetLeader() != "" && masterNode != in.GetLeader() {
 				vs.masterNodes.SetPossibleLeader(in.GetLeader())
 				doneChan <- nil
@@ -77,17 +81,22 @@ func (vs *VolumeServer) doHeartbeat(sleepInterval time.Duration) error {
 		}
 	}()
 
-	if err = stream.Send(vs.store.CollectHeartbeat()); err != nil {
+	if err = vs.store.SendHeartbeat(stream, true); err != nil {
 		glog.V(0).Infof("Volume Server Failed to talk with master %s: %v", masterNode, err)
 		return err
 	}
 
+	// volume changes are reported right away once the master has all the volumes
+	vs.store.Client = stream
+	defer func() { vs.store.Client = nil }()
+
 	tickChan := time.Tick(sleepInterval)
 
-	for {
+	for pulse := 1; ; pulse++ {
 		select {
 		case <-tickChan:
-			if err = stream.Send(vs.store.CollectHeartbeat()); err != nil {
+			full := pulse%fullHeartbeatPulses == 0
+			if err = vs.store.SendHeartbeat(stream, full); err != nil {
 				glog.V(0).Infof("Volume Server Failed to talk with master %s: %v", masterNode, err)
 				return err
 			}
@@ -2,9 +2,12 @@ package weed_server
 
 import (
 	"net/http"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/images"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 )
@@ -18,10 +21,21 @@ type VolumeServer struct {
 	store        *storage.Store
 	guard        *security.Guard
 	masterNodes  *storage.MasterNodes
+	// the read secret and the collections requiring read tokens, all if empty,
+	// once the master sends a read secret
+	readSecret         security.Secret
+	privateCollections []string
+	readLock           sync.RWMutex
 
 	needleMapKind     storage.NeedleMapType
 	FixJpgOrientation bool
 	ReadRedirect      bool
+	// image transforms need to be signed with it, if set
+	transformSecret string
+	// cache of the transformed images, nil if disabled
+	imageVariants *images.VariantCache
+	// compaction write rate limit, unlimited if 0
+	compactionBytePerSecond int64
 }
 
 func NewVolumeServer(adminMux, publicMux *http.ServeMux, ip string,
@@ -31,39 +45,62 @@ func NewVolumeServer(adminMux, publicMux *http.ServeMux, ip string,
 	masterNode string, pulseSeconds int,
 	dataCenter string, rack string,
 	whiteList []string,
+	credentials *security.CredentialStore,
 	fixJpgOrientation bool,
-	readRedirect bool) *VolumeServer {
+	readRedirect bool,
+	transformSecret string,
+	imageCacheCollection string, imageCacheMB int, imageCacheMaxAgeHours int,
+	compactionMBPerSecond int,
+	readMmap bool, readCacheMB int) *VolumeServer {
 	vs := &VolumeServer{
-		pulseSeconds:      pulseSeconds,
-		dataCenter:        dataCenter,
-		rack:              rack,
-		needleMapKind:     needleMapKind,
-		FixJpgOrientation: fixJpgOrientation,
-		ReadRedirect:      readRedirect,
+		pulseSeconds:            pulseSeconds,
+		dataCenter:              dataCenter,
+		rack:                    rack,
+		needleMapKind:           needleMapKind,
+		FixJpgOrientation:       fixJpgOrientation,
+		ReadRedirect:            readRedirect,
+		transformSecret:         transformSecret,
+		compactionBytePerSecond: int64(compactionMBPerSecond) * 1024 * 1024,
 	}
 	vs.SetMasterNode(masterNode)
 	vs.store = storage.NewStore(port, ip, publicUrl, folders, maxCounts, vs.needleMapKind)
+	vs.store.SetReadOptions(readMmap, readCacheMB)
 
 	vs.guard = security.NewGuard(whiteList, "")
+	vs.guard.Credentials = credentials
+
+	if imageCacheCollection != "" {
+		var err error
+		variants := &variantStorage{vs: vs, collection: imageCacheCollection}
+		if vs.imageVariants, err = images.NewVariantCache(filepath.Join(folders[0], "image_variants"), variants,
+			int64(imageCacheMB)*1024*1024, time.Duration(imageCacheMaxAgeHours)*time.Hour); err != nil {
+			glog.Fatalf("load image variant cache: %v", err)
+		}
+	}
 
 	adminMux.HandleFunc("/ui/index.html", vs.uiStatusHandler)
 	adminMux.HandleFunc("/status", vs.guard.WhiteList(vs.statusHandler))
-	adminMux.HandleFunc("/admin/assign_volume", vs.guard.WhiteList(vs.assignVolumeHandler))
-	adminMux.HandleFunc("/admin/vacuum/check", vs.guard.WhiteList(vs.vacuumVolumeCheckHandler))
-	adminMux.HandleFunc("/admin/vacuum/compact", vs.guard.WhiteList(vs.vacuumVolumeCompactHandler))
-	adminMux.HandleFunc("/admin/vacuum/commit", vs.guard.WhiteList(vs.vacuumVolumeCommitHandler))
-	adminMux.HandleFunc("/admin/vacuum/cleanup", vs.guard.WhiteList(vs.vacuumVolumeCleanupHandler))
-	adminMux.HandleFunc("/admin/delete_collection", vs.guard.WhiteList(vs.deleteCollectionHandler))
-	adminMux.HandleFunc("/admin/sync/status", vs.guard.WhiteList(vs.getVolumeSyncStatusHandler))
-	adminMux.HandleFunc("/admin/sync/index", vs.guard.WhiteList(vs.getVolumeIndexContentHandler))
-	adminMux.HandleFunc("/admin/sync/data", vs.guard.WhiteList(vs.getVolumeDataContentHandler))
-	adminMux.HandleFunc("/admin/volume/mount", vs.guard.WhiteList(vs.getVolumeMountHandler))
-	adminMux.HandleFunc("/admin/volume/unmount", vs.guard.WhiteList(vs.getVolumeUnmountHandler))
-	adminMux.HandleFunc("/admin/volume/delete", vs.guard.WhiteList(vs.getVolumeDeleteHandler))
+	adminMux.HandleFunc("/admin/assign_volume", vs.guard.Permit(security.PermissionAdmin, vs.assignVolumeHandler))
+	adminMux.HandleFunc("/admin/vacuum/check", vs.guard.Permit(security.PermissionAdmin, vs.vacuumVolumeCheckHandler))
+	adminMux.HandleFunc("/admin/vacuum/compact", vs.guard.Permit(security.PermissionAdmin, vs.vacuumVolumeCompactHandler))
+	adminMux.HandleFunc("/admin/vacuum/commit", vs.guard.Permit(security.PermissionAdmin, vs.vacuumVolumeCommitHandler))
+	adminMux.HandleFunc("/admin/vacuum/cleanup", vs.guard.Permit(security.PermissionAdmin, vs.vacuumVolumeCleanupHandler))
+	adminMux.HandleFunc("/admin/delete_collection", vs.guard.Permit(security.PermissionAdmin, vs.deleteCollectionHandler))
+	adminMux.HandleFunc("/admin/sync/status", vs.guard.Permit(security.PermissionAdmin, vs.getVolumeSyncStatusHandler))
+	adminMux.HandleFunc("/admin/sync/index", vs.guard.Permit(security.PermissionAdmin, vs.getVolumeIndexContentHandler))
+	adminMux.HandleFunc("/admin/sync/data", vs.guard.Permit(security.PermissionAdmin, vs.getVolumeDataContentHandler))
+	adminMux.HandleFunc("/admin/volume/file", vs.guard.Permit(security.PermissionAdmin, vs.getVolumeFileHandler))
+	adminMux.HandleFunc("/admin/volume/copy", vs.guard.Permit(security.PermissionAdmin, vs.volumeCopyHandler))
+	adminMux.HandleFunc("/admin/volume/mount", vs.guard.Permit(security.PermissionAdmin, vs.getVolumeMountHandler))
+	adminMux.HandleFunc("/admin/volume/unmount", vs.guard.Permit(security.PermissionAdmin, vs.getVolumeUnmountHandler))
+	adminMux.HandleFunc("/admin/volume/readonly", vs.guard.Permit(security.PermissionAdmin, vs.volumeReadOnlyHandler))
+	adminMux.HandleFunc("/admin/volume/sync", vs.guard.Permit(security.PermissionAdmin, vs.volumeSyncHandler))
+	adminMux.HandleFunc("/admin/volume/delete", vs.guard.Permit(security.PermissionAdmin, vs.getVolumeDeleteHandler))
 	adminMux.HandleFunc("/stats/counter", vs.guard.WhiteList(statsCounterHandler))
 	adminMux.HandleFunc("/stats/memory", vs.guard.WhiteList(statsMemoryHandler))
 	adminMux.HandleFunc("/stats/disk", vs.guard.WhiteList(vs.statsDiskHandler))
-	adminMux.HandleFunc("/delete", vs.guard.WhiteList(vs.batchDeleteHandler))
+	adminMux.HandleFunc("/metrics", vs.guard.WhiteList(statsMetricsHandler))
+	adminMux.HandleFunc("/delete", vs.batchDeleteHandler)
 	adminMux.HandleFunc("/", vs.privateStoreHandler)
 	if publicMux != adminMux {
 		// separated admin and public port
@@ -91,9 +128,82 @@ func (vs *VolumeServer) SetMasterNode(masterNode string) {
 func (vs *VolumeServer) Shutdown() {
 	glog.V(0).Infoln("Shutting down volume server...")
 	vs.store.Close()
+	if vs.imageVariants != nil {
+		vs.imageVariants.Close()
+	}
 	glog.V(0).Infoln("Shut down successfully!")
 }
 
+func (vs *VolumeServer) setReadSettings(readSecret security.Secret, privateCollections []string) {
+	vs.readLock.Lock()
+	defer vs.readLock.Unlock()
+	vs.readSecret = readSecret
+	vs.privateCollections = privateCollections
+}
+
+func (vs *VolumeServer) getReadSecret() security.Secret {
+	vs.readLock.RLock()
+	defer vs.readLock.RUnlock()
+	return vs.readSecret
+}
+
+// readProtection returns the read secret if reading the collection requires read tokens.
+func (vs *VolumeServer) readProtection(collection string) (readSecret security.Secret, isProtected bool) {
+	vs.readLock.RLock()
+	defer vs.readLock.RUnlock()
+	if vs.readSecret == "" {
+		return "", false
+	}
+	if len(vs.privateCollections) == 0 {
+		return vs.readSecret, true
+	}
+	for _, c := range vs.privateCollections {
+		if c == collection {
+			return vs.readSecret, true
+		}
+	}
+	return "", false
+}
+
+// checkRead lets the request read the file if it has a valid read token,
+// or else the read permission on the collection if credentials are required.
+func (vs *VolumeServer) checkRead(r *http.Request, fileId string, collection string) error {
+	if readSecret, isProtected := vs.readProtection(collection); isProtected {
+		err := vs.guard.CheckReadJwt(r, readSecret, fileId)
+		if err == nil || vs.guard.Credentials == nil {
+			return err
+		}
+	}
+	if vs.guard.Credentials == nil {
+		return nil
+	}
+	return vs.guard.CheckPermission(r, security.PermissionRead, collection, "")
+}
+
+// permit wraps f to check the permission on the collection
+// of the volume in the request path.
+func (vs *VolumeServer) permit(permission security.Permission, f func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vid, _, _, _, _ := parseURLPath(r.URL.Path)
+		if err := vs.guard.CheckPermission(r, permission, vs.volumeCollection(vid), ""); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f(w, r)
+	}
+}
+
+func (vs *VolumeServer) volumeCollection(vid string) string {
+	volumeId, err := storage.NewVolumeId(vid)
+	if err != nil {
+		return ""
+	}
+	if v := vs.store.GetVolume(volumeId); v != nil {
+		return v.Collection
+	}
+	return ""
+}
+
 func (vs *VolumeServer) jwt(fileId string) security.EncodedJwt {
 	return security.GenJwt(vs.guard.SecretKey, fileId)
 }
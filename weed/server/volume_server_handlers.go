@@ -2,8 +2,11 @@ package weed_server
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 )
 
 /*
@@ -19,9 +22,20 @@ security settings:
 2. secured by white list
 3. secured by JWT(Json Web Token)
 
+Writes and deletes on the admin port are checked against the white list,
+and against the credentials if configured.
+
 */
 
 func (vs *VolumeServer) privateStoreHandler(w http.ResponseWriter, r *http.Request) {
+	requestType := stats.RequestType(r.Method)
+	stats.VolumeServerRequestCounter.WithLabelValues(requestType).Inc()
+	start := time.Now()
+	defer func() {
+		stats.VolumeServerRequestHistogram.WithLabelValues(requestType).Observe(time.Since(start).Seconds())
+	}()
+	r, span := tracing.StartServerSpan(r, "volumeServer."+requestType)
+	defer span.End()
 	switch r.Method {
 	case "GET":
 		stats.ReadRequest()
@@ -31,17 +45,25 @@ func (vs *VolumeServer) privateStoreHandler(w http.ResponseWriter, r *http.Reque
 		vs.GetOrHeadHandler(w, r)
 	case "DELETE":
 		stats.DeleteRequest()
-		vs.guard.WhiteList(vs.DeleteHandler)(w, r)
+		vs.permit(security.PermissionDelete, vs.DeleteHandler)(w, r)
 	case "PUT":
 		stats.WriteRequest()
-		vs.guard.WhiteList(vs.PostHandler)(w, r)
+		vs.permit(security.PermissionWrite, vs.PostHandler)(w, r)
 	case "POST":
 		stats.WriteRequest()
-		vs.guard.WhiteList(vs.PostHandler)(w, r)
+		vs.permit(security.PermissionWrite, vs.PostHandler)(w, r)
 	}
 }
 
 func (vs *VolumeServer) publicReadOnlyHandler(w http.ResponseWriter, r *http.Request) {
+	requestType := stats.RequestType(r.Method)
+	stats.VolumeServerRequestCounter.WithLabelValues(requestType).Inc()
+	start := time.Now()
+	defer func() {
+		stats.VolumeServerRequestHistogram.WithLabelValues(requestType).Observe(time.Since(start).Seconds())
+	}()
+	r, span := tracing.StartServerSpan(r, "volumeServer."+requestType)
+	defer span.End()
 	switch r.Method {
 	case "GET":
 		stats.ReadRequest()
@@ -25,7 +25,7 @@ func (vs *VolumeServer) assignVolumeHandler(w http.ResponseWriter, r *http.Reque
 	if r.FormValue("preallocate") != "" {
 		preallocate, err = strconv.ParseInt(r.FormValue("preallocate"), 10, 64)
 		if err != nil {
-			glog.V(0).Infoln("ignoring invalid int64 value for preallocate = %v", r.FormValue("preallocate"))
+			glog.V(0).Infof("ignoring invalid int64 value for preallocate = %v", r.FormValue("preallocate"))
 		}
 	}
 	err = vs.store.AddVolume(
@@ -35,13 +35,14 @@ func (vs *VolumeServer) assignVolumeHandler(w http.ResponseWriter, r *http.Reque
 		r.FormValue("replication"),
 		r.FormValue("ttl"),
 		preallocate,
+		r.FormValue("dedup") == "true",
 	)
 	if err == nil {
 		writeJsonQuiet(w, r, http.StatusAccepted, map[string]string{"error": ""})
 	} else {
 		writeJsonError(w, r, http.StatusNotAcceptable, err)
 	}
-	glog.V(2).Infoln("assign volume = %s, collection = %s , replication = %s, error = %v",
+	glog.V(2).Infof("assign volume = %s, collection = %s , replication = %s, error = %v",
 		r.FormValue("volume"), r.FormValue("collection"), r.FormValue("replication"), err)
 }
 
@@ -106,6 +107,55 @@ func (vs *VolumeServer) getVolumeDeleteHandler(w http.ResponseWriter, r *http.Re
 		writeJsonError(w, r, http.StatusNotFound, err)
 		return
 	}
-	vs.store.DeleteVolume(vid)
+	if err = vs.store.DeleteVolume(vid); err != nil {
+		writeJsonError(w, r, http.StatusInternalServerError, err)
+		return
+	}
 	writeJsonQuiet(w, r, http.StatusOK, "Volume deleted")
 }
+
+func (vs *VolumeServer) volumeReadOnlyHandler(w http.ResponseWriter, r *http.Request) {
+	vid, err := vs.getVolumeId("volume", r)
+	if err != nil {
+		writeJsonError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	readOnly, err := strconv.ParseBool(r.FormValue("readonly"))
+	if err != nil {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("invalid readonly %q", r.FormValue("readonly")))
+		return
+	}
+	if err = vs.store.MarkVolumeReadOnly(vid, readOnly); err != nil {
+		writeJsonError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	glog.V(0).Infof("marked volume %d read-only: %v", vid, readOnly)
+	writeJsonQuiet(w, r, http.StatusOK, "Volume marked")
+}
+
+func (vs *VolumeServer) volumeSyncHandler(w http.ResponseWriter, r *http.Request) {
+	vid, err := vs.getVolumeId("volume", r)
+	if err != nil {
+		writeJsonError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err = vs.store.SynchronizeVolume(vid, r.FormValue("source")); err != nil {
+		writeJsonError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJsonQuiet(w, r, http.StatusOK, "Volume synchronized")
+}
+
+func (vs *VolumeServer) volumeCopyHandler(w http.ResponseWriter, r *http.Request) {
+	vid, err := vs.getVolumeId("volume", r)
+	if err != nil {
+		writeJsonError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err = vs.store.CopyVolume(vid, r.FormValue("source")); err != nil {
+		writeJsonError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	glog.V(0).Infof("copied volume %d from %s", vid, r.FormValue("source"))
+	writeJsonQuiet(w, r, http.StatusOK, "Volume copied")
+}
@@ -20,7 +20,6 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/images"
 	"github.com/chrislusf/seaweedfs/weed/operation"
-	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
@@ -69,6 +68,9 @@ func (vs *VolumeServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request)
 			if c := r.FormValue("collection"); c != "" {
 				arg.Set("collection", c)
 			}
+			if jwt := r.FormValue("jwt"); jwt != "" {
+				arg.Set("jwt", jwt)
+			}
 			u.RawQuery = arg.Encode()
 			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
 
@@ -78,6 +80,12 @@ func (vs *VolumeServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
+	if v := vs.store.GetVolume(volumeId); v != nil {
+		if err := vs.checkRead(r, vid+","+fid, v.Collection); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
 	cookie := n.Cookie
 	count, e := vs.store.ReadVolumeNeedle(volumeId, n)
 	glog.V(4).Infoln("read bytes", count, "error", e)
@@ -160,53 +168,49 @@ func (vs *VolumeServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	if ext == ".png" || ext == ".jpg" || ext == ".jpeg" || ext == ".gif" {
-		width, height, rotate := 0, 0, 0
-		if r.FormValue("w") != "" {
-			width, _ = strconv.Atoi(r.FormValue("w"))
-		}
-		if r.FormValue("h") != "" {
-			height, _ = strconv.Atoi(r.FormValue("h"))
-		}
-		if r.FormValue("r") != "" {
-			rotate, _ = strconv.Atoi(r.FormValue("r"))
+	if images.FormatOfExt(ext) != nil {
+		t, err := images.ParseTransform(r.URL.Query())
+		if err != nil {
+			writeJsonError(w, r, http.StatusBadRequest, err)
+			return
 		}
-
-		//缓存
-		if r.Header.Get("exist") != "1" {
-			if r.FormValue("w") != "" || r.FormValue("h") != "" {
-				var mode string
-				switch r.FormValue("f") {
-				case "2":
-					mode = "fit"
-				case "1":
-					mode = "fill"
-				}
-				n.Data, _, _ = images.Resized(ext, n.Data, width, height, mode)
+		if t != nil {
+			if vs.transformSecret == "" && !t.IsBasic() {
+				writeJsonError(w, r, http.StatusForbidden, fmt.Errorf("image transform %s needs a transform secret on the volume server", t))
+				return
 			}
-			if r.FormValue("r") != "" {
-				n.Data = images.Rotate(ext, n.Data, rotate)
+			if vs.transformSecret != "" && !t.Verify(vs.transformSecret, r.FormValue("sig")) {
+				writeJsonError(w, r, http.StatusForbidden, fmt.Errorf("image transform %s is not signed", t))
+				return
 			}
-			reqUrl := r.Header.Get("path") + "?"
-			var reqQuery string
-			if r.FormValue("w") != "" {
-				reqQuery += "&w=" + r.FormValue("w")
+			if w.Header().Get("Content-Encoding") == "gzip" {
+				if n.Data, err = operation.UnGzipData(n.Data); err != nil {
+					glog.V(0).Infoln("ungzip error:", err, r.URL.Path)
+				}
+				w.Header().Del("Content-Encoding")
 			}
-			if r.FormValue("h") != "" {
-				reqQuery += "&h=" + r.FormValue("h")
+			format := t.OutputFormat(ext, r.Header.Get("Accept"))
+			if t.Format == images.FormatAuto {
+				w.Header().Add("Vary", "Accept")
 			}
-			if r.FormValue("r") != "" {
-				reqQuery += "&r=" + r.FormValue("r")
+			generate := func() ([]byte, string, error) {
+				data, err := t.Apply(ext, n.Data, format)
+				return data, format.Mime, err
 			}
-			if r.FormValue("w") != "" && r.FormValue("h") != "" && r.FormValue("f") != "" {
-				reqQuery += "&f=" + r.FormValue("f")
+			var data []byte
+			if vs.imageVariants != nil {
+				data, mtype, err = vs.imageVariants.Get(imageOriginal(volumeId, n), etag, images.VariantOf(t, format), generate)
+			} else {
+				data, mtype, err = generate()
 			}
-			reqQuery = strings.TrimLeft(reqQuery, "&")
-			reqUrl += reqQuery
-			jwt := security.GetJwt(r)
-			_, err = operation.Upload("http://"+r.Host+reqUrl, filename, bytes.NewReader(n.Data), false, "image/jpeg", nil, jwt)
 			if err != nil {
-				glog.V(0).Infoln(err)
+				glog.V(0).Infof("transform %s with %s: %v", r.URL.Path, t, err)
+				writeJsonError(w, r, http.StatusUnprocessableEntity, err)
+				return
+			}
+			n.Data = data
+			if images.FormatOfExt(ext) != format {
+				filename = strings.TrimSuffix(filename, path.Ext(filename)) + format.Ext
 			}
 		}
 	}
@@ -217,7 +221,7 @@ func (vs *VolumeServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request)
 		md5Ctx.Write(n.Data)
 		cipherStr := md5Ctx.Sum(nil)
 		fileInfo := map[string]interface{}{
-			"url": r.Header.Get("path"),
+			"url": string(n.Name),
 			"md5": hex.EncodeToString(cipherStr),
 		}
 		fmt.Fprintf(w, "%v", (&JsonEncode{fileInfo, "success", 200}).ReturnJson())
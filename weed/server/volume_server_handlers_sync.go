@@ -2,7 +2,10 @@ package weed_server
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strconv"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/storage"
@@ -50,21 +53,43 @@ func (vs *VolumeServer) getVolumeDataContentHandler(w http.ResponseWriter, r *ht
 	}
 	offset := uint32(util.ParseUint64(r.FormValue("offset"), 0))
 	size := uint32(util.ParseUint64(r.FormValue("size"), 0))
-	content, err := storage.ReadNeedleBlob(v.DataFile(), int64(offset)*storage.NeedlePaddingSize, size)
+	id := util.ParseUint64(r.FormValue("id"), 0)
+	content, err := v.ReadNeedleBlobOf(id, offset, size)
 	if err != nil {
-		writeJsonError(w, r, http.StatusInternalServerError, err)
+		writeJsonError(w, r, http.StatusNotFound, err)
 		return
 	}
 
-	id := util.ParseUint64(r.FormValue("id"), 0)
-	n := new(storage.Needle)
-	n.ParseNeedleHeader(content)
-	if id != n.Id {
-		writeJsonError(w, r, http.StatusNotFound, fmt.Errorf("Expected file entry id %d, but found %d", id, n.Id))
+	w.Write(content)
+}
+
+// getVolumeFileHandler streams the first size bytes of the .dat or .idx file of a volume.
+func (vs *VolumeServer) getVolumeFileHandler(w http.ResponseWriter, r *http.Request) {
+	v, err := vs.getVolume("volume", r)
+	if v == nil {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("Not Found volume: %v", err))
 		return
 	}
-
-	w.Write(content)
+	ext := r.FormValue("ext")
+	if ext != ".dat" && ext != ".idx" && ext != ".ddx" {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("Unknown volume file extension %q", ext))
+		return
+	}
+	if int(v.SuperBlock.CompactRevision) != util.ParseInt(r.FormValue("revision"), 0) {
+		writeJsonError(w, r, http.StatusExpectationFailed, fmt.Errorf("Requested Volume Revision is %s, but current revision is %d", r.FormValue("revision"), v.SuperBlock.CompactRevision))
+		return
+	}
+	file, err := os.Open(v.FileName() + ext)
+	if err != nil {
+		writeJsonError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+	size := int64(util.ParseUint64(r.FormValue("size"), 0))
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	if _, err = io.CopyN(w, file, size); err != nil {
+		glog.V(0).Infof("send volume %d%s: %v", v.Id, ext, err)
+	}
 }
 
 func (vs *VolumeServer) getVolumeId(volumeParameterName string, r *http.Request) (storage.VolumeId, error) {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 	"github.com/chrislusf/seaweedfs/weed/topology"
 )
@@ -37,6 +38,8 @@ func (vs *VolumeServer) PostHandler(w http.ResponseWriter, r *http.Request) {
 	if errorStatus != "" {
 		httpStatus = http.StatusInternalServerError
 		ret.Error = errorStatus
+	} else {
+		vs.invalidateImageVariants(volumeId, needle)
 	}
 	if needle.HasName() {
 		ret.Name = string(needle.Name)
@@ -90,6 +93,7 @@ func (vs *VolumeServer) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	_, err := topology.ReplicatedDelete(vs.GetMasterNode(), vs.store, volumeId, n, r)
 
 	if err == nil {
+		vs.invalidateImageVariants(volumeId, n)
 		m := make(map[string]int64)
 		m["size"] = count
 		writeJsonQuiet(w, r, http.StatusAccepted, m)
@@ -112,6 +116,13 @@ func (vs *VolumeServer) batchDeleteHandler(w http.ResponseWriter, r *http.Reques
 				Error:  err.Error()})
 			continue
 		}
+		if err := vs.guard.CheckPermission(r, security.PermissionDelete, vs.volumeCollection(vid), ""); err != nil {
+			ret = append(ret, operation.DeleteResult{
+				Fid:    fid,
+				Status: http.StatusUnauthorized,
+				Error:  err.Error()})
+			continue
+		}
 		n := new(storage.Needle)
 		volumeId, _ := storage.NewVolumeId(vid)
 		n.ParsePath(id_cookie)
@@ -151,6 +162,7 @@ func (vs *VolumeServer) batchDeleteHandler(w http.ResponseWriter, r *http.Reques
 				Error:  err.Error()},
 			)
 		} else {
+			vs.invalidateImageVariants(volumeId, n)
 			ret = append(ret, operation.DeleteResult{
 				Fid:    fid,
 				Status: http.StatusAccepted,
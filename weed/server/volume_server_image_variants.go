@@ -0,0 +1,70 @@
+package weed_server
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/storage"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// variantStorage keeps the image variants as files of a cache collection.
+type variantStorage struct {
+	vs         *VolumeServer
+	collection string
+}
+
+func (s *variantStorage) Save(data []byte, mimeType string) (string, error) {
+	ret, err := operation.Assign(s.vs.GetMasterNode(), &operation.VolumeAssignRequest{
+		Count:      1,
+		Collection: s.collection,
+	})
+	if err != nil {
+		return "", err
+	}
+	if ret.Error != "" {
+		return "", fmt.Errorf("assign: %s", ret.Error)
+	}
+	filename := "variant"
+	if exts, _ := mime.ExtensionsByType(mimeType); len(exts) > 0 {
+		filename += exts[0]
+	}
+	_, err = operation.Upload("http://"+ret.Url+"/"+ret.Fid, filename, bytes.NewReader(data), false, mimeType, nil, s.vs.jwt(ret.Fid))
+	if err != nil {
+		return "", err
+	}
+	return ret.Fid, nil
+}
+
+func (s *variantStorage) Load(fileId string) ([]byte, error) {
+	fileUrl, err := operation.LookupFileId(s.vs.GetMasterNode(), fileId)
+	if err != nil {
+		return nil, err
+	}
+	if readSecret := s.vs.getReadSecret(); readSecret != "" {
+		fileUrl += "?jwt=" + string(security.GenReadJwtForFileId(readSecret, 60, fileId))
+	}
+	return util.Get(fileUrl)
+}
+
+func (s *variantStorage) Delete(fileIds []string) error {
+	_, err := operation.DeleteFiles(s.vs.GetMasterNode(), fileIds)
+	return err
+}
+
+// imageOriginal is the cache key of the variants of a file, without its cookie,
+// so overwriting the file with another cookie invalidates them too.
+func imageOriginal(volumeId storage.VolumeId, n *storage.Needle) string {
+	return fmt.Sprintf("%s,%x", volumeId.String(), n.Id)
+}
+
+// invalidateImageVariants deletes the cached variants of a written or deleted file.
+func (vs *VolumeServer) invalidateImageVariants(volumeId storage.VolumeId, n *storage.Needle) {
+	if vs.imageVariants == nil {
+		return
+	}
+	vs.imageVariants.Invalidate(imageOriginal(volumeId, n))
+}
@@ -0,0 +1,280 @@
+package stats
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Gather is the registry shared by the master, volume and filer servers.
+// "weed server" runs all of them in one process, so they share one registry
+// and are told apart by the metric subsystem.
+var Gather = prometheus.NewRegistry()
+
+var (
+	MasterRequestCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "request_total",
+			Help:      "Counter of master requests.",
+		}, []string{"type"})
+
+	MasterRequestHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "request_seconds",
+			Help:      "Bucketed histogram of master request processing time.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 24),
+		}, []string{"type"})
+
+	MasterReceivedHeartbeatCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "received_heartbeats",
+			Help:      "Counter of master received heartbeats.",
+		}, []string{"type"})
+
+	MasterRaftIsLeader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "is_leader",
+			Help:      "Whether this master is the raft leader.",
+		})
+
+	MasterDataNodeVolumeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "data_node_volumes",
+			Help:      "Number of volumes and free volume slots of each data node.",
+		}, []string{"dataCenter", "rack", "node", "type"})
+
+	MasterVacuumCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "vacuum_total",
+			Help:      "Counter of volume vacuum attempts by result.",
+		}, []string{"type"})
+
+	MasterExpiredVolumeCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "expired_volumes_total",
+			Help:      "Counter of expired ttl volumes deleted from all replicas.",
+		}, []string{"collection"})
+
+	MasterReclaimedBytesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "expired_volume_reclaimed_bytes",
+			Help:      "Counter of disk bytes freed by deleting expired ttl volumes, over all replicas.",
+		}, []string{"collection"})
+
+	VolumeServerRequestCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "request_total",
+			Help:      "Counter of volume server requests.",
+		}, []string{"type"})
+
+	VolumeServerRequestHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "request_seconds",
+			Help:      "Bucketed histogram of volume server request processing time.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 24),
+		}, []string{"type"})
+
+	VolumeServerVolumeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "volumes",
+			Help:      "Number of volumes or free volume slots.",
+		}, []string{"collection", "type"})
+
+	VolumeServerDiskSizeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "total_disk_size",
+			Help:      "Actual disk size used by volumes.",
+		}, []string{"collection", "type"})
+
+	VolumeServerVacuumingHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "vacuuming_seconds",
+			Help:      "Bucketed histogram of volume server vacuuming processing time.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 24),
+		}, []string{"type"})
+
+	VolumeServerNeedleCacheCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "needle_cache_total",
+			Help:      "Counter of needle cache hits, misses and evictions. The hit ratio is hit/(hit+miss).",
+		}, []string{"type"})
+
+	VolumeServerNeedleCacheSizeGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "needle_cache_bytes",
+			Help:      "Total size of the cached needles.",
+		})
+
+	FilerRequestCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "filer",
+			Name:      "request_total",
+			Help:      "Counter of filer requests.",
+		}, []string{"type"})
+
+	FilerRequestHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "filer",
+			Name:      "request_seconds",
+			Help:      "Bucketed histogram of filer request processing time.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 24),
+		}, []string{"type"})
+
+	FilerStoreCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "filerStore",
+			Name:      "request_total",
+			Help:      "Counter of filer store requests.",
+		}, []string{"store", "type"})
+
+	FilerStoreHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "filerStore",
+			Name:      "request_seconds",
+			Help:      "Bucketed histogram of filer store request processing time.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 24),
+		}, []string{"store", "type"})
+
+	NetBytesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "net",
+			Name:      "bytes_total",
+			Help:      "Counter of bytes received and sent over accepted connections.",
+		}, []string{"type"})
+
+	NetConnectionCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "net",
+			Name:      "connections_total",
+			Help:      "Counter of accepted connections.",
+		})
+)
+
+func init() {
+	Gather.MustRegister(MasterRequestCounter)
+	Gather.MustRegister(MasterRequestHistogram)
+	Gather.MustRegister(MasterReceivedHeartbeatCounter)
+	Gather.MustRegister(MasterRaftIsLeader)
+	Gather.MustRegister(MasterDataNodeVolumeGauge)
+	Gather.MustRegister(MasterVacuumCounter)
+	Gather.MustRegister(MasterExpiredVolumeCounter)
+	Gather.MustRegister(MasterReclaimedBytesCounter)
+
+	Gather.MustRegister(VolumeServerRequestCounter)
+	Gather.MustRegister(VolumeServerRequestHistogram)
+	Gather.MustRegister(VolumeServerVolumeGauge)
+	Gather.MustRegister(VolumeServerDiskSizeGauge)
+	Gather.MustRegister(VolumeServerVacuumingHistogram)
+	Gather.MustRegister(VolumeServerNeedleCacheCounter)
+	Gather.MustRegister(VolumeServerNeedleCacheSizeGauge)
+
+	Gather.MustRegister(FilerRequestCounter)
+	Gather.MustRegister(FilerRequestHistogram)
+	Gather.MustRegister(FilerStoreCounter)
+	Gather.MustRegister(FilerStoreHistogram)
+
+	Gather.MustRegister(NetBytesCounter)
+	Gather.MustRegister(NetConnectionCounter)
+
+	Gather.MustRegister(prometheus.NewGoCollector())
+	Gather.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+}
+
+// MetricsHandler serves all registered metrics in the Prometheus text format.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(Gather, promhttp.HandlerOpts{})
+}
+
+// RequestType is the request counter label of the http method, one of a fixed set,
+// to not create a label value for every method a client sends.
+func RequestType(method string) string {
+	switch method {
+	case "GET", "HEAD":
+		return "get"
+	case "POST":
+		return "post"
+	case "PUT":
+		return "put"
+	case "DELETE":
+		return "delete"
+	}
+	return "other"
+}
+
+// StartMetricsServer serves /metrics on its own port, for the servers whose
+// main port serves user paths. It is a no-op if the port is 0.
+func StartMetricsServer(ip string, port int) {
+	if port == 0 {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler())
+	address := fmt.Sprintf("%s:%d", ip, port)
+	glog.V(0).Infof("serving metrics at %s/metrics", address)
+	go func() {
+		if err := http.ListenAndServe(address, mux); err != nil {
+			glog.Fatalf("serve metrics at %s: %v", address, err)
+		}
+	}()
+}
+
+// StartPushingMetric periodically pushes all metrics to a Prometheus push gateway.
+// It is a no-op if the address is empty or the interval is not positive.
+func StartPushingMetric(name, instance string, addr string, intervalSeconds int) {
+	if addr == "" || intervalSeconds <= 0 {
+		return
+	}
+	glog.V(0).Infof("%s pushes metrics to %s every %d seconds", name, addr, intervalSeconds)
+	go loopPushMetrics(name, instance, addr, intervalSeconds)
+}
+
+func loopPushMetrics(name, instance string, addr string, intervalSeconds int) {
+	pusher := push.New(addr, name).Gatherer(Gather).Grouping("instance", instance)
+	for {
+		if err := pusher.Push(); err != nil {
+			glog.V(0).Infof("could not push metrics to prometheus push gateway %s: %v", addr, err)
+		}
+		time.Sleep(time.Duration(intervalSeconds) * time.Second)
+	}
+}
@@ -57,6 +57,7 @@ func NewServerStats() *ServerStats {
 }
 
 func ConnectionOpen() {
+	NetConnectionCounter.Inc()
 	Chan.Connections <- NewTimedValue(time.Now(), 1)
 }
 func ConnectionClose() {
@@ -81,9 +82,11 @@ func DeleteRequest() {
 	Chan.DeleteRequests <- NewTimedValue(time.Now(), 1)
 }
 func BytesIn(val int64) {
+	NetBytesCounter.WithLabelValues("in").Add(float64(val))
 	Chan.BytesIn <- NewTimedValue(time.Now(), val)
 }
 func BytesOut(val int64) {
+	NetBytesCounter.WithLabelValues("out").Add(float64(val))
 	Chan.BytesOut <- NewTimedValue(time.Now(), val)
 }
 
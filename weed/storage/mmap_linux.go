@@ -0,0 +1,22 @@
+// +build linux
+
+package storage
+
+import (
+	"os"
+	"syscall"
+)
+
+func mmapFile(file *os.File, size int64) ([]byte, error) {
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	// needles are read at random, so reading ahead only evicts other pages
+	syscall.Madvise(data, syscall.MADV_RANDOM)
+	return data, nil
+}
+
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,16 @@
+// +build !linux
+
+package storage
+
+import (
+	"errors"
+	"os"
+)
+
+func mmapFile(file *os.File, size int64) ([]byte, error) {
+	return nil, errors.New("mmap is not supported on this platform")
+}
+
+func munmapFile(data []byte) error {
+	return nil
+}
@@ -1,7 +1,11 @@
 package storage
 
 import (
+	"container/list"
 	"os"
+	"sync"
+
+	"github.com/chrislusf/seaweedfs/weed/stats"
 )
 
 func getBytesForFileBlock(r *os.File, offset int64, readSize int) (dataSlice []byte, err error) {
@@ -9,3 +13,78 @@ func getBytesForFileBlock(r *os.File, offset int64, readSize int) (dataSlice []b
 	_, err = r.ReadAt(dataSlice, offset)
 	return dataSlice, err
 }
+
+// needleCacheKey identifies a needle blob by where it is stored. A rewritten needle
+// moves to another offset and a compacted volume changes its revision, so the
+// cached blobs never need invalidation, only evicting when they are not read anymore.
+type needleCacheKey struct {
+	v        *Volume
+	revision uint16
+	id       uint64
+	offset   uint32
+}
+
+type needleCacheEntry struct {
+	key  needleCacheKey
+	blob []byte
+}
+
+// needleCache keeps the most recently read needle blobs, up to a total size.
+// A nil needleCache caches nothing.
+type needleCache struct {
+	sync.Mutex
+	capacity int64
+	size     int64
+	entries  *list.List // the most recently read first
+	items    map[needleCacheKey]*list.Element
+}
+
+func newNeedleCache(capacity int64) *needleCache {
+	if capacity <= 0 {
+		return nil
+	}
+	return &needleCache{
+		capacity: capacity,
+		entries:  list.New(),
+		items:    make(map[needleCacheKey]*list.Element),
+	}
+}
+
+func (c *needleCache) get(key needleCacheKey) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.Lock()
+	defer c.Unlock()
+	e, found := c.items[key]
+	if !found {
+		stats.VolumeServerNeedleCacheCounter.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+	stats.VolumeServerNeedleCacheCounter.WithLabelValues("hit").Inc()
+	c.entries.MoveToFront(e)
+	return e.Value.(*needleCacheEntry).blob, true
+}
+
+func (c *needleCache) set(key needleCacheKey, blob []byte) {
+	// a large needle would evict many hot small ones
+	if c == nil || int64(len(blob)) > c.capacity/16 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if _, found := c.items[key]; found {
+		return
+	}
+	c.items[key] = c.entries.PushFront(&needleCacheEntry{key: key, blob: blob})
+	c.size += int64(len(blob))
+	for c.size > c.capacity {
+		e := c.entries.Back()
+		entry := e.Value.(*needleCacheEntry)
+		c.entries.Remove(e)
+		delete(c.items, entry.key)
+		c.size -= int64(len(entry.blob))
+		stats.VolumeServerNeedleCacheCounter.WithLabelValues("evict").Inc()
+	}
+	stats.VolumeServerNeedleCacheSizeGauge.Set(float64(c.size))
+}
@@ -17,6 +17,8 @@ const (
 	NeedleMapLevelDb
 	NeedleMapBoltDb
 	NeedleMapBtree
+	NeedleMapSorted           // read-only volumes only, others are loaded in memory
+	NeedleMapSortedCompressed // as NeedleMapSorted, with delta binary packed entries
 )
 
 const (
@@ -0,0 +1,391 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/chrislusf/seaweedfs/weed/compress"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+/*
+SortedNeedleMap looks up the needles of a read-only volume in a .sdx file,
+the live entries of the .idx file sorted by key, so it keeps almost nothing in memory.
+
+The .sdx file starts with a header of the entry count, the map metrics, and
+the position of the block directory. The entries follow as 16-byte rows of
+key, offset and size, searched in place. Compressed, the entries are grouped
+into blocks of up to 128, each holding its keys relative to the first key,
+its offsets and its sizes as delta binary packed columns, and only the
+directory of the first key and position of each block is kept in memory.
+*/
+type SortedNeedleMap struct {
+	dbFileName string
+	db         *os.File
+	compressed bool
+	count      int
+	blocks     []sortedIndexBlock
+	baseNeedleMapper
+}
+
+type sortedIndexBlock struct {
+	firstKey uint64
+	offset   int64
+}
+
+const (
+	sortedIndexMagic      = "SDX1"
+	sortedIndexCompressed = uint32(1)
+	sortedIndexHeaderSize = 4 + 4 + 8*8
+	sortedIndexBlockSize  = 128
+)
+
+func NewSortedNeedleMap(dbFileName string, indexFile *os.File, compressed bool) (m *SortedNeedleMap, err error) {
+	m = &SortedNeedleMap{dbFileName: dbFileName}
+	m.indexFile = indexFile
+	if !isSortedIndexFresh(dbFileName, indexFile, compressed) {
+		glog.V(1).Infof("Start to Generate %s from %s", dbFileName, indexFile.Name())
+		if err = generateSortedIndexFile(dbFileName, indexFile, compressed); err != nil {
+			return nil, err
+		}
+		glog.V(1).Infof("Finished Generating %s from %s", dbFileName, indexFile.Name())
+	}
+	glog.V(1).Infof("Opening %s...", dbFileName)
+	if m.db, err = os.Open(dbFileName); err != nil {
+		return nil, err
+	}
+	if err = m.readHeader(); err != nil {
+		m.db.Close()
+		return nil, fmt.Errorf("cannot read %s: %v", dbFileName, err)
+	}
+	return m, nil
+}
+
+func isSortedIndexFresh(dbFileName string, indexFile *os.File, compressed bool) bool {
+	dbFile, err := os.Open(dbFileName)
+	if err != nil {
+		return false
+	}
+	defer dbFile.Close()
+	dbStat, dbStatErr := dbFile.Stat()
+	indexStat, indexStatErr := indexFile.Stat()
+	if dbStatErr != nil || indexStatErr != nil {
+		glog.V(0).Infof("Can not stat file: %v and %v", dbStatErr, indexStatErr)
+		return false
+	}
+	header := make([]byte, sortedIndexHeaderSize)
+	if _, err = io.ReadFull(dbFile, header); err != nil || string(header[0:4]) != sortedIndexMagic {
+		return false
+	}
+	if (util.BytesToUint32(header[4:8])&sortedIndexCompressed != 0) != compressed {
+		return false
+	}
+	return dbStat.ModTime().After(indexStat.ModTime())
+}
+
+// generateSortedIndexFile sorts the live entries of the .idx file through a btree,
+// so only the conversion holds the whole index in memory.
+func generateSortedIndexFile(dbFileName string, indexFile *os.File, compressed bool) error {
+	nm, err := LoadBtreeNeedleMap(indexFile)
+	if err != nil {
+		return err
+	}
+	tmpFileName := dbFileName + ".tmp"
+	f, err := os.OpenFile(tmpFileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFileName)
+
+	header := make([]byte, sortedIndexHeaderSize)
+	copy(header[0:4], sortedIndexMagic)
+	if compressed {
+		util.Uint32toBytes(header[4:8], sortedIndexCompressed)
+	}
+	util.Uint64toBytes(header[16:24], uint64(nm.FileCounter))
+	util.Uint64toBytes(header[24:32], uint64(nm.DeletionCounter))
+	util.Uint64toBytes(header[32:40], nm.FileByteCounter)
+	util.Uint64toBytes(header[40:48], nm.DeletionByteCounter)
+	util.Uint64toBytes(header[48:56], nm.MaximumFileKey)
+
+	// the header is rewritten once the entries are counted
+	w := bufio.NewWriter(f)
+	if _, err = w.Write(header); err != nil {
+		f.Close()
+		return err
+	}
+	position := int64(sortedIndexHeaderSize)
+	var count int
+	var block []needle.NeedleValue
+	var blocks []sortedIndexBlock
+	flush := func() error {
+		data, err := encodeSortedIndexBlock(block)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(data); err != nil {
+			return err
+		}
+		blocks = append(blocks, sortedIndexBlock{firstKey: uint64(block[0].Key), offset: position})
+		position += int64(len(data))
+		block = block[:0]
+		return nil
+	}
+	entry := make([]byte, NeedleIndexSize)
+	err = nm.m.Visit(func(nv needle.NeedleValue) error {
+		if nv.Offset == 0 || nv.Size == TombstoneFileSize {
+			return nil
+		}
+		count++
+		if !compressed {
+			util.Uint64toBytes(entry[0:8], uint64(nv.Key))
+			util.Uint32toBytes(entry[8:12], nv.Offset)
+			util.Uint32toBytes(entry[12:16], nv.Size)
+			_, err := w.Write(entry)
+			return err
+		}
+		// the keys of a block are packed relative to its first key
+		if len(block) > 0 && (len(block) == sortedIndexBlockSize || uint64(nv.Key-block[0].Key) > math.MaxInt32) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		block = append(block, nv)
+		return nil
+	})
+	if err == nil && len(block) > 0 {
+		err = flush()
+	}
+	if err == nil && compressed {
+		for _, b := range blocks {
+			util.Uint64toBytes(entry[0:8], b.firstKey)
+			util.Uint64toBytes(entry[8:16], uint64(b.offset))
+			if _, err = w.Write(entry); err != nil {
+				break
+			}
+		}
+		util.Uint64toBytes(header[56:64], uint64(position))
+		util.Uint64toBytes(header[64:72], uint64(len(blocks)))
+	}
+	util.Uint64toBytes(header[8:16], uint64(count))
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		_, err = f.WriteAt(header, 0)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("cannot write %s: %v", tmpFileName, err)
+	}
+	return os.Rename(tmpFileName, dbFileName)
+}
+
+func (m *SortedNeedleMap) readHeader() error {
+	header := make([]byte, sortedIndexHeaderSize)
+	if _, err := m.db.ReadAt(header, 0); err != nil {
+		return err
+	}
+	if string(header[0:4]) != sortedIndexMagic {
+		return fmt.Errorf("unknown format %q", header[0:4])
+	}
+	m.compressed = util.BytesToUint32(header[4:8])&sortedIndexCompressed != 0
+	m.count = int(util.BytesToUint64(header[8:16]))
+	m.FileCounter = int(util.BytesToUint64(header[16:24]))
+	m.DeletionCounter = int(util.BytesToUint64(header[24:32]))
+	m.FileByteCounter = util.BytesToUint64(header[32:40])
+	m.DeletionByteCounter = util.BytesToUint64(header[40:48])
+	m.MaximumFileKey = util.BytesToUint64(header[48:56])
+	if !m.compressed {
+		return nil
+	}
+	directoryOffset := int64(util.BytesToUint64(header[56:64]))
+	directory := make([]byte, NeedleIndexSize*util.BytesToUint64(header[64:72]))
+	if _, err := m.db.ReadAt(directory, directoryOffset); err != nil {
+		return err
+	}
+	m.blocks = make([]sortedIndexBlock, 0, len(directory)/NeedleIndexSize+1)
+	for i := 0; i < len(directory); i += NeedleIndexSize {
+		m.blocks = append(m.blocks, sortedIndexBlock{
+			firstKey: util.BytesToUint64(directory[i : i+8]),
+			offset:   int64(util.BytesToUint64(directory[i+8 : i+16])),
+		})
+	}
+	// the directory ends the last block
+	m.blocks = append(m.blocks, sortedIndexBlock{firstKey: math.MaxUint64, offset: directoryOffset})
+	return nil
+}
+
+func (m *SortedNeedleMap) Get(key uint64) (element *needle.NeedleValue, ok bool) {
+	var err error
+	if m.compressed {
+		element, err = m.getCompressed(key)
+	} else {
+		element, err = m.getSorted(key)
+	}
+	if err != nil {
+		glog.V(0).Infof("cannot read %s: %v", m.dbFileName, err)
+	}
+	return element, element != nil
+}
+
+func (m *SortedNeedleMap) getSorted(key uint64) (*needle.NeedleValue, error) {
+	entry := make([]byte, NeedleIndexSize)
+	var err error
+	i := sort.Search(m.count, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		_, err = m.db.ReadAt(entry, sortedIndexHeaderSize+int64(i)*NeedleIndexSize)
+		return util.BytesToUint64(entry[0:8]) >= key
+	})
+	if err != nil || i >= m.count {
+		return nil, err
+	}
+	if _, err = m.db.ReadAt(entry, sortedIndexHeaderSize+int64(i)*NeedleIndexSize); err != nil {
+		return nil, err
+	}
+	k, offset, size := idxFileEntry(entry)
+	if k != key {
+		return nil, nil
+	}
+	return &needle.NeedleValue{Key: needle.Key(k), Offset: offset, Size: size}, nil
+}
+
+func (m *SortedNeedleMap) getCompressed(key uint64) (*needle.NeedleValue, error) {
+	// the last block whose first key is not after the key, skipping the directory end
+	b := sort.Search(len(m.blocks)-1, func(i int) bool {
+		return m.blocks[i].firstKey > key
+	}) - 1
+	if b < 0 {
+		return nil, nil
+	}
+	data := make([]byte, m.blocks[b+1].offset-m.blocks[b].offset)
+	if _, err := m.db.ReadAt(data, m.blocks[b].offset); err != nil {
+		return nil, err
+	}
+	values, err := decodeSortedIndexBlock(data, m.blocks[b].firstKey)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(values), func(i int) bool {
+		return uint64(values[i].Key) >= key
+	})
+	if i >= len(values) || uint64(values[i].Key) != key {
+		return nil, nil
+	}
+	return &values[i], nil
+}
+
+// encodeSortedIndexBlock writes the entry count, then the key, offset and size columns,
+// each as its compressed length followed by the compressed values.
+func encodeSortedIndexBlock(block []needle.NeedleValue) ([]byte, error) {
+	var buf bytes.Buffer
+	word := make([]byte, 4)
+	util.Uint32toBytes(word, uint32(len(block)))
+	buf.Write(word)
+	for column := 0; column < 3; column++ {
+		// the packing works on whole blocks, so the columns are padded with their last value
+		values := make([]int32, sortedIndexBlockSize)
+		for i := range values {
+			nv := block[len(block)-1]
+			if i < len(block) {
+				nv = block[i]
+			}
+			switch column {
+			case 0:
+				values[i] = int32(nv.Key - block[0].Key)
+			case 1:
+				values[i] = int32(nv.Offset)
+			case 2:
+				values[i] = int32(nv.Size)
+			}
+		}
+		packed, err := compress.Compress32(values)
+		if err != nil {
+			return nil, err
+		}
+		util.Uint32toBytes(word, uint32(len(packed)))
+		buf.Write(word)
+		for _, v := range packed {
+			util.Uint32toBytes(word, uint32(v))
+			buf.Write(word)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSortedIndexBlock(data []byte, firstKey uint64) ([]needle.NeedleValue, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("block of %d bytes", len(data))
+	}
+	count := int(util.BytesToUint32(data[0:4]))
+	if count > sortedIndexBlockSize {
+		return nil, fmt.Errorf("block of %d entries", count)
+	}
+	data = data[4:]
+	values := make([]needle.NeedleValue, count)
+	buffer := make([]int32, sortedIndexBlockSize*2)
+	for column := 0; column < 3; column++ {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("missing column %d", column)
+		}
+		length := int(util.BytesToUint32(data[0:4]))
+		if len(data) < 4+4*length {
+			return nil, fmt.Errorf("column %d of %d bytes, expected %d", column, len(data)-4, 4*length)
+		}
+		packed := make([]int32, length)
+		for i := range packed {
+			packed[i] = int32(util.BytesToUint32(data[4+4*i : 8+4*i]))
+		}
+		data = data[4+4*length:]
+		unpacked, err := compress.Uncompress32(packed, buffer)
+		if err != nil {
+			return nil, err
+		}
+		if len(unpacked) < count {
+			return nil, fmt.Errorf("column %d of %d entries, expected %d", column, len(unpacked), count)
+		}
+		for i := range values {
+			switch column {
+			case 0:
+				values[i].Key = needle.Key(firstKey + uint64(uint32(unpacked[i])))
+			case 1:
+				values[i].Offset = uint32(unpacked[i])
+			case 2:
+				values[i].Size = uint32(unpacked[i])
+			}
+		}
+	}
+	return values, nil
+}
+
+func (m *SortedNeedleMap) Put(key uint64, offset uint32, size uint32) error {
+	return fmt.Errorf("%s is read-only", m.dbFileName)
+}
+
+func (m *SortedNeedleMap) Delete(key uint64, offset uint32) error {
+	return fmt.Errorf("%s is read-only", m.dbFileName)
+}
+
+func (m *SortedNeedleMap) Close() {
+	m.db.Close()
+}
+
+func (m *SortedNeedleMap) Destroy() error {
+	m.Close()
+	os.Remove(m.indexFile.Name())
+	return os.Remove(m.dbFileName)
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+)
+
+func TestSortedNeedleMap(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "sorted")
+	defer os.RemoveAll(dir)
+
+	indexFile, err := os.OpenFile(filepath.Join(dir, "1.idx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer indexFile.Close()
+	nm := NewCompactNeedleMap(indexFile)
+	expected := make(map[uint64]uint32)
+	for i := 0; i < 1000; i++ {
+		key := uint64(rand.Int63n(2000)) + 1
+		if i%10 == 0 {
+			// far apart keys start new blocks
+			key += uint64(i) << 40
+		}
+		nm.Put(key, uint32(i+1), uint32(rand.Int31n(1<<20)))
+		expected[key] = uint32(i + 1)
+		if i%7 == 0 {
+			nm.Delete(key, uint32(i+1))
+			delete(expected, key)
+		}
+	}
+
+	for _, compressed := range []bool{false, true} {
+		m, err := NewSortedNeedleMap(filepath.Join(dir, "1.sdx"), indexFile, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.count != len(expected) {
+			t.Errorf("compressed %v: %d entries, expected %d", compressed, m.count, len(expected))
+		}
+		for key, offset := range expected {
+			if nv, ok := m.Get(key); !ok || nv.Offset != offset {
+				t.Errorf("compressed %v: key %d at %v, expected offset %d", compressed, key, nv, offset)
+			}
+		}
+		for _, key := range []uint64{0, 2001, 1 << 62} {
+			if nv, ok := m.Get(key); ok {
+				t.Errorf("compressed %v: unexpected key %d at %v", compressed, key, nv)
+			}
+		}
+		if err = m.Put(1, 1, 1); err == nil {
+			t.Errorf("compressed %v: put into the read-only map", compressed)
+		}
+		m.Close()
+	}
+}
+
+func TestSortedNeedleMapLargeValues(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "sorted")
+	defer os.RemoveAll(dir)
+
+	indexFile, err := os.OpenFile(filepath.Join(dir, "1.idx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer indexFile.Close()
+	nm := NewCompactNeedleMap(indexFile)
+	// offsets and sizes past 1<<31 wrap to negative packed values, next to small ones
+	expected := make(map[uint64]needle.NeedleValue)
+	for i := 0; i < 300; i++ {
+		key := uint64(i + 1)
+		offset, size := uint32(1<<31)+uint32(i)*(1<<22), uint32(rand.Int31())
+		if i%3 == 0 {
+			offset, size = uint32(i+1), math.MaxUint32-uint32(i+1)
+		}
+		nm.Put(key, offset, size)
+		expected[key] = needle.NeedleValue{Key: needle.Key(key), Offset: offset, Size: size}
+	}
+
+	for _, compressed := range []bool{false, true} {
+		m, err := NewSortedNeedleMap(filepath.Join(dir, "1.sdx"), indexFile, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range expected {
+			if nv, ok := m.Get(key); !ok || *nv != value {
+				t.Errorf("compressed %v: key %d at %v, expected %v", compressed, key, nv, value)
+			}
+		}
+		m.Close()
+	}
+}
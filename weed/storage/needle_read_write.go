@@ -160,6 +160,12 @@ func (n *Needle) ReadData(r *os.File, offset int64, size uint32, version Version
 	if err != nil {
 		return err
 	}
+	return n.ReadBlob(bytes, size, version)
+}
+
+// ReadBlob fills in the needle from its blob read by ReadNeedleBlob.
+// The needle data shares the blob.
+func (n *Needle) ReadBlob(bytes []byte, size uint32, version Version) (err error) {
 	n.ParseNeedleHeader(bytes)
 	if n.Size != size {
 		return fmt.Errorf("File Entry Not Found. Needle %d Memory %d", n.Size, size)
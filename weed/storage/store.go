@@ -5,14 +5,12 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/operation"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
-)
-
-const (
-	MAX_TTL_VOLUME_REMOVAL_DELAY = 10 // 10 minutes
+	"github.com/chrislusf/seaweedfs/weed/stats"
 )
 
 type MasterNodes struct {
@@ -78,6 +76,10 @@ type Store struct {
 	VolumeSizeLimit uint64 //read from the master
 	Client          master_pb.Seaweed_SendHeartbeatClient
 	NeedleMapType   NeedleMapType
+	heartbeatLock   sync.Mutex
+	sentVolumes     map[VolumeId]master_pb.VolumeInformationMessage // volumes known by the master
+	mmapReads       bool                                            // read-only volumes are read through mmap
+	needleCache     *needleCache                                    // nil if not caching the read needles
 }
 
 func (s *Store) String() (str string) {
@@ -95,7 +97,7 @@ func NewStore(port int, ip, publicUrl string, dirnames []string, maxVolumeCounts
 	}
 	return
 }
-func (s *Store) AddVolume(volumeListString string, collection string, needleMapKind NeedleMapType, replicaPlacement string, ttlString string, preallocate int64) error {
+func (s *Store) AddVolume(volumeListString string, collection string, needleMapKind NeedleMapType, replicaPlacement string, ttlString string, preallocate int64, dedup bool) error {
 	rt, e := NewReplicaPlacementFromString(replicaPlacement)
 	if e != nil {
 		return e
@@ -111,7 +113,7 @@ func (s *Store) AddVolume(volumeListString string, collection string, needleMapK
 			if err != nil {
 				return fmt.Errorf("Volume Id %s is not a valid unsigned integer!", id_string)
 			}
-			e = s.addVolume(VolumeId(id), collection, needleMapKind, rt, ttl, preallocate)
+			e = s.addVolume(VolumeId(id), collection, needleMapKind, rt, ttl, preallocate, dedup)
 		} else {
 			pair := strings.Split(range_string, "-")
 			start, start_err := strconv.ParseUint(pair[0], 10, 64)
@@ -123,7 +125,7 @@ func (s *Store) AddVolume(volumeListString string, collection string, needleMapK
 				return fmt.Errorf("Volume End Id %s is not a valid unsigned integer!", pair[1])
 			}
 			for id := start; id <= end; id++ {
-				if err := s.addVolume(VolumeId(id), collection, needleMapKind, rt, ttl, preallocate); err != nil {
+				if err := s.addVolume(VolumeId(id), collection, needleMapKind, rt, ttl, preallocate, dedup); err != nil {
 					e = err
 				}
 			}
@@ -160,14 +162,22 @@ func (s *Store) findFreeLocation() (ret *DiskLocation) {
 	}
 	return ret
 }
-func (s *Store) addVolume(vid VolumeId, collection string, needleMapKind NeedleMapType, replicaPlacement *ReplicaPlacement, ttl *TTL, preallocate int64) error {
+
+// addVolume creates the volume, deduplicating its needles if dedup is set and it has no ttl.
+func (s *Store) addVolume(vid VolumeId, collection string, needleMapKind NeedleMapType, replicaPlacement *ReplicaPlacement, ttl *TTL, preallocate int64, dedup bool) error {
 	if s.findVolume(vid) != nil {
 		return fmt.Errorf("Volume Id %d already exists!", vid)
 	}
 	if location := s.findFreeLocation(); location != nil {
-		glog.V(0).Infof("In dir %s adds volume:%v collection:%s replicaPlacement:%v ttl:%v",
-			location.Directory, vid, collection, replicaPlacement, ttl)
+		glog.V(0).Infof("In dir %s adds volume:%v collection:%s replicaPlacement:%v ttl:%v dedup:%v",
+			location.Directory, vid, collection, replicaPlacement, ttl, dedup)
 		if volume, err := NewVolume(location.Directory, collection, vid, needleMapKind, replicaPlacement, ttl, preallocate); err == nil {
+			if dedup && ttl.String() == "" {
+				if err = volume.enableDedup(); err != nil {
+					volume.Destroy()
+					return err
+				}
+			}
 			location.SetVolume(vid, volume)
 			return nil
 		} else {
@@ -212,6 +222,9 @@ func (s *Store) CollectHeartbeat() *master_pb.Heartbeat {
 	var volumeMessages []*master_pb.VolumeInformationMessage
 	maxVolumeCount := 0
 	var maxFileKey uint64
+	collectionVolumeCount := make(map[string]int)
+	collectionVolumeSize := make(map[string]uint64)
+	collectionDeletedSize := make(map[string]uint64)
 	for _, location := range s.Locations {
 		maxVolumeCount = maxVolumeCount + location.MaxVolumeCount
 		location.Lock()
@@ -219,32 +232,39 @@ func (s *Store) CollectHeartbeat() *master_pb.Heartbeat {
 			if maxFileKey < v.nm.MaxFileKey() {
 				maxFileKey = v.nm.MaxFileKey()
 			}
-			if !v.expired(s.VolumeSizeLimit) {
-				volumeMessage := &master_pb.VolumeInformationMessage{
-					Id:               uint32(k),
-					Size:             uint64(v.Size()),
-					Collection:       v.Collection,
-					FileCount:        uint64(v.nm.FileCount()),
-					DeleteCount:      uint64(v.nm.DeletedCount()),
-					DeletedByteCount: v.nm.DeletedSize(),
-					ReadOnly:         v.readOnly,
-					ReplicaPlacement: uint32(v.ReplicaPlacement.Byte()),
-					Version:          uint32(v.Version()),
-					Ttl:              v.Ttl.ToUint32(),
-				}
-				volumeMessages = append(volumeMessages, volumeMessage)
-			} else {
-				if v.exiredLongEnough(MAX_TTL_VOLUME_REMOVAL_DELAY) {
-					location.deleteVolumeById(v.Id)
-					glog.V(0).Infoln("volume", v.Id, "is deleted.")
-				} else {
-					glog.V(0).Infoln("volume", v.Id, "is expired.")
-				}
+			collectionVolumeCount[v.Collection]++
+			collectionVolumeSize[v.Collection] += uint64(v.Size())
+			collectionDeletedSize[v.Collection] += v.nm.DeletedSize()
+			// expired ttl volumes are deleted by the master on all replicas
+			volumeMessage := &master_pb.VolumeInformationMessage{
+				Id:               uint32(k),
+				Size:             uint64(v.Size()),
+				Collection:       v.Collection,
+				FileCount:        uint64(v.nm.FileCount()),
+				DeleteCount:      uint64(v.nm.DeletedCount()),
+				DeletedByteCount: v.nm.DeletedSize(),
+				ReadOnly:         v.readOnly,
+				ReplicaPlacement: uint32(v.ReplicaPlacement.Byte()),
+				Version:          uint32(v.Version()),
+				Ttl:              v.Ttl.ToUint32(),
+				ModifiedAtSecond: v.lastModifiedTime,
 			}
+			volumeMessages = append(volumeMessages, volumeMessage)
 		}
 		location.Unlock()
 	}
 
+	stats.VolumeServerVolumeGauge.Reset()
+	stats.VolumeServerDiskSizeGauge.Reset()
+	volumeCount := 0
+	for col, count := range collectionVolumeCount {
+		volumeCount += count
+		stats.VolumeServerVolumeGauge.WithLabelValues(col, "volume").Set(float64(count))
+		stats.VolumeServerDiskSizeGauge.WithLabelValues(col, "normal").Set(float64(collectionVolumeSize[col]))
+		stats.VolumeServerDiskSizeGauge.WithLabelValues(col, "deleted_bytes").Set(float64(collectionDeletedSize[col]))
+	}
+	stats.VolumeServerVolumeGauge.WithLabelValues("", "free").Set(float64(maxVolumeCount - volumeCount))
+
 	return &master_pb.Heartbeat{
 		Ip:             s.Ip,
 		Port:           uint32(s.Port),
@@ -257,6 +277,46 @@ func (s *Store) CollectHeartbeat() *master_pb.Heartbeat {
 	}
 
 }
+
+// SendHeartbeat sends all the volumes to the master if full is true, and
+// otherwise only the volumes changed or deleted since the last heartbeat.
+// A full heartbeat must be the first one sent on a new stream.
+func (s *Store) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatClient, full bool) error {
+	s.heartbeatLock.Lock()
+	defer s.heartbeatLock.Unlock()
+
+	heartbeat := s.CollectHeartbeat()
+	volumes := make(map[VolumeId]master_pb.VolumeInformationMessage, len(heartbeat.Volumes))
+	for _, v := range heartbeat.Volumes {
+		volumes[VolumeId(v.Id)] = *v
+	}
+	if !full && s.sentVolumes != nil {
+		heartbeat.IsDelta = true
+		heartbeat.Volumes, heartbeat.DeletedVolumeIds = volumeDelta(s.sentVolumes, volumes)
+	}
+	if err := stream.Send(heartbeat); err != nil {
+		// the master state is unknown, start over with a full heartbeat
+		s.sentVolumes = nil
+		return err
+	}
+	s.sentVolumes = volumes
+	return nil
+}
+
+func volumeDelta(sent, current map[VolumeId]master_pb.VolumeInformationMessage) (changed []*master_pb.VolumeInformationMessage, deletedIds []uint32) {
+	for id, v := range current {
+		if old, ok := sent[id]; !ok || old != v {
+			v := v
+			changed = append(changed, &v)
+		}
+	}
+	for id := range sent {
+		if _, ok := current[id]; !ok {
+			deletedIds = append(deletedIds, uint32(id))
+		}
+	}
+	return
+}
 func (s *Store) Close() {
 	for _, location := range s.Locations {
 		location.Close()
@@ -283,8 +343,8 @@ func (s *Store) Write(i VolumeId, n *Needle) (size uint32, err error) {
 }
 
 func (s *Store) updateMaster() {
-	if s.Client != nil {
-		if e := s.Client.Send(s.CollectHeartbeat()); e != nil {
+	if client := s.Client; client != nil {
+		if e := s.SendHeartbeat(client, false); e != nil {
 			glog.V(0).Infoln("error when reporting size:", e)
 		}
 	}
@@ -297,9 +357,32 @@ func (s *Store) Delete(i VolumeId, n *Needle) (uint32, error) {
 	return 0, nil
 }
 
+// SetReadOptions maps the read-only volumes in memory if mmapReads is set,
+// and caches the most recently read needles up to cacheSizeMB, if positive.
+func (s *Store) SetReadOptions(mmapReads bool, cacheSizeMB int) {
+	s.mmapReads = mmapReads
+	s.needleCache = newNeedleCache(int64(cacheSizeMB) * 1024 * 1024)
+	for _, location := range s.Locations {
+		location.RLock()
+		for _, v := range location.volumes {
+			s.mmapVolume(v)
+		}
+		location.RUnlock()
+	}
+}
+
+func (s *Store) mmapVolume(v *Volume) {
+	if !s.mmapReads || !v.readOnly {
+		return
+	}
+	if err := v.mmapDataFile(); err != nil {
+		glog.V(0).Infof("volume %d is read without mmap: %v", v.Id, err)
+	}
+}
+
 func (s *Store) ReadVolumeNeedle(i VolumeId, n *Needle) (int, error) {
 	if v := s.findVolume(i); v != nil {
-		return v.readNeedle(n)
+		return v.readNeedle(n, s.needleCache)
 	}
 	return 0, fmt.Errorf("Volume %d not found!", i)
 }
@@ -315,6 +398,9 @@ func (s *Store) HasVolume(i VolumeId) bool {
 func (s *Store) MountVolume(i VolumeId) error {
 	for _, location := range s.Locations {
 		if found := location.LoadVolume(i, s.NeedleMapType); found == true {
+			if v := s.findVolume(i); v != nil {
+				s.mmapVolume(v)
+			}
 			s.updateMaster()
 			return nil
 		}
@@ -334,6 +420,28 @@ func (s *Store) UnmountVolume(i VolumeId) error {
 	return fmt.Errorf("Volume %d not found on disk", i)
 }
 
+// MarkVolumeReadOnly stops or resumes the writes to the volume, and reports it to the master.
+func (s *Store) MarkVolumeReadOnly(i VolumeId, readOnly bool) error {
+	v := s.findVolume(i)
+	if v == nil {
+		return fmt.Errorf("Volume %d not found!", i)
+	}
+	if err := v.MarkReadOnly(readOnly); err != nil {
+		return err
+	}
+	s.updateMaster()
+	return nil
+}
+
+// SynchronizeVolume catches up the volume with its copy on the source volume server.
+func (s *Store) SynchronizeVolume(i VolumeId, source string) error {
+	v := s.findVolume(i)
+	if v == nil {
+		return fmt.Errorf("Volume %d not found!", i)
+	}
+	return v.Synchronize(source)
+}
+
 func (s *Store) DeleteVolume(i VolumeId) error {
 	for _, location := range s.Locations {
 		if error := location.deleteVolumeById(i); error == nil {
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func TestVolumeDelta(t *testing.T) {
+	sent := map[VolumeId]master_pb.VolumeInformationMessage{
+		1: {Id: 1, Size: 100},
+		2: {Id: 2, Size: 200},
+		3: {Id: 3, Size: 300},
+	}
+	current := map[VolumeId]master_pb.VolumeInformationMessage{
+		1: {Id: 1, Size: 100},
+		2: {Id: 2, Size: 250},
+		4: {Id: 4},
+	}
+
+	changed, deletedIds := volumeDelta(sent, current)
+	changedIds := make(map[uint32]bool)
+	for _, v := range changed {
+		changedIds[v.Id] = true
+	}
+	if len(changed) != 2 || !changedIds[2] || !changedIds[4] {
+		t.Errorf("changed volumes %v, expected 2 and 4", changed)
+	}
+	if len(deletedIds) != 1 || deletedIds[0] != 3 {
+		t.Errorf("deleted volumes %v, expected 3", deletedIds)
+	}
+
+	if changed, deletedIds = volumeDelta(current, current); len(changed) != 0 || len(deletedIds) != 0 {
+		t.Errorf("unexpected delta %v %v of unchanged volumes", changed, deletedIds)
+	}
+}
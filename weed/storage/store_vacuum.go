@@ -22,13 +22,13 @@ func (s *Store) CheckCompactVolume(volumeIdString string, garbageThresholdString
 	}
 	return fmt.Errorf("volume id %d is not found during check compact", vid), false
 }
-func (s *Store) CompactVolume(volumeIdString string, preallocate int64) error {
+func (s *Store) CompactVolume(volumeIdString string, preallocate int64, compactionBytePerSecond int64) error {
 	vid, err := NewVolumeId(volumeIdString)
 	if err != nil {
 		return fmt.Errorf("Volume Id %s is not a valid unsigned integer", volumeIdString)
 	}
 	if v := s.findVolume(vid); v != nil {
-		return v.Compact(preallocate)
+		return v.Compact(preallocate, compactionBytePerSecond)
 	}
 	return fmt.Errorf("volume id %d is not found during compact", vid)
 }
@@ -5,7 +5,6 @@ import (
 	"os"
 	"path"
 	"sync"
-	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 )
@@ -18,10 +17,15 @@ type Volume struct {
 	nm            NeedleMapper
 	needleMapKind NeedleMapType
 	readOnly      bool
+	// set if the volume is read-only by MarkReadOnly, not by its file permissions
+	markedReadOnly bool
+	dedup          *dedupIndex // nil unless the needles are deduplicated
 
 	SuperBlock
 
 	dataFileAccessLock sync.Mutex
+	dataMap            []byte // the data file mapped in memory, only for read-only volumes
+	dataMapLock        sync.RWMutex
 	lastModifiedTime   uint64 //unix time in seconds
 
 	lastCompactIndexOffset uint64
@@ -68,7 +72,11 @@ func (v *Volume) Size() int64 {
 func (v *Volume) Close() {
 	v.dataFileAccessLock.Lock()
 	defer v.dataFileAccessLock.Unlock()
+	v.munmapDataFile()
 	v.nm.Close()
+	if v.dedup != nil {
+		v.dedup.Close()
+	}
 	_ = v.dataFile.Close()
 }
 
@@ -79,43 +87,3 @@ func (v *Volume) NeedToReplicate() bool {
 func (v *Volume) ContentSize() uint64 {
 	return v.nm.ContentSize()
 }
-
-// volume is expired if modified time + volume ttl < now
-// except when volume is empty
-// or when the volume does not have a ttl
-// or when volumeSizeLimit is 0 when server just starts
-func (v *Volume) expired(volumeSizeLimit uint64) bool {
-	if volumeSizeLimit == 0 {
-		//skip if we don't know size limit
-		return false
-	}
-	if v.ContentSize() == 0 {
-		return false
-	}
-	if v.Ttl == nil || v.Ttl.Minutes() == 0 {
-		return false
-	}
-	glog.V(1).Infof("now:%v lastModified:%v", time.Now().Unix(), v.lastModifiedTime)
-	livedMinutes := (time.Now().Unix() - int64(v.lastModifiedTime)) / 60
-	glog.V(1).Infof("ttl:%v lived:%v", v.Ttl, livedMinutes)
-	if int64(v.Ttl.Minutes()) < livedMinutes {
-		return true
-	}
-	return false
-}
-
-// wait either maxDelayMinutes or 10% of ttl minutes
-func (v *Volume) exiredLongEnough(maxDelayMinutes uint32) bool {
-	if v.Ttl == nil || v.Ttl.Minutes() == 0 {
-		return false
-	}
-	removalDelay := v.Ttl.Minutes() / 10
-	if removalDelay > maxDelayMinutes {
-		removalDelay = maxDelayMinutes
-	}
-
-	if uint64(v.Ttl.Minutes()+removalDelay)*60+v.lastModifiedTime < uint64(time.Now().Unix()) {
-		return true
-	}
-	return false
-}
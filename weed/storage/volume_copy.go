@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+)
+
+/*
+CopyVolume pulls a volume from another volume server in 2 steps:
+ 1. The .idx and .dat files, and the .ddx dedup index if any, are streamed up
+    to their sizes when the copy starts, into temporary files renamed to the
+    volume files when complete.
+ 2. The volume is loaded, and the writes arriving at the source during the
+    copy are caught up with Synchronize.
+*/
+func (s *Store) CopyVolume(vid VolumeId, source string) error {
+	if s.findVolume(vid) != nil {
+		return fmt.Errorf("Volume Id %d already exists!", vid)
+	}
+	location := s.findFreeLocation()
+	if location == nil {
+		return fmt.Errorf("No more free space left")
+	}
+
+	syncStatus, err := operation.GetVolumeSyncStatus(source, vid.String())
+	if err != nil {
+		return err
+	}
+	v := &Volume{dir: location.Directory, Collection: syncStatus.Collection, Id: vid}
+	fileName := v.FileName()
+	glog.V(0).Infof("copying volume %d from %s to %s, %d bytes", vid, source, fileName, syncStatus.TailOffset)
+
+	if err = copyVolumeFile(source, vid, ".idx", fileName+".cpx", syncStatus.CompactRevision, syncStatus.IdxFileSize); err != nil {
+		return err
+	}
+	if err = copyVolumeFile(source, vid, ".dat", fileName+".cpd", syncStatus.CompactRevision, syncStatus.TailOffset); err != nil {
+		os.Remove(fileName + ".cpx")
+		return err
+	}
+	if syncStatus.DedupFileSize > 0 {
+		if err = copyVolumeFile(source, vid, ".ddx", fileName+".ddx", syncStatus.CompactRevision, syncStatus.DedupFileSize); err != nil {
+			os.Remove(fileName + ".cpx")
+			os.Remove(fileName + ".cpd")
+			return err
+		}
+	}
+	if err = os.Rename(fileName+".cpx", fileName+".idx"); err != nil {
+		os.Remove(fileName + ".cpx")
+		os.Remove(fileName + ".cpd")
+		os.Remove(fileName + ".ddx")
+		return err
+	}
+	if err = os.Rename(fileName+".cpd", fileName+".dat"); err != nil {
+		os.Remove(fileName + ".idx")
+		os.Remove(fileName + ".cpd")
+		os.Remove(fileName + ".ddx")
+		return err
+	}
+
+	if v, err = NewVolume(location.Directory, syncStatus.Collection, vid, s.NeedleMapType, nil, nil, 0); err != nil {
+		return fmt.Errorf("load copied volume %d: %v", vid, err)
+	}
+	if err = v.Synchronize(source); err != nil {
+		v.Destroy()
+		return fmt.Errorf("catch up copied volume %d: %v", vid, err)
+	}
+	location.SetVolume(vid, v)
+	s.updateMaster()
+	return nil
+}
+
+func copyVolumeFile(source string, vid VolumeId, ext string, dst string, compactRevision uint16, size uint64) error {
+	file, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err = operation.DownloadVolumeFile(source, vid.String(), ext, compactRevision, size, file); err != nil {
+		file.Close()
+		os.Remove(dst)
+		return fmt.Errorf("copy volume %d%s from %s: %v", vid, ext, source, err)
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		os.Remove(dst)
+		return err
+	}
+	return file.Close()
+}
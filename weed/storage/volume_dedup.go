@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+const (
+	dedupOpAdd     = byte(1) // the needle holds the content of the hash
+	dedupOpRef     = byte(2) // the needle shares the content of the hash
+	dedupOpRelease = byte(3) // the needle is deleted or overwritten
+
+	dedupRecordSize = 1 + 8 + 4 + 8 + sha256.Size
+)
+
+// dedupNeedle is a needle with deduplicated content, holding it or sharing it.
+type dedupNeedle struct {
+	hash         [sha256.Size]byte
+	cookie       uint32
+	lastModified uint64
+}
+
+// dedupContent is the content shared by needles, held in the data file by its owner.
+type dedupContent struct {
+	owner   uint64
+	needles map[uint64]bool
+}
+
+/*
+dedupIndex deduplicates the needles of a volume by the SHA-256 hashes of their
+data, name, mime type, pairs and flags, see dedupHash.
+
+A needle written with the content of an existing needle is not written, and
+shares the content held by the existing needle instead, keeping its own id,
+cookie and last modified time. Deleting or overwriting a needle releases it once.
+If it holds content still shared by others, the content is written again as one
+of them, which holds it from then on.
+All changes are appended to the .ddx file, replayed when the volume is loaded.
+The replicas dedup on their own, but every file id reads the same on all of them.
+*/
+type dedupIndex struct {
+	sync.Mutex
+	file     *os.File
+	needles  map[uint64]*dedupNeedle
+	contents map[[sha256.Size]byte]*dedupContent
+}
+
+func loadDedupIndex(fileName string) (*dedupIndex, error) {
+	file, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open dedup index %s: %v", fileName, err)
+	}
+	d := &dedupIndex{
+		file:     file,
+		needles:  make(map[uint64]*dedupNeedle),
+		contents: make(map[[sha256.Size]byte]*dedupContent),
+	}
+	record := make([]byte, dedupRecordSize)
+	var offset int64
+	for {
+		if _, err = io.ReadFull(file, record); err != nil {
+			break
+		}
+		var hash [sha256.Size]byte
+		copy(hash[:], record[21:])
+		d.apply(record[0], util.BytesToUint64(record[1:9]), dedupNeedle{
+			hash:         hash,
+			cookie:       util.BytesToUint32(record[9:13]),
+			lastModified: util.BytesToUint64(record[13:21]),
+		})
+		offset += dedupRecordSize
+	}
+	if err != io.EOF {
+		// drop the partial record of an interrupted write
+		glog.V(0).Infof("truncating dedup index %s to %d: %v", fileName, offset, err)
+		if err = file.Truncate(offset); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if _, err = file.Seek(offset, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *dedupIndex) apply(op byte, id uint64, n dedupNeedle) {
+	if old := d.needles[id]; old != nil && (op == dedupOpRelease || old.hash != n.hash) {
+		delete(d.needles, id)
+		if c := d.contents[old.hash]; c != nil {
+			if delete(c.needles, id); len(c.needles) == 0 {
+				delete(d.contents, old.hash)
+			}
+		}
+	}
+	if op == dedupOpRelease {
+		return
+	}
+	d.needles[id] = &n
+	c := d.contents[n.hash]
+	if c == nil {
+		c = &dedupContent{owner: id, needles: make(map[uint64]bool)}
+		d.contents[n.hash] = c
+	}
+	c.needles[id] = true
+	if op == dedupOpAdd {
+		c.owner = id
+	}
+}
+
+func (d *dedupIndex) append(op byte, id uint64, n dedupNeedle) error {
+	record := make([]byte, dedupRecordSize)
+	record[0] = op
+	util.Uint64toBytes(record[1:9], id)
+	util.Uint32toBytes(record[9:13], n.cookie)
+	util.Uint64toBytes(record[13:21], n.lastModified)
+	copy(record[21:], n.hash[:])
+	if _, err := d.file.Write(record); err != nil {
+		return err
+	}
+	d.apply(op, id, n)
+	return nil
+}
+
+// find returns the needle holding the content.
+func (d *dedupIndex) find(hash [sha256.Size]byte) (owner uint64, found bool) {
+	d.Lock()
+	defer d.Unlock()
+	if c := d.contents[hash]; c != nil {
+		return c.owner, true
+	}
+	return 0, false
+}
+
+// get returns the deduplicated needle, and the needle holding its content.
+func (d *dedupIndex) get(id uint64) (n dedupNeedle, owner uint64, found bool) {
+	d.Lock()
+	defer d.Unlock()
+	if dn := d.needles[id]; dn != nil {
+		return *dn, d.contents[dn.hash].owner, true
+	}
+	return n, 0, false
+}
+
+// successor returns another needle sharing the content held by the owner.
+func (d *dedupIndex) successor(hash [sha256.Size]byte, owner uint64) (id uint64, n dedupNeedle, found bool) {
+	d.Lock()
+	defer d.Unlock()
+	if c := d.contents[hash]; c != nil {
+		for id := range c.needles {
+			if id != owner {
+				return id, *d.needles[id], true
+			}
+		}
+	}
+	return 0, n, false
+}
+
+// sharing returns the needles sharing the content of others, with their owners.
+func (d *dedupIndex) sharing() map[uint64]uint64 {
+	d.Lock()
+	defer d.Unlock()
+	owners := make(map[uint64]uint64)
+	for id, n := range d.needles {
+		if owner := d.contents[n.hash].owner; owner != id {
+			owners[id] = owner
+		}
+	}
+	return owners
+}
+
+func (d *dedupIndex) add(id uint64, n dedupNeedle) error {
+	d.Lock()
+	defer d.Unlock()
+	return d.append(dedupOpAdd, id, n)
+}
+
+func (d *dedupIndex) ref(id uint64, n dedupNeedle) error {
+	d.Lock()
+	defer d.Unlock()
+	return d.append(dedupOpRef, id, n)
+}
+
+// release forgets the needle, if deduplicated. Releasing it again does nothing.
+func (d *dedupIndex) release(id uint64) error {
+	d.Lock()
+	defer d.Unlock()
+	if n := d.needles[id]; n != nil {
+		return d.append(dedupOpRelease, id, *n)
+	}
+	return nil
+}
+
+func (d *dedupIndex) size() uint64 {
+	d.Lock()
+	defer d.Unlock()
+	if stat, err := d.file.Stat(); err == nil {
+		return uint64(stat.Size())
+	}
+	return 0
+}
+
+func (d *dedupIndex) Close() {
+	d.file.Close()
+}
+
+// dedupHash hashes what needles of the same content share: the data, name,
+// mime type, pairs and flags. The id, cookie and last modified time are their own.
+func dedupHash(n *Needle) (hash [sha256.Size]byte) {
+	h := sha256.New()
+	sizes := make([]byte, 8)
+	sizes[0], sizes[1], sizes[2] = n.Flags, byte(len(n.Name)), byte(len(n.Mime))
+	util.Uint16toBytes(sizes[3:5], uint16(len(n.Pairs)))
+	h.Write(sizes)
+	h.Write(n.Name)
+	h.Write(n.Mime)
+	h.Write(n.Pairs)
+	h.Write(n.Data)
+	copy(hash[:], h.Sum(nil))
+	return
+}
+
+// enableDedup starts deduplicating the needles written to the volume.
+func (v *Volume) enableDedup() (err error) {
+	v.dataFileAccessLock.Lock()
+	defer v.dataFileAccessLock.Unlock()
+	if v.dedup == nil {
+		v.dedup, err = loadDedupIndex(v.FileName() + ".ddx")
+	}
+	return
+}
+
+// IsDedup tells whether the volume deduplicates its needles.
+func (v *Volume) IsDedup() bool {
+	return v.dedup != nil
+}
+
+// writeDuplicate releases what the needle held or shared before, and shares the
+// existing needle with the same content instead of writing the needle, if any.
+// It requires serialized access in the same volume.
+func (v *Volume) writeDuplicate(n *Needle, hash [sha256.Size]byte) (size uint32, found bool, err error) {
+	if err = v.releaseDedup(n.Id); err != nil {
+		return 0, true, err
+	}
+	owner, found := v.dedup.find(hash)
+	if !found {
+		return 0, false, nil
+	}
+	if _, ok := v.getLive(owner); !ok {
+		glog.V(0).Infof("needle %x holding the content of %x is missing", owner, n.Id)
+		return 0, false, nil
+	}
+	// the needle no longer holds its old content
+	if _, ok := v.getLive(n.Id); ok {
+		if err = v.deleteFromMap(&Needle{Id: n.Id, Cookie: n.Cookie}); err != nil {
+			return 0, true, err
+		}
+	}
+	if err = v.dedup.ref(n.Id, dedupNeedle{hash: hash, cookie: n.Cookie, lastModified: n.LastModified}); err != nil {
+		return 0, true, err
+	}
+	glog.V(4).Infof("needle %x shares the content of %x", n.Id, owner)
+	return uint32(len(n.Data)), true, nil
+}
+
+// releaseDedup releases the needle if deduplicated. If it holds content shared
+// by other needles, the content is written again as one of them first.
+// It requires serialized access in the same volume.
+func (v *Volume) releaseDedup(id uint64) error {
+	dn, owner, found := v.dedup.get(id)
+	if !found {
+		return nil
+	}
+	if owner == id {
+		if next, nextNeedle, shared := v.dedup.successor(dn.hash, id); shared {
+			if err := v.moveDedupContent(id, next, nextNeedle); err != nil {
+				return err
+			}
+		}
+	}
+	return v.dedup.release(id)
+}
+
+// moveDedupContent writes the content held by the owner again as the needle sharing it.
+func (v *Volume) moveDedupContent(owner uint64, id uint64, dn dedupNeedle) error {
+	nv, ok := v.getLive(owner)
+	if !ok {
+		return fmt.Errorf("needle %x holding shared content is missing", owner)
+	}
+	blob, err := v.readNeedleBlob(int64(nv.Offset)*NeedlePaddingSize, nv.Size)
+	if err != nil {
+		return err
+	}
+	n := new(Needle)
+	if err = n.ReadBlob(blob, nv.Size, v.Version()); err != nil {
+		return err
+	}
+	n.Id, n.Cookie, n.LastModified = id, dn.cookie, dn.lastModified
+	offset, err := v.appendNeedle(n)
+	if err != nil {
+		return err
+	}
+	if err = v.nm.Put(id, uint32(offset/NeedlePaddingSize), n.Size); err != nil {
+		return err
+	}
+	glog.V(4).Infof("needle %x holds the content of the released %x", id, owner)
+	return v.dedup.add(id, dn)
+}
+
+// getLive returns the needle map entry of the needle, unless it is missing or deleted.
+func (v *Volume) getLive(id uint64) (*needle.NeedleValue, bool) {
+	nv, ok := v.nm.Get(id)
+	if !ok || nv.Offset == 0 || nv.Size == 0 || nv.Size == TombstoneFileSize {
+		return nil, false
+	}
+	return nv, true
+}
+
+// visitSharedNeedles lists the needles sharing the content of others,
+// at the offset of the needles holding it.
+func (v *Volume) visitSharedNeedles(fn func(key uint64, offset, size uint32)) {
+	if v.dedup == nil {
+		return
+	}
+	for id, owner := range v.dedup.sharing() {
+		if nv, ok := v.getLive(owner); ok {
+			fn(id, nv.Offset, nv.Size)
+		}
+	}
+}
+
+// ReadNeedleBlobOf reads the blob of the needle at the offset, for synchronizing
+// the volume. A needle sharing the content at the offset gets a blob of its own.
+func (v *Volume) ReadNeedleBlobOf(id uint64, offset, size uint32) ([]byte, error) {
+	blob, err := ReadNeedleBlob(v.dataFile, int64(offset)*NeedlePaddingSize, size)
+	if err != nil {
+		return nil, err
+	}
+	n := new(Needle)
+	n.ParseNeedleHeader(blob)
+	if n.Id == id {
+		return blob, nil
+	}
+	var dn dedupNeedle
+	var owner uint64
+	found := false
+	if v.dedup != nil {
+		dn, owner, found = v.dedup.get(id)
+	}
+	if !found || owner != n.Id {
+		return nil, fmt.Errorf("Expected file entry id %d, but found %d", id, n.Id)
+	}
+	if err = n.ReadBlob(blob, size, v.Version()); err != nil {
+		return nil, err
+	}
+	n.Id, n.Cookie, n.LastModified = id, dn.cookie, dn.lastModified
+	var buf bytes.Buffer
+	if _, _, err = n.Append(&buf, v.Version()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestVolumeDedup(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "dedup")
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, &ReplicaPlacement{}, &TTL{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.enableDedup(); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(id uint64, cookie uint32, name, data string) {
+		n := &Needle{Id: id, Cookie: cookie, Name: []byte(name), Data: []byte(data)}
+		n.Checksum = NewCRC(n.Data)
+		if _, err := v.writeNeedle(n); err != nil {
+			t.Fatalf("write %d: %v", id, err)
+		}
+	}
+	read := func(id uint64, cookie uint32, data string) {
+		n := &Needle{Id: id}
+		if _, err := v.readNeedle(n, nil); err != nil {
+			t.Errorf("read %d: %v", id, err)
+		} else if n.Id != id || n.Cookie != cookie || string(n.Data) != data {
+			t.Errorf("read %d: got needle %d cookie %d data %q", id, n.Id, n.Cookie, n.Data)
+		}
+	}
+	write(1, 11, "a", "hello")
+	write(2, 22, "a", "world")
+	write(3, 33, "a", "hello")
+	write(4, 44, "b", "hello")
+	if _, ok := v.nm.Get(3); ok {
+		t.Errorf("needle 3 is written")
+	}
+	if _, ok := v.nm.Get(4); !ok {
+		t.Errorf("needle 4 of another name is deduplicated")
+	}
+	read(3, 33, "hello")
+	if content, _ := v.IndexFileContent(); len(content) != 4*16 {
+		t.Errorf("expected 4 index entries, got %d bytes", len(content))
+	}
+
+	// reload to replay the dedup index
+	v.Close()
+	if v, err = NewVolume(dir, "", 1, NeedleMapInMemory, nil, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	if !v.IsDedup() {
+		t.Fatalf("dedup is not reloaded")
+	}
+
+	// the content of a deleted owner stays with the needles sharing it
+	if _, err = v.deleteNeedle(&Needle{Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = v.readNeedle(&Needle{Id: 1}, nil); err == nil {
+		t.Errorf("needle 1 is not deleted")
+	}
+	read(3, 33, "hello")
+
+	// overwriting with shared content drops the old content
+	write(5, 55, "a", "world")
+	write(2, 23, "a", "other")
+	write(2, 24, "a", "world")
+	read(2, 24, "world")
+	read(5, 55, "world")
+
+	for i := 0; i < 2; i++ {
+		if _, err = v.deleteNeedle(&Needle{Id: 5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read(2, 24, "world")
+	if _, err = v.readNeedle(&Needle{Id: 5}, nil); err == nil {
+		t.Errorf("needle 5 is not deleted")
+	}
+}
@@ -35,6 +35,7 @@ func (v *Volume) load(alsoLoadIndex bool, createDatIfMissing bool, needleMapKind
 	} else {
 		if createDatIfMissing {
 			v.dataFile, e = createVolumeFile(fileName+".dat", preallocate)
+			v.lastModifiedTime = uint64(time.Now().Unix())
 		} else {
 			return fmt.Errorf("Volume Data file %s.dat does not exist.", fileName)
 		}
@@ -52,6 +53,14 @@ func (v *Volume) load(alsoLoadIndex bool, createDatIfMissing bool, needleMapKind
 		e = v.maybeWriteSuperBlock()
 	}
 	if e == nil && alsoLoadIndex {
+		if exists, _, _, _ := checkFile(fileName + ".ddx"); exists {
+			if v.dedup != nil {
+				v.dedup.Close()
+			}
+			if v.dedup, e = loadDedupIndex(fileName + ".ddx"); e != nil {
+				return e
+			}
+		}
 		var indexFile *os.File
 		if v.readOnly {
 			glog.V(1).Infoln("open to read file", fileName+".idx")
@@ -89,6 +98,18 @@ func (v *Volume) load(alsoLoadIndex bool, createDatIfMissing bool, needleMapKind
 			if v.nm, e = LoadBtreeNeedleMap(indexFile); e != nil {
 				glog.V(0).Infof("loading index %s to btree error: %v", fileName+".idx", e)
 			}
+		case NeedleMapSorted, NeedleMapSortedCompressed:
+			if !v.readOnly {
+				glog.V(0).Infoln("loading index", fileName+".idx", "of writable volume to memory")
+				if v.nm, e = LoadCompactNeedleMap(indexFile); e != nil {
+					glog.V(0).Infof("loading index %s to memory error: %v", fileName+".idx", e)
+				}
+				break
+			}
+			glog.V(0).Infoln("loading sorted index", fileName+".sdx")
+			if v.nm, e = NewSortedNeedleMap(fileName+".sdx", indexFile, needleMapKind == NeedleMapSortedCompressed); e != nil {
+				glog.V(0).Infof("loading sorted index %s error: %v", fileName+".sdx", e)
+			}
 		}
 	}
 
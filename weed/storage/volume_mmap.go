@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// mmapDataFile maps the data file of a read-only volume in memory, so the needles
+// are copied from the page cache without a syscall for each read.
+func (v *Volume) mmapDataFile() error {
+	if !v.readOnly {
+		return fmt.Errorf("%s is writable", v.dataFile.Name())
+	}
+	v.dataMapLock.Lock()
+	defer v.dataMapLock.Unlock()
+	if v.dataMap != nil {
+		return nil
+	}
+	size := v.Size()
+	if size == 0 {
+		return nil
+	}
+	data, err := mmapFile(v.dataFile, size)
+	if err != nil {
+		return fmt.Errorf("cannot mmap %s: %v", v.dataFile.Name(), err)
+	}
+	glog.V(0).Infof("mapped %d bytes of %s in memory", size, v.dataFile.Name())
+	v.dataMap = data
+	return nil
+}
+
+func (v *Volume) munmapDataFile() {
+	v.dataMapLock.Lock()
+	defer v.dataMapLock.Unlock()
+	if v.dataMap == nil {
+		return
+	}
+	if err := munmapFile(v.dataMap); err != nil {
+		glog.V(0).Infof("cannot munmap %s: %v", v.dataFile.Name(), err)
+	}
+	v.dataMap = nil
+}
+
+// readNeedleBlob reads the needle from the mapped data file if any, or from the data file.
+// The mapped bytes are copied, so they can be unmapped while the needle is still in use.
+func (v *Volume) readNeedleBlob(offset int64, size uint32) ([]byte, error) {
+	v.dataMapLock.RLock()
+	if readSize := getActualSize(size); v.dataMap != nil && offset+readSize <= int64(len(v.dataMap)) {
+		blob := make([]byte, readSize)
+		copy(blob, v.dataMap[offset:])
+		v.dataMapLock.RUnlock()
+		return blob, nil
+	}
+	v.dataMapLock.RUnlock()
+	return ReadNeedleBlob(v.dataFile, offset, size)
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestVolumeMmapAndNeedleCache(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "mmap")
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, &ReplicaPlacement{}, &TTL{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := map[uint64]string{1: "hello", 2: "world"}
+	for id, data := range contents {
+		n := &Needle{Id: id, Cookie: 1, Data: []byte(data)}
+		n.Checksum = NewCRC(n.Data)
+		if _, err = v.writeNeedle(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v.Close()
+
+	// a volume is read-only once its data file is
+	os.Chmod(v.FileName()+".dat", 0444)
+	if v, err = NewVolume(dir, "", 1, NeedleMapInMemory, nil, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	if err = v.mmapDataFile(); err != nil {
+		t.Skip(err)
+	}
+
+	cache := newNeedleCache(1024 * 1024)
+	for i := 0; i < 2; i++ {
+		for id, data := range contents {
+			n := &Needle{Id: id}
+			if _, err = v.readNeedle(n, cache); err != nil || string(n.Data) != data {
+				t.Errorf("read %d: %q %v", id, n.Data, err)
+			}
+		}
+	}
+	if _, err = v.readNeedle(&Needle{Id: 3}, cache); err == nil {
+		t.Errorf("read the needle 3 never written")
+	}
+	if len(cache.items) != 2 {
+		t.Errorf("%d needles cached, expected 2", len(cache.items))
+	}
+}
+
+func TestVolumeMmapAfterCompaction(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "mmap")
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, &ReplicaPlacement{}, &TTL{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	for id := uint64(1); id <= 2; id++ {
+		n := &Needle{Id: id, Cookie: 1, Data: []byte("hello")}
+		n.Checksum = NewCRC(n.Data)
+		if _, err = v.writeNeedle(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = v.deleteNeedle(&Needle{Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = v.MarkReadOnly(true); err != nil {
+		t.Fatal(err)
+	}
+	if err = v.mmapDataFile(); err != nil {
+		t.Skip(err)
+	}
+
+	if err = v.Compact(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = v.commitCompact(); err != nil {
+		t.Fatal(err)
+	}
+	if v.dataMap == nil || int64(len(v.dataMap)) != v.Size() {
+		t.Errorf("mapped %d bytes of the compacted %d bytes", len(v.dataMap), v.Size())
+	}
+	n := &Needle{Id: 2}
+	if _, err = v.readNeedle(n, nil); err != nil || string(n.Data) != "hello" {
+		t.Errorf("read 2: %q %v", n.Data, err)
+	}
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -35,7 +36,7 @@ func (v *Volume) isFileUnchanged(n *Needle) bool {
 
 // Destroy removes everything related to this volume
 func (v *Volume) Destroy() (err error) {
-	if v.readOnly {
+	if v.readOnly && !v.markedReadOnly {
 		err = fmt.Errorf("%s is read-only", v.dataFile.Name())
 		return
 	}
@@ -45,6 +46,9 @@ func (v *Volume) Destroy() (err error) {
 		return
 	}
 	err = v.nm.Destroy()
+	if v.dedup != nil {
+		os.Remove(v.FileName() + ".ddx")
+	}
 	return
 }
 
@@ -72,20 +76,22 @@ func (v *Volume) AppendBlob(b []byte) (offset int64, err error) {
 	return
 }
 
-func (v *Volume) writeNeedle(n *Needle) (size uint32, err error) {
-	glog.V(4).Infof("writing needle %s", NewFileIdFromNeedle(v.Id, n).String())
-	if v.readOnly {
-		err = fmt.Errorf("%s is read-only", v.dataFile.Name())
-		return
-	}
+// MarkReadOnly stops or resumes the writes and deletes of a writable volume.
+// The writes in progress are done when it returns.
+func (v *Volume) MarkReadOnly(readOnly bool) error {
 	v.dataFileAccessLock.Lock()
 	defer v.dataFileAccessLock.Unlock()
-	if v.isFileUnchanged(n) {
-		size = n.DataSize
-		glog.V(4).Infof("needle is unchanged!")
-		return
+	if v.readOnly && !v.markedReadOnly {
+		return fmt.Errorf("%s is read-only", v.dataFile.Name())
 	}
-	var offset int64
+	v.readOnly = readOnly
+	v.markedReadOnly = readOnly
+	return nil
+}
+
+// appendNeedle appends the needle at the aligned end of the data file.
+// It requires serialized access in the same volume.
+func (v *Volume) appendNeedle(n *Needle) (offset int64, err error) {
 	if offset, err = v.dataFile.Seek(0, 2); err != nil {
 		glog.V(0).Infof("failed to seek the end of file: %v", err)
 		return
@@ -100,12 +106,40 @@ func (v *Volume) writeNeedle(n *Needle) (size uint32, err error) {
 		}
 	}
 
-	if size, _, err = n.Append(v.dataFile, v.Version()); err != nil {
+	if _, _, err = n.Append(v.dataFile, v.Version()); err != nil {
 		if e := v.dataFile.Truncate(offset); e != nil {
 			err = fmt.Errorf("%s\ncannot truncate %s: %v", err, v.dataFile.Name(), e)
 		}
+	}
+	return
+}
+
+func (v *Volume) writeNeedle(n *Needle) (size uint32, err error) {
+	glog.V(4).Infof("writing needle %s", NewFileIdFromNeedle(v.Id, n).String())
+	v.dataFileAccessLock.Lock()
+	defer v.dataFileAccessLock.Unlock()
+	if v.readOnly {
+		err = fmt.Errorf("%s is read-only", v.dataFile.Name())
+		return
+	}
+	if v.isFileUnchanged(n) {
+		size = n.DataSize
+		glog.V(4).Infof("needle is unchanged!")
+		return
+	}
+	var hash [sha256.Size]byte
+	if v.dedup != nil {
+		hash = dedupHash(n)
+		var found bool
+		if size, found, err = v.writeDuplicate(n, hash); found {
+			return
+		}
+	}
+	var offset int64
+	if offset, err = v.appendNeedle(n); err != nil {
 		return
 	}
+	size = n.DataSize
 
 	nv, ok := v.nm.Get(n.Id)
 	if !ok || int64(nv.Offset)*NeedlePaddingSize < offset {
@@ -113,6 +147,11 @@ func (v *Volume) writeNeedle(n *Needle) (size uint32, err error) {
 			glog.V(4).Infof("failed to save in needle map %d: %v", n.Id, err)
 		}
 	}
+	if v.dedup != nil {
+		if err = v.dedup.add(n.Id, dedupNeedle{hash: hash, cookie: n.Cookie, lastModified: n.LastModified}); err != nil {
+			glog.V(0).Infof("failed to save in dedup index %d: %v", n.Id, err)
+		}
+	}
 	if v.lastModifiedTime < n.LastModified {
 		v.lastModifiedTime = n.LastModified
 	}
@@ -121,42 +160,82 @@ func (v *Volume) writeNeedle(n *Needle) (size uint32, err error) {
 
 func (v *Volume) deleteNeedle(n *Needle) (uint32, error) {
 	glog.V(4).Infof("delete needle %s", NewFileIdFromNeedle(v.Id, n).String())
+	v.dataFileAccessLock.Lock()
+	defer v.dataFileAccessLock.Unlock()
 	if v.readOnly {
 		return 0, fmt.Errorf("%s is read-only", v.dataFile.Name())
 	}
-	v.dataFileAccessLock.Lock()
-	defer v.dataFileAccessLock.Unlock()
-	nv, ok := v.nm.Get(n.Id)
-	//fmt.Println("key", n.Id, "volume offset", nv.Offset, "data_size", n.Size, "cached size", nv.Size)
-	if ok && nv.Size != TombstoneFileSize {
-		size := nv.Size
-		offset, err := v.dataFile.Seek(0, 2)
-		if err != nil {
-			return size, err
+	if v.dedup != nil {
+		if _, owner, found := v.dedup.get(n.Id); found && owner != n.Id {
+			// the needle only shares the content of another one
+			var size uint32
+			if nv, ok := v.getLive(owner); ok {
+				size = nv.Size
+			}
+			return size, v.dedup.release(n.Id)
 		}
-		if err := v.nm.Delete(n.Id, uint32(offset/NeedlePaddingSize)); err != nil {
-			return size, err
+		if err := v.releaseDedup(n.Id); err != nil {
+			return 0, err
 		}
-		n.Data = nil
-		_, _, err = n.Append(v.dataFile, v.Version())
-		return size, err
+	}
+	//fmt.Println("key", n.Id, "volume offset", nv.Offset, "data_size", n.Size, "cached size", nv.Size)
+	if nv, ok := v.getLive(n.Id); ok {
+		return nv.Size, v.deleteFromMap(n)
 	}
 	return 0, nil
 }
 
-// read fills in Needle content by looking up n.Id from NeedleMapper
-func (v *Volume) readNeedle(n *Needle) (int, error) {
+// deleteFromMap deletes the needle from the needle map, and appends its tombstone.
+// It requires serialized access in the same volume.
+func (v *Volume) deleteFromMap(n *Needle) error {
+	offset, err := v.dataFile.Seek(0, 2)
+	if err != nil {
+		return err
+	}
+	if err = v.nm.Delete(n.Id, uint32(offset/NeedlePaddingSize)); err != nil {
+		return err
+	}
+	n.Data = nil
+	_, _, err = n.Append(v.dataFile, v.Version())
+	return err
+}
+
+// read fills in Needle content by looking up n.Id from NeedleMapper,
+// sharing the needle blob with the cache if not nil
+func (v *Volume) readNeedle(n *Needle, cache *needleCache) (int, error) {
 	nv, ok := v.nm.Get(n.Id)
+	var shared *dedupNeedle
+	if _, live := v.getLive(n.Id); !live && v.dedup != nil {
+		// the content shared with another needle is read from it
+		if dn, owner, found := v.dedup.get(n.Id); found && owner != n.Id {
+			shared = &dn
+			nv, ok = v.nm.Get(owner)
+		}
+	}
 	if !ok || nv.Offset == 0 {
 		return -1, errors.New("Not Found")
 	}
 	if nv.Size == TombstoneFileSize {
 		return -1, errors.New("Already Deleted")
 	}
-	err := n.ReadData(v.dataFile, int64(nv.Offset)*NeedlePaddingSize, nv.Size, v.Version())
-	if err != nil {
+	key := needleCacheKey{v: v, revision: v.CompactRevision, id: n.Id, offset: nv.Offset}
+	blob, cached := cache.get(key)
+	if !cached {
+		var err error
+		if blob, err = v.readNeedleBlob(int64(nv.Offset)*NeedlePaddingSize, nv.Size); err != nil {
+			return 0, err
+		}
+	}
+	id := n.Id
+	if err := n.ReadBlob(blob, nv.Size, v.Version()); err != nil {
 		return 0, err
 	}
+	if shared != nil {
+		n.Id, n.Cookie, n.LastModified = id, shared.cookie, shared.lastModified
+	}
+	if !cached {
+		cache.set(key, blob)
+	}
 	bytesRead := len(n.Data)
 	if !n.HasTtl() {
 		return bytesRead, nil
@@ -55,7 +55,7 @@ func (v *Volume) Synchronize(volumeServer string) (err error) {
 			return fmt.Errorf("Failed to sync volume %d entries with %s: %v", v.Id, volumeServer, err)
 		}
 		if lastCompactRevision != compactRevision && lastCompactRevision != 0 {
-			if err = v.Compact(0); err != nil {
+			if err = v.Compact(0, 0); err != nil {
 				return fmt.Errorf("Compact Volume before synchronizing %v", err)
 			}
 			if err = v.commitCompact(); err != nil {
@@ -88,6 +88,9 @@ func (v *Volume) trySynchronizing(volumeServer string, masterMap *needle.Compact
 	if err != nil {
 		return fmt.Errorf("Load volume %d index file: %v", v.Id, err)
 	}
+	v.visitSharedNeedles(func(key uint64, offset, size uint32) {
+		slaveMap.m.Set(needle.Key(key), offset, size)
+	})
 	var delta []needle.NeedleValue
 	if err := masterMap.Visit(func(needleValue needle.NeedleValue) error {
 		if needleValue.Key == 0 {
@@ -164,18 +167,36 @@ func fetchVolumeFileEntries(volumeServer string, vid VolumeId) (m *needle.Compac
 
 func (v *Volume) GetVolumeSyncStatus() operation.SyncVolumeResponse {
 	var syncStatus = operation.SyncVolumeResponse{}
+	// the index size is taken first, so all its entries point before the tail offset
+	syncStatus.IdxFileSize = v.nm.IndexFileSize()
 	if stat, err := v.dataFile.Stat(); err == nil {
 		syncStatus.TailOffset = uint64(stat.Size())
 	}
-	syncStatus.IdxFileSize = v.nm.IndexFileSize()
+	if v.dedup != nil {
+		syncStatus.DedupFileSize = v.dedup.size()
+	}
+	syncStatus.Collection = v.Collection
 	syncStatus.CompactRevision = v.SuperBlock.CompactRevision
 	syncStatus.Ttl = v.SuperBlock.Ttl.String()
 	syncStatus.Replication = v.SuperBlock.ReplicaPlacement.String()
 	return syncStatus
 }
 
+// IndexFileContent returns the index file content, with an entry for each needle
+// sharing the content of another one at the offset of the latter.
 func (v *Volume) IndexFileContent() ([]byte, error) {
-	return v.nm.IndexFileContent()
+	content, err := v.nm.IndexFileContent()
+	if err != nil {
+		return nil, err
+	}
+	entry := make([]byte, 16)
+	v.visitSharedNeedles(func(key uint64, offset, size uint32) {
+		util.Uint64toBytes(entry[0:8], key)
+		util.Uint32toBytes(entry[8:12], offset)
+		util.Uint32toBytes(entry[12:16], size)
+		content = append(content, entry...)
+	})
+	return content, nil
 }
 
 // removeNeedle removes one needle by needle key
@@ -13,7 +13,8 @@ func (v *Volume) garbageLevel() float64 {
 	return float64(v.nm.DeletedSize()) / float64(v.ContentSize())
 }
 
-func (v *Volume) Compact(preallocate int64) error {
+// Compact copies the live needles, writing at most compactionBytePerSecond, unlimited if 0.
+func (v *Volume) Compact(preallocate int64, compactionBytePerSecond int64) error {
 	glog.V(3).Infof("Compacting ...")
 	//no need to lock for copy on write
 	//v.accessLock.Lock()
@@ -24,7 +25,7 @@ func (v *Volume) Compact(preallocate int64) error {
 	v.lastCompactIndexOffset = v.nm.IndexFileSize()
 	v.lastCompactRevision = v.SuperBlock.CompactRevision
 	glog.V(3).Infof("creating copies for volume %d ,last offset %d...", v.Id, v.lastCompactIndexOffset)
-	return v.copyDataAndGenerateIndexFile(filePath+".cpd", filePath+".cpx", preallocate)
+	return v.copyDataAndGenerateIndexFile(filePath+".cpd", filePath+".cpx", preallocate, compactionBytePerSecond)
 }
 
 func (v *Volume) Compact2() error {
@@ -39,6 +40,11 @@ func (v *Volume) commitCompact() error {
 	v.dataFileAccessLock.Lock()
 	defer v.dataFileAccessLock.Unlock()
 	glog.V(3).Infof("Got Committing lock...")
+	// the data file is mapped again once the compacted one is loaded
+	v.dataMapLock.RLock()
+	mapped := v.dataMap != nil
+	v.dataMapLock.RUnlock()
+	v.munmapDataFile()
 	v.nm.Close()
 	_ = v.dataFile.Close()
 
@@ -69,6 +75,11 @@ func (v *Volume) commitCompact() error {
 	if e = v.load(true, false, v.needleMapKind, 0); e != nil {
 		return e
 	}
+	if mapped {
+		if e = v.mmapDataFile(); e != nil {
+			glog.V(0).Infof("volume %d is read without mmap: %v", v.Id, e)
+		}
+	}
 	return nil
 }
 
@@ -221,7 +232,7 @@ func (v *Volume) makeupDiff(newDatFileName, newIdxFileName, oldDatFileName, oldI
 	return nil
 }
 
-func (v *Volume) copyDataAndGenerateIndexFile(dstName, idxName string, preallocate int64) (err error) {
+func (v *Volume) copyDataAndGenerateIndexFile(dstName, idxName string, preallocate int64, compactionBytePerSecond int64) (err error) {
 	var (
 		dst, idx *os.File
 	)
@@ -239,6 +250,7 @@ func (v *Volume) copyDataAndGenerateIndexFile(dstName, idxName string, prealloca
 	new_offset := int64(SuperBlockSize)
 
 	now := uint64(time.Now().Unix())
+	writeThrottler := util.NewWriteThrottler(compactionBytePerSecond)
 
 	err = ScanVolumeFile(v.dir, v.Collection, v.Id, v.needleMapKind,
 		func(superBlock SuperBlock) error {
@@ -259,6 +271,7 @@ func (v *Volume) copyDataAndGenerateIndexFile(dstName, idxName string, prealloca
 					return fmt.Errorf("cannot append needle: %s", err)
 				}
 				new_offset += n.DiskSize()
+				writeThrottler.MaybeSlowdown(n.DiskSize())
 				glog.V(3).Infoln("saving key", n.Id, "volume offset", offset, "=>", new_offset, "data_size", n.Size)
 			}
 			return nil
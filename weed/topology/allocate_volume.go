@@ -14,13 +14,16 @@ type AllocateVolumeResult struct {
 	Error string
 }
 
-func AllocateVolume(dn *DataNode, vid storage.VolumeId, option *VolumeGrowOption) error {
+func AllocateVolume(dn *DataNode, vid storage.VolumeId, option *VolumeGrowOption, dedup bool) error {
 	values := make(url.Values)
 	values.Add("volume", vid.String())
 	values.Add("collection", option.Collection)
 	values.Add("replication", option.ReplicaPlacement.String())
 	values.Add("ttl", option.Ttl.String())
 	values.Add("preallocate", fmt.Sprintf("%d", option.Prealloacte))
+	if dedup {
+		values.Add("dedup", "true")
+	}
 	jsonBlob, err := util.Post("http://"+dn.Url()+"/admin/assign_volume", values)
 	if err != nil {
 		return err
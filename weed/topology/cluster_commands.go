@@ -3,6 +3,7 @@ package topology
 import (
 	"github.com/chrislusf/raft"
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/sequence"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 )
 
@@ -29,3 +30,65 @@ func (c *MaxVolumeIdCommand) Apply(server raft.Server) (interface{}, error) {
 
 	return nil, nil
 }
+
+// ReserveFileIdsCommand reserves a range of file ids for the raft sequencer.
+// All masters must use the raft sequencer for the reservations to be kept.
+type ReserveFileIdsCommand struct {
+	Min   uint64 `json:"min"`
+	Count uint64 `json:"count"`
+}
+
+func NewReserveFileIdsCommand(min, count uint64) *ReserveFileIdsCommand {
+	return &ReserveFileIdsCommand{
+		Min:   min,
+		Count: count,
+	}
+}
+
+func (c *ReserveFileIdsCommand) CommandName() string {
+	return "ReserveFileIds"
+}
+
+func (c *ReserveFileIdsCommand) Apply(server raft.Server) (interface{}, error) {
+	topo := server.Context().(*Topology)
+	seq, ok := topo.Sequence.(*sequence.RaftSequencer)
+	if !ok {
+		glog.V(0).Infof("skip reserving file ids without the raft sequencer")
+		return uint64(0), nil
+	}
+	start := seq.ApplyReservation(c.Min, c.Count)
+	glog.V(1).Infoln("reserved file ids", start, "to", start+c.Count)
+	return start, nil
+}
+
+type SetCollectionSettingsCommand struct {
+	Collection string             `json:"collection"`
+	Settings   CollectionSettings `json:"settings"`
+}
+
+func (c *SetCollectionSettingsCommand) CommandName() string {
+	return "SetCollectionSettings"
+}
+
+func (c *SetCollectionSettingsCommand) Apply(server raft.Server) (interface{}, error) {
+	topo := server.Context().(*Topology)
+	topo.ClusterConfig.setCollectionSettings(c.Collection, c.Settings)
+	glog.V(0).Infof("collection %q settings: %+v", c.Collection, c.Settings)
+	return nil, nil
+}
+
+type SetNodeStateCommand struct {
+	Node  string `json:"node"`
+	State string `json:"state"`
+}
+
+func (c *SetNodeStateCommand) CommandName() string {
+	return "SetNodeState"
+}
+
+func (c *SetNodeStateCommand) Apply(server raft.Server) (interface{}, error) {
+	topo := server.Context().(*Topology)
+	topo.ClusterConfig.setNodeState(c.Node, c.State)
+	glog.V(0).Infof("volume server %s is %s", c.Node, c.State)
+	return nil, nil
+}
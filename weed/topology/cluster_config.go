@@ -0,0 +1,139 @@
+package topology
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+const (
+	NodeStateActive = "active"
+	// no new volumes are created on a draining volume server
+	NodeStateDraining = "draining"
+)
+
+// CollectionSettings are the defaults for assigning file ids in a collection,
+// when the request does not specify them, how its new volumes are placed,
+// and whether they deduplicate their files.
+type CollectionSettings struct {
+	Replication string `json:"replication,omitempty"`
+	Ttl         string `json:"ttl,omitempty"`
+	Placement   string `json:"placement,omitempty"`
+	// new volumes without ttl keep one copy of identical files
+	Dedup bool `json:"dedup,omitempty"`
+}
+
+func (s CollectionSettings) validate() error {
+	if s.Replication != "" {
+		if _, err := storage.NewReplicaPlacementFromString(s.Replication); err != nil {
+			return err
+		}
+	}
+	if _, err := storage.ReadTTL(s.Ttl); err != nil {
+		return err
+	}
+	if _, err := NewPlacementStrategy(s.Placement); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClusterConfig is the cluster level configuration, changed through raft
+// commands and kept in the raft snapshots, so it survives restarts
+// of all the masters.
+type ClusterConfig struct {
+	sync.RWMutex
+	collections map[string]CollectionSettings
+	nodeStates  map[string]string // volume server url => state, if not active
+}
+
+func newClusterConfig() *ClusterConfig {
+	return &ClusterConfig{
+		collections: make(map[string]CollectionSettings),
+		nodeStates:  make(map[string]string),
+	}
+}
+
+func (c *ClusterConfig) CollectionSettings(collection string) (CollectionSettings, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	s, ok := c.collections[collection]
+	return s, ok
+}
+
+func (c *ClusterConfig) AllCollectionSettings() map[string]CollectionSettings {
+	c.RLock()
+	defer c.RUnlock()
+	ret := make(map[string]CollectionSettings, len(c.collections))
+	for k, v := range c.collections {
+		ret[k] = v
+	}
+	return ret
+}
+
+func (c *ClusterConfig) setCollectionSettings(collection string, settings CollectionSettings) {
+	c.Lock()
+	defer c.Unlock()
+	if settings == (CollectionSettings{}) {
+		delete(c.collections, collection)
+		return
+	}
+	c.collections[collection] = settings
+}
+
+func (c *ClusterConfig) NodeState(url string) string {
+	c.RLock()
+	defer c.RUnlock()
+	if state, ok := c.nodeStates[url]; ok {
+		return state
+	}
+	return NodeStateActive
+}
+
+func (c *ClusterConfig) AllNodeStates() map[string]string {
+	c.RLock()
+	defer c.RUnlock()
+	ret := make(map[string]string, len(c.nodeStates))
+	for k, v := range c.nodeStates {
+		ret[k] = v
+	}
+	return ret
+}
+
+func (c *ClusterConfig) setNodeState(url string, state string) {
+	c.Lock()
+	defer c.Unlock()
+	if state == NodeStateActive || state == "" {
+		delete(c.nodeStates, url)
+		return
+	}
+	c.nodeStates[url] = state
+}
+
+// restore replaces the collection settings and the node states with the ones
+// of a raft snapshot.
+func (c *ClusterConfig) restore(collections map[string]CollectionSettings, nodeStates map[string]string) {
+	c.Lock()
+	defer c.Unlock()
+	c.collections = make(map[string]CollectionSettings, len(collections))
+	for collection, settings := range collections {
+		if settings != (CollectionSettings{}) {
+			c.collections[collection] = settings
+		}
+	}
+	c.nodeStates = make(map[string]string, len(nodeStates))
+	for url, state := range nodeStates {
+		if state != NodeStateActive && state != "" {
+			c.nodeStates[url] = state
+		}
+	}
+}
+
+func validateNodeState(state string) error {
+	switch state {
+	case NodeStateActive, NodeStateDraining:
+		return nil
+	}
+	return fmt.Errorf("unknown volume server state %q", state)
+}
@@ -24,14 +24,22 @@ func (c *Collection) String() string {
 }
 
 func (c *Collection) GetOrCreateVolumeLayout(rp *storage.ReplicaPlacement, ttl *storage.TTL) *VolumeLayout {
+	vl := c.storageType2VolumeLayout.Get(volumeLayoutKey(rp, ttl), func() interface{} {
+		return NewVolumeLayout(rp, ttl, c.volumeSizeLimit)
+	})
+	return vl.(*VolumeLayout)
+}
+
+func (c *Collection) DeleteVolumeLayout(rp *storage.ReplicaPlacement, ttl *storage.TTL) {
+	c.storageType2VolumeLayout.Delete(volumeLayoutKey(rp, ttl))
+}
+
+func volumeLayoutKey(rp *storage.ReplicaPlacement, ttl *storage.TTL) string {
 	keyString := rp.String()
 	if ttl != nil {
 		keyString += ttl.String()
 	}
-	vl := c.storageType2VolumeLayout.Get(keyString, func() interface{} {
-		return NewVolumeLayout(rp, ttl, c.volumeSizeLimit)
-	})
-	return vl.(*VolumeLayout)
+	return keyString
 }
 
 func (c *Collection) Lookup(vid storage.VolumeId) []*DataNode {
@@ -32,7 +32,8 @@ func (dn *DataNode) String() string {
 	return fmt.Sprintf("Node:%s, volumes:%v, Ip:%s, Port:%d, PublicUrl:%s", dn.NodeImpl.String(), dn.volumes, dn.Ip, dn.Port, dn.PublicUrl)
 }
 
-func (dn *DataNode) AddOrUpdateVolume(v storage.VolumeInfo) {
+// AddOrUpdateVolume returns true if the volume is new on the data node.
+func (dn *DataNode) AddOrUpdateVolume(v storage.VolumeInfo) (isNew bool) {
 	dn.Lock()
 	defer dn.Unlock()
 	if _, ok := dn.volumes[v.Id]; !ok {
@@ -42,12 +43,26 @@ func (dn *DataNode) AddOrUpdateVolume(v storage.VolumeInfo) {
 			dn.UpAdjustActiveVolumeCountDelta(1)
 		}
 		dn.UpAdjustMaxVolumeId(v.Id)
-	} else {
-		dn.volumes[v.Id] = v
+		return true
 	}
+	dn.volumes[v.Id] = v
+	return false
 }
 
-func (dn *DataNode) UpdateVolumes(actualVolumes []storage.VolumeInfo) (deletedVolumes []storage.VolumeInfo) {
+// DeleteVolumeById forgets a volume deleted from the data node, freeing its slot.
+func (dn *DataNode) DeleteVolumeById(id storage.VolumeId) {
+	dn.Lock()
+	defer dn.Unlock()
+	if v, ok := dn.volumes[id]; ok {
+		delete(dn.volumes, id)
+		dn.UpAdjustVolumeCountDelta(-1)
+		if !v.ReadOnly {
+			dn.UpAdjustActiveVolumeCountDelta(-1)
+		}
+	}
+}
+
+func (dn *DataNode) UpdateVolumes(actualVolumes []storage.VolumeInfo) (newVolumes, deletedVolumes []storage.VolumeInfo) {
 	actualVolumeMap := make(map[storage.VolumeId]storage.VolumeInfo)
 	for _, v := range actualVolumes {
 		actualVolumeMap[v.Id] = v
@@ -64,11 +79,36 @@ func (dn *DataNode) UpdateVolumes(actualVolumes []storage.VolumeInfo) (deletedVo
 	}
 	dn.Unlock()
 	for _, v := range actualVolumes {
-		dn.AddOrUpdateVolume(v)
+		if dn.AddOrUpdateVolume(v) {
+			newVolumes = append(newVolumes, v)
+		}
 	}
 	return
 }
 
+// DeltaUpdateVolumes applies the volume changes of a delta heartbeat,
+// and returns the volumes it added and removed.
+func (dn *DataNode) DeltaUpdateVolumes(changedVolumes []storage.VolumeInfo, deletedIds []storage.VolumeId) (newVolumes, deletedVolumes []storage.VolumeInfo) {
+	for _, v := range changedVolumes {
+		if dn.AddOrUpdateVolume(v) {
+			newVolumes = append(newVolumes, v)
+		}
+	}
+	for _, vid := range deletedIds {
+		if v, err := dn.GetVolumesById(vid); err == nil {
+			glog.V(0).Infoln("Deleting volume id:", vid)
+			dn.DeleteVolumeById(vid)
+			deletedVolumes = append(deletedVolumes, v)
+		}
+	}
+	return
+}
+
+// IsDraining tells whether no new volumes should be created on the data node.
+func (dn *DataNode) IsDraining() bool {
+	return dn.GetTopology().ClusterConfig.NodeState(dn.Url()) == NodeStateDraining
+}
+
 func (dn *DataNode) GetVolumes() (ret []storage.VolumeInfo) {
 	dn.RLock()
 	for _, v := range dn.volumes {
@@ -102,8 +142,7 @@ func (dn *DataNode) GetTopology() *Topology {
 	for p.Parent() != nil {
 		p = p.Parent()
 	}
-	t := p.(*Topology)
-	return t
+	return p.GetValue().(*Topology)
 }
 
 func (dn *DataNode) MatchLocation(ip string, port int) bool {
@@ -0,0 +1,181 @@
+package topology
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const (
+	// PlacementRandom picks nodes randomly, weighted by their free volume slots
+	PlacementRandom = "random"
+	// PlacementLeastUsedBytes picks the nodes storing the least bytes
+	PlacementLeastUsedBytes = "leastUsedBytes"
+	// PlacementSpread picks the nodes having the least volumes of the collection,
+	// spreading each collection evenly over the data centers, racks and servers
+	PlacementSpread = "spread"
+)
+
+// PlacementStrategy chooses the data centers, racks and data nodes of a new volume.
+// The candidates are siblings, all having free volume slots.
+type PlacementStrategy interface {
+	// Pick returns count of the candidates, or all of them if there are not enough.
+	Pick(option *VolumeGrowOption, candidates []Node, count int) []Node
+}
+
+func NewPlacementStrategy(name string) (PlacementStrategy, error) {
+	switch name {
+	case PlacementRandom, "":
+		return randomPlacement{}, nil
+	case PlacementLeastUsedBytes:
+		return scoredPlacement(func(option *VolumeGrowOption, node Node) uint64 {
+			return usedBytes(node)
+		}), nil
+	case PlacementSpread:
+		return scoredPlacement(func(option *VolumeGrowOption, node Node) uint64 {
+			return uint64(collectionVolumeCount(node, option.Collection))
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown volume placement %q, should be one of %s, %s, %s",
+		name, PlacementRandom, PlacementLeastUsedBytes, PlacementSpread)
+}
+
+type randomPlacement struct{}
+
+func (randomPlacement) Pick(option *VolumeGrowOption, candidates []Node, count int) (picked []Node) {
+	rest := append([]Node(nil), candidates...)
+	for len(picked) < count && len(rest) > 0 {
+		total := 0
+		for _, node := range rest {
+			total += freeSlots(node)
+		}
+		i, r := 0, rand.Intn(total)
+		for ; r >= freeSlots(rest[i]); i++ {
+			r -= freeSlots(rest[i])
+		}
+		picked = append(picked, rest[i])
+		rest = append(rest[:i], rest[i+1:]...)
+	}
+	return
+}
+
+func freeSlots(node Node) int {
+	if free := node.FreeSpace(); free > 0 {
+		return free
+	}
+	return 1
+}
+
+// scoredPlacement picks the nodes with the lowest scores, randomly among equal scores.
+type scoredPlacement func(option *VolumeGrowOption, node Node) uint64
+
+func (score scoredPlacement) Pick(option *VolumeGrowOption, candidates []Node, count int) []Node {
+	sorted := make([]Node, len(candidates))
+	for i, j := range rand.Perm(len(candidates)) {
+		sorted[i] = candidates[j]
+	}
+	scores := make(map[NodeId]uint64, len(sorted))
+	for _, node := range sorted {
+		scores[node.Id()] = score(option, node)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return scores[sorted[i].Id()] < scores[sorted[j].Id()]
+	})
+	if count < len(sorted) {
+		sorted = sorted[:count]
+	}
+	return sorted
+}
+
+func usedBytes(node Node) (size uint64) {
+	if node.IsDataNode() {
+		for _, v := range node.(*DataNode).GetVolumes() {
+			size += v.Size
+		}
+		return
+	}
+	for _, child := range node.Children() {
+		size += usedBytes(child)
+	}
+	return
+}
+
+func collectionVolumeCount(node Node, collection string) (count int) {
+	if node.IsDataNode() {
+		for _, v := range node.(*DataNode).GetVolumes() {
+			if v.Collection == collection {
+				count++
+			}
+		}
+		return
+	}
+	for _, child := range node.Children() {
+		count += collectionVolumeCount(child, collection)
+	}
+	return
+}
+
+// pickNodes picks the first node passing the filter, and the rest nodes
+// having a free slot, among the children of the parent.
+func pickNodes(strategy PlacementStrategy, option *VolumeGrowOption, parent Node, numberOfNodes int, filterFirstNodeFn func(node Node) error) (firstNode Node, restNodes []Node, err error) {
+	var candidates []Node
+	var errs []string
+	for _, node := range parent.Children() {
+		if err := filterFirstNodeFn(node); err == nil {
+			candidates = append(candidates, node)
+		} else {
+			errs = append(errs, string(node.Id())+":"+err.Error())
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("No matching data node found! \n%s", strings.Join(errs, "\n"))
+	}
+	firstNode = strategy.Pick(option, candidates, 1)[0]
+
+	candidates = candidates[:0]
+	for _, node := range parent.Children() {
+		if node.Id() != firstNode.Id() && hasFreeSlot(node) {
+			candidates = append(candidates, node)
+		}
+	}
+	restNodes = strategy.Pick(option, candidates, numberOfNodes-1)
+	if len(restNodes) < numberOfNodes-1 {
+		return nil, nil, errors.New("Not enough data node found!")
+	}
+	return
+}
+
+// pickOneDataNode picks a data node with a free slot under the node.
+func pickOneDataNode(strategy PlacementStrategy, option *VolumeGrowOption, node Node) (*DataNode, error) {
+	for !node.IsDataNode() {
+		var candidates []Node
+		for _, child := range node.Children() {
+			if hasFreeSlot(child) {
+				candidates = append(candidates, child)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("No free volume slot found in %s!", node.Id())
+		}
+		node = strategy.Pick(option, candidates, 1)[0]
+	}
+	return node.(*DataNode), nil
+}
+
+// hasFreeSlot tells whether a data node under the node can take a new volume.
+func hasFreeSlot(node Node) bool {
+	if node.FreeSpace() <= 0 {
+		return false
+	}
+	if node.IsDataNode() {
+		return !node.(*DataNode).IsDraining()
+	}
+	for _, child := range node.Children() {
+		if hasFreeSlot(child) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package topology
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+func simulatePlacement(t *testing.T, placement string, count int, option *VolumeGrowOption) (*Topology, error) {
+	strategy, err := NewPlacementStrategy(placement)
+	if err != nil {
+		t.Fatalf("placement %s: %v", placement, err)
+	}
+	topo := setup(topologyLayout)
+	if option.ReplicaPlacement == nil {
+		option.ReplicaPlacement, _ = storage.NewReplicaPlacementFromString("000")
+	}
+	if option.Ttl == nil {
+		option.Ttl = storage.EMPTY_TTL
+	}
+	_, err = NewSimulatedVolumeGrowth(strategy).GrowByCountAndType(count, option, topo)
+	return topo, err
+}
+
+func findNode(topo *Topology, id string) Node {
+	for _, dc := range topo.Children() {
+		if string(dc.Id()) == id {
+			return dc
+		}
+		for _, rack := range dc.Children() {
+			for _, dn := range rack.Children() {
+				if string(dn.Id()) == id {
+					return dn
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestRandomPlacementFillsAllSlots(t *testing.T) {
+	topo, err := simulatePlacement(t, PlacementRandom, 15, &VolumeGrowOption{})
+	if err != nil {
+		t.Fatalf("grow 15 volumes into 15 free slots: %v", err)
+	}
+	if topo.FreeSpace() != 0 {
+		t.Errorf("expected no free slot left, got %d", topo.FreeSpace())
+	}
+	if _, err = NewSimulatedVolumeGrowth(randomPlacement{}).GrowByCountAndType(1, &VolumeGrowOption{
+		ReplicaPlacement: &storage.ReplicaPlacement{},
+		Ttl:              storage.EMPTY_TTL,
+	}, topo); err == nil {
+		t.Errorf("grew a volume without free slots")
+	}
+}
+
+func TestLeastUsedBytesPlacement(t *testing.T) {
+	topo, err := simulatePlacement(t, PlacementLeastUsedBytes, 1, &VolumeGrowOption{DataCenter: "dc1", Rack: "rack2"})
+	if err != nil {
+		t.Fatalf("grow volume: %v", err)
+	}
+	if count := findNode(topo, "server122").GetVolumeCount(); count != 1 {
+		t.Errorf("expected the volume on the empty server122, got %d volumes there", count)
+	}
+}
+
+func TestSpreadPlacement(t *testing.T) {
+	topo, err := simulatePlacement(t, PlacementSpread, 2, &VolumeGrowOption{Collection: "pictures"})
+	if err != nil {
+		t.Fatalf("grow volumes: %v", err)
+	}
+	for _, dc := range []string{"dc1", "dc3"} {
+		if count := collectionVolumeCount(findNode(topo, dc), "pictures"); count != 1 {
+			t.Errorf("expected 1 volume of the collection in %s, got %d", dc, count)
+		}
+	}
+}
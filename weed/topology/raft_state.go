@@ -0,0 +1,54 @@
+package topology
+
+import (
+	"encoding/json"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/sequence"
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+// raftState is what the raft commands change, saved in the raft snapshots.
+type raftState struct {
+	MaxVolumeId    storage.VolumeId              `json:"maxVolumeId"`
+	ReservedFileId uint64                        `json:"reservedFileId,omitempty"`
+	Collections    map[string]CollectionSettings `json:"collections,omitempty"`
+	NodeStates     map[string]string             `json:"nodeStates,omitempty"`
+}
+
+// StateMachine saves and restores the topology state for raft snapshots,
+// which lets raft compact its log.
+type StateMachine struct {
+	topo *Topology
+}
+
+func NewStateMachine(topo *Topology) *StateMachine {
+	return &StateMachine{topo: topo}
+}
+
+func (s *StateMachine) Save() ([]byte, error) {
+	state := raftState{
+		MaxVolumeId: s.topo.GetMaxVolumeId(),
+		Collections: s.topo.ClusterConfig.AllCollectionSettings(),
+		NodeStates:  s.topo.ClusterConfig.AllNodeStates(),
+	}
+	if seq, ok := s.topo.Sequence.(*sequence.RaftSequencer); ok {
+		state.ReservedFileId = seq.Reserved()
+	}
+	glog.V(1).Infof("save raft state, max volume id %d", state.MaxVolumeId)
+	return json.Marshal(state)
+}
+
+func (s *StateMachine) Recovery(data []byte) error {
+	var state raftState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	s.topo.UpAdjustMaxVolumeId(state.MaxVolumeId)
+	if seq, ok := s.topo.Sequence.(*sequence.RaftSequencer); ok {
+		seq.ApplyReservation(state.ReservedFileId, 0)
+	}
+	s.topo.ClusterConfig.restore(state.Collections, state.NodeStates)
+	glog.V(0).Infof("recovered raft state, max volume id %d", state.MaxVolumeId)
+	return nil
+}
@@ -0,0 +1,63 @@
+package topology
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/sequence"
+)
+
+func TestStateMachineRecovery(t *testing.T) {
+	topo := NewTopology("weedfs", sequence.NewRaftSequencer(), 32*1024, 5)
+	topo.UpAdjustMaxVolumeId(7)
+	topo.Sequence.(*sequence.RaftSequencer).ApplyReservation(5000, 100)
+	topo.ClusterConfig.setCollectionSettings("logs", CollectionSettings{Replication: "001", Ttl: "3d"})
+	topo.ClusterConfig.setNodeState("127.0.0.1:8080", NodeStateDraining)
+
+	data, err := NewStateMachine(topo).Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewTopology("weedfs", sequence.NewRaftSequencer(), 32*1024, 5)
+	if err := NewStateMachine(restarted).Recovery(data); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.GetMaxVolumeId() != 7 {
+		t.Errorf("max volume id %d, expected 7", restarted.GetMaxVolumeId())
+	}
+	if reserved := restarted.Sequence.(*sequence.RaftSequencer).Reserved(); reserved != 5100 {
+		t.Errorf("reserved file ids up to %d, expected 5100", reserved)
+	}
+	if settings, _ := restarted.ClusterConfig.CollectionSettings("logs"); settings.Replication != "001" || settings.Ttl != "3d" {
+		t.Errorf("collection settings %+v not recovered", settings)
+	}
+	if state := restarted.ClusterConfig.NodeState("127.0.0.1:8080"); state != NodeStateDraining {
+		t.Errorf("node state %q, expected draining", state)
+	}
+}
+
+func TestStateMachineRecoveryReplacesConfig(t *testing.T) {
+	topo := NewTopology("weedfs", sequence.NewRaftSequencer(), 32*1024, 5)
+	topo.ClusterConfig.setCollectionSettings("logs", CollectionSettings{Replication: "001"})
+	data, err := NewStateMachine(topo).Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := NewTopology("weedfs", sequence.NewRaftSequencer(), 32*1024, 5)
+	stale.ClusterConfig.setCollectionSettings("logs", CollectionSettings{Replication: "010"})
+	stale.ClusterConfig.setCollectionSettings("removed", CollectionSettings{Ttl: "3d"})
+	stale.ClusterConfig.setNodeState("127.0.0.1:8080", NodeStateDraining)
+	if err := NewStateMachine(stale).Recovery(data); err != nil {
+		t.Fatal(err)
+	}
+	if settings, _ := stale.ClusterConfig.CollectionSettings("logs"); settings.Replication != "001" {
+		t.Errorf("collection settings %+v, expected the snapshot ones", settings)
+	}
+	if _, found := stale.ClusterConfig.CollectionSettings("removed"); found {
+		t.Errorf("collection settings missing from the snapshot kept")
+	}
+	if state := stale.ClusterConfig.NodeState("127.0.0.1:8080"); state != NodeStateActive {
+		t.Errorf("node state %q missing from the snapshot kept", state)
+	}
+}
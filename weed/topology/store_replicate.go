@@ -2,6 +2,7 @@ package topology
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/operation"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/storage"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
@@ -24,6 +26,10 @@ func ReplicatedWrite(masterNode string, s *storage.Store,
 	//check JWT
 	jwt := security.GetJwt(r)
 
+	ctx, span := tracing.StartSpan(r.Context(), "ReplicatedWrite", tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("volumeId", volumeId.String())
+
 	ret, err := s.Write(volumeId, needle)
 	needToReplicate := !s.HasVolume(volumeId)
 	if err != nil {
@@ -39,7 +45,7 @@ func ReplicatedWrite(masterNode string, s *storage.Store,
 	if needToReplicate { //send to other replica locations
 		if r.FormValue("type") != "replicate" {
 
-			if err = distributedOperation(masterNode, s, volumeId, func(location operation.Location) error {
+			if err = distributedOperation(ctx, masterNode, s, volumeId, func(ctx context.Context, location operation.Location) error {
 				u := url.URL{
 					Scheme: "http",
 					Host:   location.Url,
@@ -68,7 +74,7 @@ func ReplicatedWrite(masterNode string, s *storage.Store,
 					}
 				}
 
-				_, err := operation.Upload(u.String(),
+				_, err := operation.UploadContext(ctx, u.String(),
 					string(needle.Name), bytes.NewReader(needle.Data), needle.IsGzipped(), string(needle.Mime),
 					pairMap, jwt)
 				return err
@@ -78,6 +84,9 @@ func ReplicatedWrite(masterNode string, s *storage.Store,
 			}
 		}
 	}
+	if errorStatus != "" {
+		span.SetError(errors.New(errorStatus))
+	}
 	size = ret
 	return
 }
@@ -89,6 +98,10 @@ func ReplicatedDelete(masterNode string, store *storage.Store,
 	//check JWT
 	jwt := security.GetJwt(r)
 
+	ctx, span := tracing.StartSpan(r.Context(), "ReplicatedDelete", tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("volumeId", volumeId.String())
+
 	ret, err := store.Delete(volumeId, n)
 	if err != nil {
 		glog.V(0).Infoln("delete error:", err)
@@ -101,10 +114,11 @@ func ReplicatedDelete(masterNode string, store *storage.Store,
 	}
 	if needToReplicate { //send to other replica locations
 		if r.FormValue("type") != "replicate" {
-			if err = distributedOperation(masterNode, store, volumeId, func(location operation.Location) error {
-				return util.Delete("http://"+location.Url+r.URL.Path+"?type=replicate", jwt)
+			if err = distributedOperation(ctx, masterNode, store, volumeId, func(ctx context.Context, location operation.Location) error {
+				return util.DeleteContext(ctx, "http://"+location.Url+r.URL.Path+"?type=replicate", jwt)
 			}); err != nil {
 				ret = 0
+				span.SetError(err)
 			}
 		}
 	}
@@ -131,8 +145,8 @@ type RemoteResult struct {
 	Error error
 }
 
-func distributedOperation(masterNode string, store *storage.Store, volumeId storage.VolumeId, op func(location operation.Location) error) error {
-	if lookupResult, lookupErr := operation.Lookup(masterNode, volumeId.String()); lookupErr == nil {
+func distributedOperation(ctx context.Context, masterNode string, store *storage.Store, volumeId storage.VolumeId, op func(ctx context.Context, location operation.Location) error) error {
+	if lookupResult, lookupErr := operation.LookupContext(ctx, masterNode, volumeId.String()); lookupErr == nil {
 		length := 0
 		selfUrl := (store.Ip + ":" + strconv.Itoa(store.Port))
 		results := make(chan RemoteResult)
@@ -140,7 +154,12 @@ func distributedOperation(masterNode string, store *storage.Store, volumeId stor
 			if location.Url != selfUrl {
 				length++
 				go func(location operation.Location, results chan RemoteResult) {
-					results <- RemoteResult{location.Url, op(location)}
+					ctx, span := tracing.StartSpan(ctx, "replicate", tracing.SpanKindInternal)
+					span.SetAttribute("replica", location.Url)
+					err := op(ctx, location)
+					span.SetError(err)
+					span.End()
+					results <- RemoteResult{location.Url, err}
 				}(location, results)
 			}
 		}
@@ -26,7 +26,13 @@ type Topology struct {
 
 	Configuration *Configuration
 
+	ClusterConfig *ClusterConfig
+
 	RaftServer raft.Server
+
+	vacuumOptions   VacuumOptions
+	vacuumScheduler *vacuumScheduler
+	vacuumHistory   vacuumHistory
 }
 
 func NewTopology(id string, seq sequence.Sequencer, volumeSizeLimit uint64, pulse int) *Topology {
@@ -44,6 +50,10 @@ func NewTopology(id string, seq sequence.Sequencer, volumeSizeLimit uint64, puls
 	t.chanFullVolumes = make(chan storage.VolumeInfo)
 
 	t.Configuration = &Configuration{}
+	t.ClusterConfig = newClusterConfig()
+
+	t.vacuumOptions = NewVacuumOptions("0.3")
+	t.vacuumScheduler = newVacuumScheduler()
 
 	return t
 }
@@ -94,6 +104,22 @@ func (t *Topology) NextVolumeId() storage.VolumeId {
 	return next
 }
 
+// ReserveFileIds reserves file ids for the raft sequencer through the raft log.
+func (t *Topology) ReserveFileIds(min, count uint64) (uint64, error) {
+	if t.RaftServer == nil {
+		return 0, errors.New("Raft Server not ready yet!")
+	}
+	ret, err := t.RaftServer.Do(NewReserveFileIdsCommand(min, count))
+	if err != nil {
+		return 0, err
+	}
+	start, ok := ret.(uint64)
+	if !ok || start == 0 {
+		return 0, errors.New("file ids are not reserved, check all masters use the raft sequencer")
+	}
+	return start, nil
+}
+
 func (t *Topology) HasWritableVolume(option *VolumeGrowOption) bool {
 	vl := t.GetVolumeLayout(option.Collection, option.ReplicaPlacement, option.Ttl)
 	return vl.GetActiveVolumeCount(option) > 0
@@ -105,6 +131,9 @@ func (t *Topology) PickForWrite(count uint64, option *VolumeGrowOption) (string,
 		return "", 0, nil, errors.New("No writable volumes available!")
 	}
 	fileId, count := t.Sequence.NextFileId(count)
+	if count == 0 {
+		return "", 0, nil, errors.New("No file ids available!")
+	}
 	return storage.NewFileId(*vid, fileId, rand.Uint32()).String(), count, datanodes.Head(), nil
 }
 
@@ -122,6 +151,14 @@ func (t *Topology) FindCollection(collectionName string) (*Collection, bool) {
 	return c.(*Collection), hasCollection
 }
 
+// ListCollectionNames returns the names of all the collections.
+func (t *Topology) ListCollectionNames() (ret []string) {
+	for _, c := range t.collectionMap.Items() {
+		ret = append(ret, c.(*Collection).Name)
+	}
+	return
+}
+
 func (t *Topology) DeleteCollection(collectionName string) {
 	t.collectionMap.Delete(collectionName)
 }
@@ -134,6 +171,18 @@ func (t *Topology) UnRegisterVolumeLayout(v storage.VolumeInfo, dn *DataNode) {
 	t.GetVolumeLayout(v.Collection, v.ReplicaPlacement, v.Ttl).UnRegisterVolume(&v, dn)
 }
 
+// DataNodes returns all the volume servers.
+func (t *Topology) DataNodes() (ret []*DataNode) {
+	for _, c := range t.Children() {
+		for _, r := range c.Children() {
+			for _, n := range r.Children() {
+				ret = append(ret, n.(*DataNode))
+			}
+		}
+	}
+	return
+}
+
 func (t *Topology) GetOrCreateDataCenter(dcName string) *DataCenter {
 	for _, c := range t.Children() {
 		dc := c.(*DataCenter)
@@ -0,0 +1,32 @@
+package topology
+
+import (
+	"errors"
+
+	"github.com/chrislusf/raft"
+)
+
+// SetCollectionSettings changes the defaults of the collection on all masters.
+// Empty settings remove them.
+func (t *Topology) SetCollectionSettings(collection string, settings CollectionSettings) error {
+	if err := settings.validate(); err != nil {
+		return err
+	}
+	return t.doRaftCommand(&SetCollectionSettingsCommand{Collection: collection, Settings: settings})
+}
+
+// SetNodeState changes the state of the volume server on all masters.
+func (t *Topology) SetNodeState(url string, state string) error {
+	if err := validateNodeState(state); err != nil {
+		return err
+	}
+	return t.doRaftCommand(&SetNodeStateCommand{Node: url, State: state})
+}
+
+func (t *Topology) doRaftCommand(command raft.Command) error {
+	if t.RaftServer == nil {
+		return errors.New("Raft Server not ready yet!")
+	}
+	_, err := t.RaftServer.Do(command)
+	return err
+}
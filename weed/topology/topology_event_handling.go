@@ -8,7 +8,8 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/storage"
 )
 
-func (t *Topology) StartRefreshWritableVolumes(garbageThreshold string, preallocate int64) {
+func (t *Topology) StartRefreshWritableVolumes(vacuumOptions VacuumOptions) {
+	t.vacuumOptions = vacuumOptions
 	go func() {
 		for {
 			if t.IsLeader() {
@@ -18,14 +19,24 @@ func (t *Topology) StartRefreshWritableVolumes(garbageThreshold string, prealloc
 			time.Sleep(time.Duration(float32(t.pulse*1e3)*(1+rand.Float32())) * time.Millisecond)
 		}
 	}()
-	go func(garbageThreshold string) {
-		c := time.Tick(15 * time.Minute)
+	go func() {
+		// much longer than the heartbeat interval, so writes to a volume
+		// taken out of the writables are reported before it is deleted
+		c := time.Tick(time.Minute)
 		for _ = range c {
 			if t.IsLeader() {
-				t.Vacuum(garbageThreshold, preallocate)
+				t.CollectExpiredVolumes()
 			}
 		}
-	}(garbageThreshold)
+	}()
+	go func() {
+		c := time.Tick(vacuumOptions.Interval)
+		for _ = range c {
+			if t.IsLeader() && vacuumOptions.inWindow(time.Now()) {
+				t.vacuum("", func() bool { return vacuumOptions.inWindow(time.Now()) })
+			}
+		}
+	}()
 	go func() {
 		for {
 			select {
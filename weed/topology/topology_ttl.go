@@ -0,0 +1,132 @@
+package topology
+
+import (
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/storage"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// an expired ttl volume is deleted after 10% of its ttl more,
+// at most this many minutes, to tolerate clock differences
+const maxTtlVolumeRemovalDelayMinutes = 10
+
+// CollectExpiredVolumes deletes the ttl volumes whose needles have all expired,
+// i.e. nothing was written on any replica for longer than the ttl.
+// An expired volume is first taken out of the writable volumes, and deleted
+// on the next call, so writes already assigned to it are seen in time.
+func (t *Topology) CollectExpiredVolumes() {
+	now := time.Now().Unix()
+	for _, col := range t.collectionMap.Items() {
+		c := col.(*Collection)
+		for _, layout := range c.storageType2VolumeLayout.Items() {
+			vl := layout.(*VolumeLayout)
+			if vl.ttl == nil || vl.ttl.Minutes() == 0 {
+				continue
+			}
+			for vid, dataNodes := range vl.expiredVolumes(now) {
+				if vl.setVolumeExpiring(vid) {
+					glog.V(0).Infof("ttl volume %d of collection %q expired, deleting it next round", vid, c.Name)
+					continue
+				}
+				t.deleteExpiredVolume(c.Name, vl, vid, dataNodes)
+			}
+			if vl.isEmpty() {
+				glog.V(1).Infof("remove empty layout %s of collection %q", volumeLayoutKey(vl.rp, vl.ttl), c.Name)
+				c.DeleteVolumeLayout(vl.rp, vl.ttl)
+			}
+		}
+	}
+}
+
+func (t *Topology) deleteExpiredVolume(collection string, vl *VolumeLayout, vid storage.VolumeId, dataNodes []*DataNode) {
+	var reclaimed uint64
+	deleted := 0
+	for _, dn := range dataNodes {
+		vi, err := dn.GetVolumesById(vid)
+		if err != nil {
+			continue
+		}
+		if _, err := util.Get("http://" + dn.Url() + "/admin/volume/delete?volume=" + vid.String()); err != nil {
+			glog.V(0).Infof("delete expired volume %d on %s: %v", vid, dn.Url(), err)
+			continue
+		}
+		dn.DeleteVolumeById(vid)
+		vl.removeVolumeLocation(dn, vid)
+		reclaimed += vi.Size
+		deleted++
+	}
+	if deleted == 0 {
+		return
+	}
+	glog.V(0).Infof("deleted expired volume %d of collection %q on %d servers, reclaimed %d bytes", vid, collection, deleted, reclaimed)
+	stats.MasterExpiredVolumeCounter.WithLabelValues(collection).Inc()
+	stats.MasterReclaimedBytesCounter.WithLabelValues(collection).Add(float64(reclaimed))
+}
+
+// expiredVolumes returns the data nodes of each volume whose last write,
+// on all replicas, is older than the ttl and the removal delay.
+// Volumes without a known last write time are skipped.
+func (vl *VolumeLayout) expiredVolumes(now int64) map[storage.VolumeId][]*DataNode {
+	removalDelay := vl.ttl.Minutes() / 10
+	if removalDelay > maxTtlVolumeRemovalDelayMinutes {
+		removalDelay = maxTtlVolumeRemovalDelayMinutes
+	}
+	expireBefore := now - int64(vl.ttl.Minutes()+removalDelay)*60
+
+	vl.accessLock.RLock()
+	defer vl.accessLock.RUnlock()
+
+	expired := make(map[storage.VolumeId][]*DataNode)
+	for vid, locations := range vl.vid2location {
+		isExpired := locations.Length() > 0
+		for _, dn := range locations.list {
+			vi, err := dn.GetVolumesById(vid)
+			if err != nil || vi.ModifiedAtSecond == 0 || vi.ModifiedAtSecond >= expireBefore {
+				isExpired = false
+				break
+			}
+		}
+		if isExpired {
+			expired[vid] = append([]*DataNode(nil), locations.list...)
+		}
+	}
+	return expired
+}
+
+// setVolumeExpiring takes the volume out of the writables,
+// and returns false if it was already expiring.
+func (vl *VolumeLayout) setVolumeExpiring(vid storage.VolumeId) bool {
+	vl.accessLock.Lock()
+	defer vl.accessLock.Unlock()
+
+	if vl.expiringVolumes[vid] {
+		return false
+	}
+	vl.expiringVolumes[vid] = true
+	vl.removeFromWritable(vid)
+	return true
+}
+
+func (vl *VolumeLayout) removeVolumeLocation(dn *DataNode, vid storage.VolumeId) {
+	vl.accessLock.Lock()
+	defer vl.accessLock.Unlock()
+
+	if location, ok := vl.vid2location[vid]; ok {
+		location.Remove(dn)
+		if location.Length() == 0 {
+			vl.removeFromWritable(vid)
+			delete(vl.vid2location, vid)
+			delete(vl.expiringVolumes, vid)
+		}
+	}
+}
+
+func (vl *VolumeLayout) isEmpty() bool {
+	vl.accessLock.RLock()
+	defer vl.accessLock.RUnlock()
+
+	return len(vl.vid2location) == 0
+}
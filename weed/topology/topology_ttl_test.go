@@ -0,0 +1,69 @@
+package topology
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/sequence"
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+func TestCollectExpiredVolumes(t *testing.T) {
+	var deleteRequests int32
+	volumeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin/volume/delete" && r.FormValue("volume") == "1" {
+			atomic.AddInt32(&deleteRequests, 1)
+		}
+	}))
+	defer volumeServer.Close()
+	host, portString, _ := net.SplitHostPort(volumeServer.Listener.Addr().String())
+	port, _ := strconv.Atoi(portString)
+
+	topo := NewTopology("weedfs", sequence.NewMemorySequencer(), 32*1024, 5)
+	dc := NewDataCenter("dc1")
+	topo.LinkChildNode(dc)
+	rack := NewRack("rack1")
+	dc.LinkChildNode(rack)
+	dn := rack.GetOrCreateDataNode(host, port, host, 10)
+
+	ttl, _ := storage.ReadTTL("1h")
+	rp, _ := storage.NewReplicaPlacementFromString("000")
+	now := time.Now().Unix()
+	for _, vi := range []storage.VolumeInfo{
+		{Id: 1, Size: 1000, Ttl: ttl, ReplicaPlacement: rp, Version: storage.CurrentVersion, ModifiedAtSecond: now - 2*3600},
+		{Id: 2, Size: 1000, Ttl: ttl, ReplicaPlacement: rp, Version: storage.CurrentVersion, ModifiedAtSecond: now - 60},
+	} {
+		dn.AddOrUpdateVolume(vi)
+		topo.RegisterVolumeLayout(vi, dn)
+	}
+	vl := topo.GetVolumeLayout("", rp, ttl)
+
+	topo.CollectExpiredVolumes()
+	if deleteRequests != 0 {
+		t.Fatalf("expired volume deleted before leaving the writables")
+	}
+	for _, vid := range vl.writables {
+		if vid == 1 {
+			t.Fatalf("expired volume 1 is still writable")
+		}
+	}
+
+	topo.CollectExpiredVolumes()
+	if deleteRequests != 1 {
+		t.Fatalf("expected 1 delete request, got %d", deleteRequests)
+	}
+	if vl.Lookup(1) != nil {
+		t.Errorf("expired volume 1 is still registered")
+	}
+	if vl.Lookup(2) == nil {
+		t.Errorf("fresh volume 2 is gone")
+	}
+	if dn.GetVolumeCount() != 1 {
+		t.Errorf("expected 1 volume left on the data node, got %d", dn.GetVolumeCount())
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
@@ -92,34 +95,111 @@ func batchVacuumVolumeCleanup(vl *VolumeLayout, vid storage.VolumeId, locationli
 	}
 }
 
-func (t *Topology) Vacuum(garbageThreshold string, preallocate int64) int {
-	glog.V(0).Infof("Start vacuum on demand with threshold:%s", garbageThreshold)
+// Vacuum checks all writable volumes now, against garbageThreshold if given,
+// otherwise against the threshold of their collection.
+// It returns the number of volumes vacuumed.
+func (t *Topology) Vacuum(garbageThreshold string) int {
+	return t.vacuum(garbageThreshold, func() bool { return true })
+}
+
+// VacuumHistory returns the recent volume vacuums, the latest first.
+func (t *Topology) VacuumHistory() []VacuumRecord {
+	return t.vacuumHistory.list()
+}
+
+// vacuum starts vacuuming volumes while canStart allows, and waits for the
+// started ones to finish. It returns the number of volumes vacuumed.
+func (t *Topology) vacuum(garbageThreshold string, canStart func() bool) int {
+	glog.V(0).Infof("Start vacuum with threshold:%s", garbageThreshold)
+	options := t.vacuumOptions
+	var wg sync.WaitGroup
+	var vacuumed int32
+collections:
 	for _, col := range t.collectionMap.Items() {
 		c := col.(*Collection)
+		threshold := garbageThreshold
+		if threshold == "" {
+			threshold = options.garbageThreshold(c.Name)
+		}
 		for _, vl := range c.storageType2VolumeLayout.Items() {
-			if vl != nil {
-				volumeLayout := vl.(*VolumeLayout)
-				for vid, locationlist := range volumeLayout.vid2location {
+			if vl == nil {
+				continue
+			}
+			volumeLayout := vl.(*VolumeLayout)
+			for vid, locationlist := range volumeLayout.vacuumCandidates() {
+				if !canStart() {
+					glog.V(0).Infof("vacuum window closed, stop starting vacuums")
+					break collections
+				}
+				var servers []string
+				for _, dn := range locationlist.list {
+					servers = append(servers, dn.Url())
+				}
+				if !t.vacuumScheduler.acquire(vid, servers, options.ConcurrencyPerNode) {
+					continue
+				}
+				// checked after acquiring, which may have waited past the window
+				if !canStart() {
+					t.vacuumScheduler.release(vid, servers)
+					glog.V(0).Infof("vacuum window closed, stop starting vacuums")
+					break collections
+				}
+				wg.Add(1)
+				go func(collection string, volumeLayout *VolumeLayout, vid storage.VolumeId, locationlist *VolumeLocationList, servers []string, threshold string) {
+					defer wg.Done()
+					defer t.vacuumScheduler.release(vid, servers)
+					if t.vacuumVolume(collection, volumeLayout, vid, locationlist, servers, threshold, options.Preallocate) {
+						atomic.AddInt32(&vacuumed, 1)
+					}
+				}(c.Name, volumeLayout, vid, locationlist, servers, threshold)
+			}
+		}
+	}
+	wg.Wait()
+	return int(atomic.LoadInt32(&vacuumed))
+}
 
-					volumeLayout.accessLock.RLock()
-					isReadOnly, hasValue := volumeLayout.readonlyVolumes[vid]
-					volumeLayout.accessLock.RUnlock()
+// vacuumVolume returns true if the volume is vacuumed and committed on all its servers.
+func (t *Topology) vacuumVolume(collection string, volumeLayout *VolumeLayout, vid storage.VolumeId, locationlist *VolumeLocationList, servers []string, garbageThreshold string, preallocate int64) bool {
+	glog.V(0).Infof("check vacuum on collection:%s volume:%d", collection, vid)
+	if !batchVacuumVolumeCheck(volumeLayout, vid, locationlist, garbageThreshold) {
+		return false
+	}
+	start := time.Now()
+	result := "committed"
+	if batchVacuumVolumeCompact(volumeLayout, vid, locationlist, preallocate) {
+		if !batchVacuumVolumeCommit(volumeLayout, vid, locationlist) {
+			result = "commitFailed"
+		}
+	} else {
+		result = "compactFailed"
+	}
+	stats.MasterVacuumCounter.WithLabelValues(result).Inc()
+	t.vacuumHistory.add(VacuumRecord{
+		VolumeId:         vid,
+		Collection:       collection,
+		Servers:          servers,
+		GarbageThreshold: garbageThreshold,
+		Start:            start,
+		Seconds:          time.Since(start).Seconds(),
+		Result:           result,
+	})
+	return result == "committed"
+}
 
-					if hasValue && isReadOnly {
-						continue
-					}
+// vacuumCandidates returns a copy of the locations of the volumes not read only.
+func (vl *VolumeLayout) vacuumCandidates() map[storage.VolumeId]*VolumeLocationList {
+	vl.accessLock.RLock()
+	defer vl.accessLock.RUnlock()
 
-					glog.V(0).Infof("check vacuum on collection:%s volume:%d", c.Name, vid)
-					if batchVacuumVolumeCheck(volumeLayout, vid, locationlist, garbageThreshold) {
-						if batchVacuumVolumeCompact(volumeLayout, vid, locationlist, preallocate) {
-							batchVacuumVolumeCommit(volumeLayout, vid, locationlist)
-						}
-					}
-				}
-			}
+	ret := make(map[storage.VolumeId]*VolumeLocationList)
+	for vid, locationlist := range vl.vid2location {
+		if vl.readonlyVolumes[vid] {
+			continue
 		}
+		ret[vid] = &VolumeLocationList{list: append([]*DataNode(nil), locationlist.list...)}
 	}
-	return 0
+	return ret
 }
 
 type VacuumVolumeResult struct {
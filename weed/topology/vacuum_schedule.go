@@ -0,0 +1,217 @@
+package topology
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+// VacuumOptions configures the periodic vacuum run by the leader.
+type VacuumOptions struct {
+	GarbageThreshold string // garbage ratio to vacuum a volume
+	// garbage ratio per collection, overriding GarbageThreshold
+	CollectionThresholds map[string]string
+	// local times of day to start vacuuming volumes, any time if empty
+	Windows            []VacuumWindow
+	Interval           time.Duration
+	ConcurrencyPerNode int // volumes vacuumed at the same time on one volume server
+	Preallocate        int64
+}
+
+func NewVacuumOptions(garbageThreshold string) VacuumOptions {
+	return VacuumOptions{
+		GarbageThreshold:   garbageThreshold,
+		Interval:           15 * time.Minute,
+		ConcurrencyPerNode: 1,
+	}
+}
+
+func (o *VacuumOptions) garbageThreshold(collection string) string {
+	if threshold, ok := o.CollectionThresholds[collection]; ok {
+		return threshold
+	}
+	return o.GarbageThreshold
+}
+
+func (o *VacuumOptions) inWindow(now time.Time) bool {
+	if len(o.Windows) == 0 {
+		return true
+	}
+	for _, w := range o.Windows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// VacuumWindow is a time range of the day, in minutes since midnight.
+// It spans midnight if End is before Start.
+type VacuumWindow struct {
+	Start, End int
+}
+
+func (w VacuumWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start <= w.End {
+		return w.Start <= minute && minute < w.End
+	}
+	return w.Start <= minute || minute < w.End
+}
+
+func (w VacuumWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+// ParseVacuumWindows parses comma separated time ranges, like "01:00-05:00,22:30-23:30".
+func ParseVacuumWindows(windows string) (ret []VacuumWindow, err error) {
+	if windows == "" {
+		return nil, nil
+	}
+	for _, window := range strings.Split(windows, ",") {
+		parts := strings.Split(strings.TrimSpace(window), "-")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("vacuum window %q is not like 01:00-05:00", window)
+		}
+		var w VacuumWindow
+		if w.Start, err = parseMinuteOfDay(parts[0]); err != nil {
+			return nil, fmt.Errorf("vacuum window %q: %v", window, err)
+		}
+		if w.End, err = parseMinuteOfDay(parts[1]); err != nil {
+			return nil, fmt.Errorf("vacuum window %q: %v", window, err)
+		}
+		ret = append(ret, w)
+	}
+	return ret, nil
+}
+
+func parseMinuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseCollectionThresholds parses comma separated collection:threshold pairs,
+// like "logs:0.5,pictures:0.2".
+func ParseCollectionThresholds(thresholds string) (map[string]string, error) {
+	ret := make(map[string]string)
+	if thresholds == "" {
+		return ret, nil
+	}
+	for _, pair := range strings.Split(thresholds, ",") {
+		i := strings.LastIndex(pair, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("%q is not like collection:threshold", pair)
+		}
+		collection, threshold := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if _, err := strconv.ParseFloat(threshold, 32); err != nil {
+			return nil, fmt.Errorf("garbage threshold of collection %q: %v", collection, err)
+		}
+		ret[collection] = threshold
+	}
+	return ret, nil
+}
+
+// vacuumScheduler limits the volumes vacuumed at the same time on each
+// volume server, and keeps a volume from being vacuumed twice at once.
+type vacuumScheduler struct {
+	sync.Mutex
+	cond    *sync.Cond
+	running map[string]int
+	volumes map[storage.VolumeId]bool
+}
+
+func newVacuumScheduler() *vacuumScheduler {
+	s := &vacuumScheduler{
+		running: make(map[string]int),
+		volumes: make(map[storage.VolumeId]bool),
+	}
+	s.cond = sync.NewCond(s)
+	return s
+}
+
+// acquire waits until all the servers can vacuum one more volume.
+// It returns false if the volume is being vacuumed already.
+func (s *vacuumScheduler) acquire(vid storage.VolumeId, servers []string, concurrencyPerNode int) bool {
+	s.Lock()
+	defer s.Unlock()
+	for {
+		if s.volumes[vid] {
+			return false
+		}
+		if s.hasSlots(servers, concurrencyPerNode) {
+			break
+		}
+		s.cond.Wait()
+	}
+	s.volumes[vid] = true
+	for _, server := range servers {
+		s.running[server]++
+	}
+	return true
+}
+
+func (s *vacuumScheduler) hasSlots(servers []string, concurrencyPerNode int) bool {
+	for _, server := range servers {
+		if s.running[server] >= concurrencyPerNode {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *vacuumScheduler) release(vid storage.VolumeId, servers []string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.volumes, vid)
+	for _, server := range servers {
+		if s.running[server]--; s.running[server] <= 0 {
+			delete(s.running, server)
+		}
+	}
+	s.cond.Broadcast()
+}
+
+const maxVacuumHistory = 256
+
+// VacuumRecord describes the vacuum of one volume on all its replicas.
+type VacuumRecord struct {
+	VolumeId         storage.VolumeId `json:"volumeId"`
+	Collection       string           `json:"collection"`
+	Servers          []string         `json:"servers"`
+	GarbageThreshold string           `json:"garbageThreshold"`
+	Start            time.Time        `json:"start"`
+	Seconds          float64          `json:"seconds"`
+	Result           string           `json:"result"`
+}
+
+type vacuumHistory struct {
+	sync.Mutex
+	records []VacuumRecord
+}
+
+func (h *vacuumHistory) add(record VacuumRecord) {
+	h.Lock()
+	defer h.Unlock()
+	h.records = append(h.records, record)
+	if len(h.records) > maxVacuumHistory {
+		h.records = h.records[len(h.records)-maxVacuumHistory:]
+	}
+}
+
+// list returns the records, the latest first.
+func (h *vacuumHistory) list() []VacuumRecord {
+	h.Lock()
+	defer h.Unlock()
+	ret := make([]VacuumRecord, 0, len(h.records))
+	for i := len(h.records) - 1; i >= 0; i-- {
+		ret = append(ret, h.records[i])
+	}
+	return ret
+}
@@ -0,0 +1,79 @@
+package topology
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVacuumWindows(t *testing.T) {
+	windows, err := ParseVacuumWindows("01:00-05:00, 22:30-00:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := VacuumOptions{Windows: windows}
+	for clock, expected := range map[string]bool{
+		"00:59": false,
+		"01:00": true,
+		"04:59": true,
+		"05:00": false,
+		"22:29": false,
+		"23:45": true,
+		"00:15": true,
+		"00:30": false,
+	} {
+		now, _ := time.Parse("15:04", clock)
+		if options.inWindow(now) != expected {
+			t.Errorf("%s in window: expected %v", clock, expected)
+		}
+	}
+
+	for _, bad := range []string{"01:00", "1-5", "25:00-26:00"} {
+		if _, err := ParseVacuumWindows(bad); err == nil {
+			t.Errorf("window %q accepted", bad)
+		}
+	}
+}
+
+func TestVacuumCollectionThresholds(t *testing.T) {
+	thresholds, err := ParseCollectionThresholds("logs:0.5,pictures:0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := NewVacuumOptions("0.3")
+	options.CollectionThresholds = thresholds
+	if options.garbageThreshold("logs") != "0.5" || options.garbageThreshold("other") != "0.3" {
+		t.Errorf("unexpected thresholds %v", thresholds)
+	}
+	if _, err := ParseCollectionThresholds("logs:half"); err == nil {
+		t.Errorf("invalid threshold accepted")
+	}
+}
+
+func TestVacuumSchedulerConcurrency(t *testing.T) {
+	s := newVacuumScheduler()
+	if !s.acquire(1, []string{"a", "b"}, 1) {
+		t.Fatal("first volume not scheduled")
+	}
+	if s.acquire(1, []string{"a", "b"}, 2) {
+		t.Fatal("volume scheduled twice")
+	}
+
+	started := make(chan bool)
+	go func() {
+		started <- s.acquire(2, []string{"b", "c"}, 1)
+	}()
+	select {
+	case <-started:
+		t.Fatal("second volume started beyond the limit of server b")
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.release(1, []string{"a", "b"})
+	select {
+	case ok := <-started:
+		if !ok {
+			t.Fatal("second volume not scheduled")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second volume not started after the first finished")
+	}
+}
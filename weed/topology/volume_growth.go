@@ -2,7 +2,6 @@ package topology
 
 import (
 	"fmt"
-	"math/rand"
 	"sync"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
@@ -29,6 +28,10 @@ type VolumeGrowOption struct {
 
 type VolumeGrowth struct {
 	accessLock sync.Mutex
+	placement  PlacementStrategy // unless the collection settings choose another one
+	// simulate only registers the new volumes in the topology,
+	// without allocating them on the volume servers
+	simulate bool
 }
 
 func (o *VolumeGrowOption) String() string {
@@ -36,7 +39,26 @@ func (o *VolumeGrowOption) String() string {
 }
 
 func NewDefaultVolumeGrowth() *VolumeGrowth {
-	return &VolumeGrowth{}
+	return NewVolumeGrowth(randomPlacement{})
+}
+
+func NewVolumeGrowth(placement PlacementStrategy) *VolumeGrowth {
+	return &VolumeGrowth{placement: placement}
+}
+
+// NewSimulatedVolumeGrowth grows volumes only in the topology, so a placement
+// strategy can be tried out on a topology built for it, like in the tests.
+func NewSimulatedVolumeGrowth(placement PlacementStrategy) *VolumeGrowth {
+	return &VolumeGrowth{placement: placement, simulate: true}
+}
+
+func (vg *VolumeGrowth) placementStrategy(topo *Topology, collection string) PlacementStrategy {
+	if settings, ok := topo.ClusterConfig.CollectionSettings(collection); ok && settings.Placement != "" {
+		if strategy, err := NewPlacementStrategy(settings.Placement); err == nil {
+			return strategy
+		}
+	}
+	return vg.placement
 }
 
 // one replication type may need rp.GetCopyCount() actual volumes
@@ -81,7 +103,13 @@ func (vg *VolumeGrowth) findAndGrow(topo *Topology, option *VolumeGrowOption) (i
 	if e != nil {
 		return 0, e
 	}
-	vid := topo.NextVolumeId()
+	var vid storage.VolumeId
+	if vg.simulate {
+		vid = topo.GetMaxVolumeId()
+		vid = vid.Next()
+	} else {
+		vid = topo.NextVolumeId()
+	}
 	err := vg.grow(topo, vid, option, servers...)
 	return len(servers), err
 }
@@ -91,10 +119,13 @@ func (vg *VolumeGrowth) findAndGrow(topo *Topology, option *VolumeGrowOption) (i
 // 2.2 collect all racks that have rp.SameRackCount+1
 // 2.2 collect all data centers that have DiffRackCount+rp.SameRackCount+1
 // 2. find rest data nodes
+// The placement strategy of the collection chooses among the matching nodes.
 func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *VolumeGrowOption) (servers []*DataNode, err error) {
+	strategy := vg.placementStrategy(topo, option.Collection)
+
 	//find main datacenter and other data centers
 	rp := option.ReplicaPlacement
-	mainDataCenter, otherDataCenters, dc_err := topo.RandomlyPickNodes(rp.DiffDataCenterCount+1, func(node Node) error {
+	mainDataCenter, otherDataCenters, dc_err := pickNodes(strategy, option, topo, rp.DiffDataCenterCount+1, func(node Node) error {
 		if option.DataCenter != "" && node.IsDataCenter() && node.Id() != NodeId(option.DataCenter) {
 			return fmt.Errorf("Not matching preferred data center:%s", option.DataCenter)
 		}
@@ -126,7 +157,7 @@ func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *Volum
 	}
 
 	//find main rack and other racks
-	mainRack, otherRacks, rack_err := mainDataCenter.(*DataCenter).RandomlyPickNodes(rp.DiffRackCount+1, func(node Node) error {
+	mainRack, otherRacks, rack_err := pickNodes(strategy, option, mainDataCenter, rp.DiffRackCount+1, func(node Node) error {
 		if option.Rack != "" && node.IsRack() && node.Id() != NodeId(option.Rack) {
 			return fmt.Errorf("Not matching preferred rack:%s", option.Rack)
 		}
@@ -153,13 +184,16 @@ func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *Volum
 	}
 
 	//find main rack and other racks
-	mainServer, otherServers, server_err := mainRack.(*Rack).RandomlyPickNodes(rp.SameRackCount+1, func(node Node) error {
+	mainServer, otherServers, server_err := pickNodes(strategy, option, mainRack, rp.SameRackCount+1, func(node Node) error {
 		if option.DataNode != "" && node.IsDataNode() && node.Id() != NodeId(option.DataNode) {
 			return fmt.Errorf("Not matching preferred data node:%s", option.DataNode)
 		}
 		if node.FreeSpace() < 1 {
 			return fmt.Errorf("Free:%d < Expected:%d", node.FreeSpace(), 1)
 		}
+		if node.IsDataNode() && node.(*DataNode).IsDraining() {
+			return fmt.Errorf("Data node %s is draining", node.Id())
+		}
 		return nil
 	})
 	if server_err != nil {
@@ -171,16 +205,14 @@ func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *Volum
 		servers = append(servers, server.(*DataNode))
 	}
 	for _, rack := range otherRacks {
-		r := rand.Intn(rack.FreeSpace())
-		if server, e := rack.ReserveOneVolume(r); e == nil {
+		if server, e := pickOneDataNode(strategy, option, rack); e == nil {
 			servers = append(servers, server)
 		} else {
 			return servers, e
 		}
 	}
 	for _, datacenter := range otherDataCenters {
-		r := rand.Intn(datacenter.FreeSpace())
-		if server, e := datacenter.ReserveOneVolume(r); e == nil {
+		if server, e := pickOneDataNode(strategy, option, datacenter); e == nil {
 			servers = append(servers, server)
 		} else {
 			return servers, e
@@ -190,8 +222,13 @@ func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *Volum
 }
 
 func (vg *VolumeGrowth) grow(topo *Topology, vid storage.VolumeId, option *VolumeGrowOption, servers ...*DataNode) error {
+	settings, _ := topo.ClusterConfig.CollectionSettings(option.Collection)
 	for _, server := range servers {
-		if err := AllocateVolume(server, vid, option); err == nil {
+		var err error
+		if !vg.simulate {
+			err = AllocateVolume(server, vid, option, settings.Dedup)
+		}
+		if err == nil {
 			vi := storage.VolumeInfo{
 				Id:               vid,
 				Size:             0,
@@ -18,6 +18,8 @@ type VolumeLayout struct {
 	writables        []storage.VolumeId        // transient array of writable volume id
 	readonlyVolumes  map[storage.VolumeId]bool // transient set of readonly volumes
 	oversizedVolumes map[storage.VolumeId]bool // set of oversized volumes
+	expiringVolumes  map[storage.VolumeId]bool // set of expired ttl volumes, waiting to be deleted
+	movingVolumes    map[storage.VolumeId]bool // set of volumes being moved to another server
 	volumeSizeLimit  uint64
 	accessLock       sync.RWMutex
 }
@@ -30,6 +32,8 @@ func NewVolumeLayout(rp *storage.ReplicaPlacement, ttl *storage.TTL, volumeSizeL
 		writables:        *new([]storage.VolumeId),
 		readonlyVolumes:  make(map[storage.VolumeId]bool),
 		oversizedVolumes: make(map[storage.VolumeId]bool),
+		expiringVolumes:  make(map[storage.VolumeId]bool),
+		movingVolumes:    make(map[storage.VolumeId]bool),
 		volumeSizeLimit:  volumeSizeLimit,
 	}
 }
@@ -65,7 +69,7 @@ func (vl *VolumeLayout) RegisterVolume(v *storage.VolumeInfo, dn *DataNode) {
 		}
 	}
 	if vl.vid2location[v.Id].Length() == vl.rp.GetCopyCount() && vl.isWritable(v) {
-		if _, ok := vl.oversizedVolumes[v.Id]; !ok {
+		if _, ok := vl.oversizedVolumes[v.Id]; !ok && !vl.expiringVolumes[v.Id] && !vl.movingVolumes[v.Id] {
 			vl.addToWritable(v.Id)
 		}
 	} else {
@@ -86,6 +90,7 @@ func (vl *VolumeLayout) UnRegisterVolume(v *storage.VolumeInfo, dn *DataNode) {
 
 	vl.removeFromWritable(v.Id)
 	delete(vl.vid2location, v.Id)
+	delete(vl.expiringVolumes, v.Id)
 }
 
 func (vl *VolumeLayout) addToWritable(vid storage.VolumeId) {
@@ -0,0 +1,129 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+/*
+MoveVolume moves one replica of a volume from the source to the target volume server:
+1. The volume stops taking new writes, so no file id is assigned on it during the move.
+2. The target pulls the volume from the source, catching up the writes during the copy.
+3. The source is marked read-only, and the target catches up the writes made with
+   file ids assigned before the move.
+4. The location of the volume is switched from the source to the target.
+5. The volume is deleted from the source, and takes new writes again.
+*/
+func (t *Topology) MoveVolume(vid storage.VolumeId, sourceUrl, targetUrl string) (source, target *DataNode, err error) {
+	for _, dn := range t.Lookup("", vid) {
+		if dn.Url() == sourceUrl {
+			source = dn
+		}
+		if dn.Url() == targetUrl {
+			return nil, nil, fmt.Errorf("volume %d is already on %s", vid, targetUrl)
+		}
+	}
+	if source == nil {
+		return nil, nil, fmt.Errorf("volume %d is not on %s", vid, sourceUrl)
+	}
+	for _, dn := range t.DataNodes() {
+		if dn.Url() == targetUrl {
+			target = dn
+		}
+	}
+	if target == nil {
+		return nil, nil, fmt.Errorf("unknown volume server %s", targetUrl)
+	}
+	if target.IsDraining() {
+		return nil, nil, fmt.Errorf("volume server %s is draining", targetUrl)
+	}
+	if target.FreeSpace() <= 0 {
+		return nil, nil, fmt.Errorf("volume server %s has no free volume slot", targetUrl)
+	}
+	vi, err := source.GetVolumesById(vid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vl := t.GetVolumeLayout(vi.Collection, vi.ReplicaPlacement, vi.Ttl)
+	if !vl.setVolumeMoving(vid) {
+		return nil, nil, fmt.Errorf("volume %d is being moved already", vid)
+	}
+	defer vl.setVolumeMoved(&vi)
+
+	glog.V(0).Infof("moving volume %d from %s to %s", vid, sourceUrl, targetUrl)
+	if err = operation.CopyVolume(targetUrl, vid.String(), sourceUrl); err != nil {
+		return nil, nil, fmt.Errorf("copy volume %d to %s: %v", vid, targetUrl, err)
+	}
+	if err = operation.MarkVolumeReadOnly(sourceUrl, vid.String(), true); err != nil {
+		abortVolumeMove(vid, sourceUrl, targetUrl)
+		return nil, nil, fmt.Errorf("mark volume %d read-only on %s: %v", vid, sourceUrl, err)
+	}
+	if err = operation.SyncVolume(targetUrl, vid.String(), sourceUrl); err != nil {
+		abortVolumeMove(vid, sourceUrl, targetUrl)
+		return nil, nil, fmt.Errorf("catch up volume %d on %s: %v", vid, targetUrl, err)
+	}
+
+	target.AddOrUpdateVolume(vi)
+	source.DeleteVolumeById(vid)
+	vl.moveVolumeLocation(vid, source, target)
+
+	if err = operation.DeleteVolume(sourceUrl, vid.String()); err != nil {
+		return source, target, fmt.Errorf("volume %d moved to %s, but not deleted from %s: %v", vid, targetUrl, sourceUrl, err)
+	}
+	glog.V(0).Infof("moved volume %d from %s to %s", vid, sourceUrl, targetUrl)
+	return source, target, nil
+}
+
+// abortVolumeMove deletes the copy of the volume from the target,
+// and lets the source take writes again.
+func abortVolumeMove(vid storage.VolumeId, sourceUrl, targetUrl string) {
+	if err := operation.DeleteVolume(targetUrl, vid.String()); err != nil {
+		glog.V(0).Infof("delete the copy of volume %d from %s: %v", vid, targetUrl, err)
+	}
+	if err := operation.MarkVolumeReadOnly(sourceUrl, vid.String(), false); err != nil {
+		glog.V(0).Infof("mark volume %d writable on %s: %v", vid, sourceUrl, err)
+	}
+}
+
+// setVolumeMoving takes the volume out of the writables,
+// and returns false if it was being moved already.
+func (vl *VolumeLayout) setVolumeMoving(vid storage.VolumeId) bool {
+	vl.accessLock.Lock()
+	defer vl.accessLock.Unlock()
+
+	if vl.movingVolumes[vid] {
+		return false
+	}
+	vl.movingVolumes[vid] = true
+	vl.removeFromWritable(vid)
+	return true
+}
+
+// setVolumeMoved makes the volume writable again if it has all its replicas.
+func (vl *VolumeLayout) setVolumeMoved(v *storage.VolumeInfo) {
+	vl.accessLock.Lock()
+	defer vl.accessLock.Unlock()
+
+	delete(vl.movingVolumes, v.Id)
+	location, ok := vl.vid2location[v.Id]
+	if !ok || location.Length() != vl.rp.GetCopyCount() || !vl.isWritable(v) {
+		return
+	}
+	if _, ok := vl.oversizedVolumes[v.Id]; !ok && !vl.expiringVolumes[v.Id] && !vl.readonlyVolumes[v.Id] {
+		vl.addToWritable(v.Id)
+	}
+}
+
+func (vl *VolumeLayout) moveVolumeLocation(vid storage.VolumeId, source, target *DataNode) {
+	vl.accessLock.Lock()
+	defer vl.accessLock.Unlock()
+
+	if location, ok := vl.vid2location[vid]; ok {
+		location.Remove(source)
+		location.Set(target)
+	}
+}
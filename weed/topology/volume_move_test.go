@@ -0,0 +1,76 @@
+package topology
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/sequence"
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+func TestMoveVolume(t *testing.T) {
+	// the admin requests on volume 1, in the order received by both servers
+	var lock sync.Mutex
+	var requests []string
+	volumeServer := func(name string) (*httptest.Server, string, int) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.FormValue("volume") == "1" {
+				lock.Lock()
+				requests = append(requests, name+" "+r.URL.Path+" "+r.FormValue("readonly"))
+				lock.Unlock()
+			}
+		}))
+		host, portString, _ := net.SplitHostPort(server.Listener.Addr().String())
+		port, _ := strconv.Atoi(portString)
+		return server, host, port
+	}
+	sourceServer, sourceHost, sourcePort := volumeServer("source")
+	defer sourceServer.Close()
+	targetServer, targetHost, targetPort := volumeServer("target")
+	defer targetServer.Close()
+
+	topo := NewTopology("weedfs", sequence.NewMemorySequencer(), 32*1024, 5)
+	dc := NewDataCenter("dc1")
+	topo.LinkChildNode(dc)
+	rack := NewRack("rack1")
+	dc.LinkChildNode(rack)
+	source := rack.GetOrCreateDataNode(sourceHost, sourcePort, sourceHost, 10)
+	target := rack.GetOrCreateDataNode(targetHost, targetPort, targetHost, 10)
+
+	rp, _ := storage.NewReplicaPlacementFromString("000")
+	vi := storage.VolumeInfo{Id: 1, Size: 1000, ReplicaPlacement: rp, Ttl: storage.EMPTY_TTL, Version: storage.CurrentVersion}
+	source.AddOrUpdateVolume(vi)
+	topo.RegisterVolumeLayout(vi, source)
+	vl := topo.GetVolumeLayout("", rp, storage.EMPTY_TTL)
+
+	if _, _, err := topo.MoveVolume(1, target.Url(), source.Url()); err == nil {
+		t.Fatalf("moved volume 1 from a server without it")
+	}
+	if _, _, err := topo.MoveVolume(1, source.Url(), target.Url()); err != nil {
+		t.Fatalf("move volume 1: %v", err)
+	}
+	expected := []string{
+		"target /admin/volume/copy ",
+		"source /admin/volume/readonly true",
+		"target /admin/volume/sync ",
+		"source /admin/volume/delete ",
+	}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected the requests %q, got %q", expected, requests)
+	}
+	if locations := vl.Lookup(1); len(locations) != 1 || locations[0] != target {
+		t.Errorf("volume 1 is on %v, expected only %s", locations, target.Url())
+	}
+	if source.GetVolumeCount() != 0 || target.GetVolumeCount() != 1 {
+		t.Errorf("expected the volume on the target only, got %d on source and %d on target",
+			source.GetVolumeCount(), target.GetVolumeCount())
+	}
+	if len(vl.writables) != 1 || vl.movingVolumes[1] {
+		t.Errorf("moved volume 1 is not writable again")
+	}
+}
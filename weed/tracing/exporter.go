@@ -0,0 +1,184 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+const (
+	exportBatchSize     = 512
+	exportQueueSize     = 4096
+	exportFlushInterval = 2 * time.Second
+)
+
+var currentExporter *exporter
+
+// exporter batches finished spans and sends them to an OTLP/HTTP
+// collector endpoint, using the JSON encoding of the OTLP protocol.
+type exporter struct {
+	serviceName string
+	endpoint    string
+	sampleRate  float64
+	spans       chan *Span
+	client      *http.Client
+}
+
+// Init enables tracing for this process. Spans are sent to the OTLP/HTTP
+// endpoint, e.g. http://localhost:4318. A root span is sampled with the
+// given rate; child spans follow the decision of their parent.
+func Init(serviceName string, endpoint string, sampleRate float64) {
+	if endpoint == "" {
+		return
+	}
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "http://" + endpoint
+	}
+	e := &exporter{
+		serviceName: serviceName,
+		endpoint:    strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		sampleRate:  sampleRate,
+		spans:       make(chan *Span, exportQueueSize),
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	glog.V(0).Infof("%s exports traces to %s with sample rate %v", serviceName, e.endpoint, sampleRate)
+	go e.loop()
+	currentExporter = e
+}
+
+func (e *exporter) shouldSample() bool {
+	return e.sampleRate >= 1 || (e.sampleRate > 0 && rand.Float64() < e.sampleRate)
+}
+
+func (e *exporter) export(s *Span) {
+	select {
+	case e.spans <- s:
+	default:
+		glog.V(1).Infof("trace export queue is full, dropping span %s", s.name)
+	}
+}
+
+func (e *exporter) loop() {
+	var batch []*Span
+	ticker := time.NewTicker(exportFlushInterval)
+	for {
+		select {
+		case s := <-e.spans:
+			batch = append(batch, s)
+			if len(batch) < exportBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := e.send(batch); err != nil {
+			glog.V(0).Infof("failed to export %d spans to %s: %v", len(batch), e.endpoint, err)
+		}
+		batch = nil
+	}
+}
+
+func (e *exporter) send(batch []*Span) error {
+	body, err := json.Marshal(e.toRequest(batch))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s: %s", e.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// the types below mirror the JSON mapping of the OTLP trace protobuf messages
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func newKeyValue(key, value string) (kv otlpKeyValue) {
+	kv.Key = key
+	kv.Value.StringValue = value
+	return
+}
+
+func (e *exporter) toRequest(batch []*Span) *otlpExportRequest {
+	scopeSpans := otlpScopeSpans{}
+	scopeSpans.Scope.Name = "github.com/chrislusf/seaweedfs/weed/tracing"
+	for _, s := range batch {
+		s.Lock()
+		span := otlpSpan{
+			TraceId:           s.context.TraceId.String(),
+			SpanId:            s.context.SpanId.String(),
+			Name:              s.name,
+			Kind:              int(s.kind),
+			StartTimeUnixNano: strconv.FormatInt(s.startTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.endTime.UnixNano(), 10),
+		}
+		if s.parentSpanId.IsValid() {
+			span.ParentSpanId = s.parentSpanId.String()
+		}
+		for k, v := range s.attributes {
+			span.Attributes = append(span.Attributes, newKeyValue(k, v))
+		}
+		if s.err != nil {
+			span.Status = &otlpStatus{Code: 2, Message: s.err.Error()}
+		}
+		s.Unlock()
+		scopeSpans.Spans = append(scopeSpans.Spans, span)
+	}
+	resourceSpans := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scopeSpans}}
+	resourceSpans.Resource.Attributes = []otlpKeyValue{newKeyValue("service.name", e.serviceName)}
+	return &otlpExportRequest{ResourceSpans: []otlpResourceSpans{resourceSpans}}
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerInterceptor continues the caller's trace for each unary gRPC call.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(TraceParentHeader); len(values) > 0 {
+				if sc, ok := ParseTraceParent(values[0]); ok {
+					ctx = ContextWithRemoteSpanContext(ctx, sc)
+				}
+			}
+		}
+		ctx, span := StartSpan(ctx, info.FullMethod, SpanKindServer)
+		defer span.End()
+		resp, err := handler(ctx, req)
+		span.SetError(err)
+		return resp, err
+	}
+}
+
+// UnaryClientInterceptor propagates the current trace to the called gRPC server.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := StartSpan(ctx, method, SpanKindClient)
+		defer span.End()
+		if sc := SpanContextFromContext(ctx); sc.IsValid() {
+			ctx = metadata.AppendToOutgoingContext(ctx, TraceParentHeader, FormatTraceParent(sc))
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		span.SetError(err)
+		return err
+	}
+}
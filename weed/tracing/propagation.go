@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceParentHeader is the W3C trace context header understood by
+// OpenTelemetry collectors and SDKs.
+const TraceParentHeader = "traceparent"
+
+func FormatTraceParent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceId, sc.SpanId, flags)
+}
+
+func ParseTraceParent(value string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return
+	}
+	if _, err := hex.Decode(sc.TraceId[:], []byte(parts[1])); err != nil {
+		return
+	}
+	if _, err := hex.Decode(sc.SpanId[:], []byte(parts[2])); err != nil {
+		return
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+	return sc, sc.IsValid()
+}
+
+// Inject writes the current span context in ctx into the outgoing request headers.
+func Inject(ctx context.Context, header http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		header.Set(TraceParentHeader, FormatTraceParent(sc))
+	}
+}
+
+// Extract returns ctx carrying the remote span context of the incoming request headers, if any.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if sc, ok := ParseTraceParent(header.Get(TraceParentHeader)); ok {
+		return ContextWithRemoteSpanContext(ctx, sc)
+	}
+	return ctx
+}
+
+// StartServerSpan starts a server span for an incoming http request,
+// continuing the trace of the caller. The returned request carries the span.
+func StartServerSpan(r *http.Request, name string) (*http.Request, *Span) {
+	ctx, span := StartSpan(Extract(r.Context(), r.Header), name, SpanKindServer)
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.target", r.URL.Path)
+	return r.WithContext(ctx), span
+}
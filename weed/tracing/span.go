@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type TraceId [16]byte
+type SpanId [8]byte
+
+func (t TraceId) String() string { return hex.EncodeToString(t[:]) }
+func (t TraceId) IsValid() bool  { return t != TraceId{} }
+func (s SpanId) String() string  { return hex.EncodeToString(s[:]) }
+func (s SpanId) IsValid() bool   { return s != SpanId{} }
+
+// SpanContext identifies a span within a trace. It is what travels
+// between servers in the "traceparent" header.
+type SpanContext struct {
+	TraceId TraceId
+	SpanId  SpanId
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceId.IsValid() && sc.SpanId.IsValid()
+}
+
+type SpanKind int
+
+// values follow the OTLP span kind enumeration
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+type Span struct {
+	sync.Mutex
+	name         string
+	kind         SpanKind
+	context      SpanContext
+	parentSpanId SpanId
+	startTime    time.Time
+	endTime      time.Time
+	attributes   map[string]string
+	err          error
+}
+
+type contextKey int
+
+const (
+	spanKey contextKey = iota
+	remoteSpanContextKey
+)
+
+// StartSpan starts a child span of the span carried by ctx, or of the
+// remote span extracted from an incoming request. It returns a nil span,
+// whose methods are all no-ops, if tracing is not enabled.
+func StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if currentExporter == nil {
+		return ctx, nil
+	}
+	parent := SpanContextFromContext(ctx)
+	s := &Span{
+		name:       name,
+		kind:       kind,
+		startTime:  time.Now(),
+		attributes: make(map[string]string),
+	}
+	if parent.IsValid() {
+		s.context.TraceId = parent.TraceId
+		s.context.Sampled = parent.Sampled
+		s.parentSpanId = parent.SpanId
+	} else {
+		rand.Read(s.context.TraceId[:])
+		s.context.Sampled = currentExporter.shouldSample()
+	}
+	rand.Read(s.context.SpanId[:])
+	return context.WithValue(ctx, spanKey, s), s
+}
+
+// SpanContextFromContext returns the context of the current local span,
+// falling back to a remote parent extracted from an incoming request.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if s, ok := ctx.Value(spanKey).(*Span); ok && s != nil {
+		return s.context
+	}
+	if sc, ok := ctx.Value(remoteSpanContextKey).(SpanContext); ok {
+		return sc
+	}
+	return SpanContext{}
+}
+
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanContextKey, sc)
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	s.attributes[key] = value
+	s.Unlock()
+}
+
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.Lock()
+	s.err = err
+	s.Unlock()
+}
+
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	s.endTime = time.Now()
+	s.Unlock()
+	if s.context.Sampled && currentExporter != nil {
+		currentExporter.export(s)
+	}
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	sc, ok := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		t.Fatal("failed to parse traceparent")
+	}
+	if sc.TraceId.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanId.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if got := FormatTraceParent(sc); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("unexpected traceparent %s", got)
+	}
+	for _, invalid := range []string{"", "00-abc-def-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"} {
+		if _, ok := ParseTraceParent(invalid); ok {
+			t.Errorf("traceparent %q should be invalid", invalid)
+		}
+	}
+}
+
+func TestExportToCollector(t *testing.T) {
+	received := make(chan otlpSpan, 16)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req otlpExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode export request: %v", err)
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					received <- span
+				}
+			}
+		}
+	}))
+	defer collector.Close()
+
+	Init("weed-test", collector.URL, 1)
+	defer func() { currentExporter = nil }()
+
+	header := make(http.Header)
+	header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, parent := StartSpan(Extract(context.Background(), header), "parent", SpanKindServer)
+	_, child := StartSpan(ctx, "child", SpanKindClient)
+	child.End()
+	parent.End()
+
+	var spans []otlpSpan
+	for len(spans) < 2 {
+		select {
+		case span := <-received:
+			spans = append(spans, span)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected 2 exported spans, got %d", len(spans))
+		}
+	}
+	if spans[0].Name != "child" || spans[0].ParentSpanId != spans[1].SpanId {
+		t.Errorf("child span is not linked to its parent: %+v", spans)
+	}
+	if spans[1].ParentSpanId != "00f067aa0ba902b7" || spans[1].TraceId != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("parent span does not continue the remote trace: %+v", spans[1])
+	}
+}
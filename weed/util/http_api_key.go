@@ -0,0 +1,30 @@
+package util
+
+import (
+	"net/http"
+
+	"github.com/chrislusf/seaweedfs/weed/security"
+)
+
+var apiKey string
+
+// SetApiKey sets the api key this process sends to the other servers of the
+// cluster requiring credentials.
+func SetApiKey(key string) {
+	apiKey = key
+}
+
+// GetApiKey returns the api key set by SetApiKey.
+func GetApiKey() string {
+	return apiKey
+}
+
+// AddApiKey adds the api key set by SetApiKey to the header of a request to
+// another server of the cluster, unless the request carries its own key.
+// Only the helpers calling the servers of the cluster add it, Do does not,
+// so the key is not sent to any other url.
+func AddApiKey(header http.Header) {
+	if apiKey != "" && header.Get(security.ApiKeyHeader) == "" {
+		header.Set(security.ApiKeyHeader, apiKey)
+	}
+}
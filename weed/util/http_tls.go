@@ -0,0 +1,38 @@
+package util
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+var clientTLSConfig *tls.Config
+
+// EnableTLS makes the shared http transport reach other servers over TLS.
+// Urls are built with "http://" all over the code base, so requests with
+// the "http" scheme are upgraded to "https" by the transport.
+func EnableTLS(config *tls.Config) {
+	clientTLSConfig = config
+	UpgradeToTLS(Transport)
+}
+
+// UpgradeToTLS makes t send "http://" requests over TLS if TLS is enabled.
+func UpgradeToTLS(t *http.Transport) {
+	if clientTLSConfig == nil {
+		return
+	}
+	t.TLSClientConfig = clientTLSConfig
+	t.RegisterProtocol("http", &httpsUpgrader{transport: t})
+}
+
+type httpsUpgrader struct {
+	transport *http.Transport
+}
+
+func (u *httpsUpgrader) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := new(http.Request)
+	*r = *req
+	secureUrl := *req.URL
+	secureUrl.Scheme = "https"
+	r.URL = &secureUrl
+	return u.transport.RoundTrip(r)
+}
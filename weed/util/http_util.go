@@ -2,6 +2,7 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"crypto/rand"
 	"encoding/base64"
@@ -20,6 +21,7 @@ import (
 	"os/exec"
 
 	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 )
 
 var (
@@ -34,8 +36,26 @@ func init() {
 	client = &http.Client{Transport: Transport}
 }
 
+// clusterRequest is a request to another server of the cluster, with the api key
+// of this process. The exported helpers below, except Do, only call such servers.
+func clusterRequest(method string, url string, contentType string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	AddApiKey(req.Header)
+	return req, nil
+}
+
 func PostBytes(url string, body []byte) ([]byte, error) {
-	r, err := client.Post(url, "application/octet-stream", bytes.NewReader(body))
+	req, err := clusterRequest("POST", url, "application/octet-stream", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("Post to %s: %v", url, err)
 	}
@@ -51,14 +71,24 @@ func PostBytes(url string, body []byte) ([]byte, error) {
 }
 
 func Post(url string, values url.Values) ([]byte, error) {
-	r, err := client.PostForm(url, values)
+	return PostContext(context.Background(), url, values)
+}
+
+// PostContext is like Post, but propagates the trace carried by ctx.
+func PostContext(ctx context.Context, url string, values url.Values) ([]byte, error) {
+	req, err := clusterRequest("POST", url, "application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	tracing.Inject(ctx, req.Header)
+	r, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer r.Body.Close()
 	b, err := ioutil.ReadAll(r.Body)
 	if r.StatusCode >= 400 {
-		if err != nil {
+		if err == nil {
 			return nil, fmt.Errorf("%s: %d - %s", url, r.StatusCode, string(b))
 		} else {
 			return nil, fmt.Errorf("%s: %s", url, r.Status)
@@ -71,7 +101,11 @@ func Post(url string, values url.Values) ([]byte, error) {
 }
 
 func Get(url string) ([]byte, error) {
-	r, err := client.Get(url)
+	req, err := clusterRequest("GET", url, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	r, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +121,11 @@ func Get(url string) ([]byte, error) {
 }
 
 func Head(url string) (http.Header, error) {
-	r, err := client.Head(url)
+	req, err := clusterRequest("HEAD", url, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	r, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -98,13 +136,19 @@ func Head(url string) (http.Header, error) {
 }
 
 func Delete(url string, jwt security.EncodedJwt) error {
-	req, err := http.NewRequest("DELETE", url, nil)
-	if jwt != "" {
-		req.Header.Set("Authorization", "BEARER "+string(jwt))
-	}
+	return DeleteContext(context.Background(), url, jwt)
+}
+
+// DeleteContext is like Delete, but propagates the trace carried by ctx.
+func DeleteContext(ctx context.Context, url string, jwt security.EncodedJwt) error {
+	req, err := clusterRequest("DELETE", url, "", nil)
 	if err != nil {
 		return err
 	}
+	if jwt != "" {
+		req.Header.Set("Authorization", "BEARER "+string(jwt))
+	}
+	tracing.Inject(ctx, req.Header)
 	resp, e := client.Do(req)
 	if e != nil {
 		return e
@@ -128,7 +172,7 @@ func Delete(url string, jwt security.EncodedJwt) error {
 }
 
 func GetBufferStream(url string, values url.Values, allocatedBytes []byte, eachBuffer func([]byte)) error {
-	r, err := client.PostForm(url, values)
+	r, err := postForm(url, values)
 	if err != nil {
 		return err
 	}
@@ -152,7 +196,7 @@ func GetBufferStream(url string, values url.Values, allocatedBytes []byte, eachB
 }
 
 func GetUrlStream(url string, values url.Values, readFn func(io.Reader) error) error {
-	r, err := client.PostForm(url, values)
+	r, err := postForm(url, values)
 	if err != nil {
 		return err
 	}
@@ -163,8 +207,20 @@ func GetUrlStream(url string, values url.Values, readFn func(io.Reader) error) e
 	return readFn(r.Body)
 }
 
+func postForm(url string, values url.Values) (*http.Response, error) {
+	req, err := clusterRequest("POST", url, "application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
 func DownloadUrl(fileUrl string) (filename string, rc io.ReadCloser, e error) {
-	response, err := client.Get(fileUrl)
+	req, err := clusterRequest("GET", fileUrl, "", nil)
+	if err != nil {
+		return "", nil, err
+	}
+	response, err := client.Do(req)
 	if err != nil {
 		return "", nil, err
 	}
@@ -180,6 +236,7 @@ func DownloadUrl(fileUrl string) (filename string, rc io.ReadCloser, e error) {
 	return
 }
 
+// Do sends the request as is, without the api key of this process, see AddApiKey.
 func Do(req *http.Request) (resp *http.Response, err error) {
 	return client.Do(req)
 }
@@ -0,0 +1,36 @@
+package util
+
+import "time"
+
+// WriteThrottler sleeps as needed to keep writes under a rate.
+type WriteThrottler struct {
+	compactionBytePerSecond int64
+	lastSizeCounter         int64
+	lastSizeCheckTime       time.Time
+}
+
+// NewWriteThrottler limits writes to bytesPerSecond, unlimited if 0.
+func NewWriteThrottler(bytesPerSecond int64) *WriteThrottler {
+	return &WriteThrottler{
+		compactionBytePerSecond: bytesPerSecond,
+		lastSizeCheckTime:       time.Now(),
+	}
+}
+
+// MaybeSlowdown counts delta written bytes, and sleeps if written too fast.
+func (wt *WriteThrottler) MaybeSlowdown(delta int64) {
+	if wt.compactionBytePerSecond <= 0 {
+		return
+	}
+	wt.lastSizeCounter += delta
+	// check every tenth of the allowed bytes per second
+	if wt.lastSizeCounter < wt.compactionBytePerSecond/10 {
+		return
+	}
+	expected := time.Duration(wt.lastSizeCounter * int64(time.Second) / wt.compactionBytePerSecond)
+	if elapsed := time.Since(wt.lastSizeCheckTime); elapsed < expected {
+		time.Sleep(expected - elapsed)
+	}
+	wt.lastSizeCounter = 0
+	wt.lastSizeCheckTime = time.Now()
+}
@@ -0,0 +1,28 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteThrottler(t *testing.T) {
+	// 1MB at 4MB per second takes about a quarter of a second
+	wt := NewWriteThrottler(4 * 1024 * 1024)
+	start := time.Now()
+	for i := 0; i < 64; i++ {
+		wt.MaybeSlowdown(16 * 1024)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > time.Second {
+		t.Errorf("wrote 1MB at 4MB/s in %v", elapsed)
+	}
+
+	// unlimited
+	wt = NewWriteThrottler(0)
+	start = time.Now()
+	for i := 0; i < 64; i++ {
+		wt.MaybeSlowdown(16 * 1024 * 1024)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("unlimited writes slowed down for %v", elapsed)
+	}
+}